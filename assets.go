@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed assets
+var embeddedAssets embed.FS
+
+var siteAssets = newAssetSet()
+
+// assetSet knows the fingerprint of every file under assets/. The
+// fingerprinted name ("application.3f2a9c1d0b.css") changes with the
+// content, so it can be cached forever.
+type assetSet struct {
+	fsys fs.FS
+	// solo fuera de dev mode, en dev los archivos cambian
+	fingerprinted map[string]string // name -> fingerprinted name
+	originals     map[string]string // fingerprinted name -> name
+}
+
+func newAssetSet() *assetSet {
+	if devMode {
+		return &assetSet{fsys: os.DirFS("assets")}
+	}
+
+	fsys, err := fs.Sub(embeddedAssets, "assets")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	set := &assetSet{
+		fsys:          fsys,
+		fingerprinted: make(map[string]string),
+		originals:     make(map[string]string),
+	}
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		withHash := fingerprintName(name, fingerprint(data))
+		set.fingerprinted[name] = withHash
+		set.originals[withHash] = name
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return set
+}
+
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:5])
+}
+
+// fingerprintName puts the hash before the extension, images/a.jpg ->
+// images/a.<hash>.jpg
+func fingerprintName(name string, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// assetURL is the "asset" template func. Unknown names and everything in dev
+// mode get the plain URL.
+func assetURL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if withHash, ok := siteAssets.fingerprinted[name]; ok {
+		return "/assets/" + withHash
+	}
+	return "/assets/" + name
+}
+
+// serveAsset serves /assets/*filepath. Fingerprinted URLs are cached for a
+// year, plain URLs (dev mode, or urls stored in the database like the food
+// item pictures) have to be revalidated with their ETag.
+func serveAsset(c *gin.Context) {
+
+	name := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+
+	cacheControl := "no-cache"
+	if original, ok := siteAssets.originals[name]; ok {
+		name = original
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+
+	data, err := fs.ReadFile(siteAssets.fsys, name)
+	if err != nil {
+		notFound(c)
+		return
+	}
+
+	c.Header("Cache-Control", cacheControl)
+	c.Header("ETag", `"`+fingerprint(data)+`"`)
+	http.ServeContent(c.Writer, c.Request, name, time.Time{}, bytes.NewReader(data))
+}
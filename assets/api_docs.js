@@ -0,0 +1,191 @@
+// Interactive docs for /api/v1/openapi.json: every operation with its
+// parameters and schemas, and a form to try it. Requests go with the session
+// cookie, or with the token typed at the top.
+(function () {
+  "use strict";
+
+  var root = document.getElementById("api-docs");
+  var labels = {
+    send: root.getAttribute("data-send"),
+    body: root.getAttribute("data-body"),
+    response: root.getAttribute("data-response")
+  };
+  var spec;
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) {
+      if (key === "text") {
+        node.textContent = attrs[key];
+      } else {
+        node.setAttribute(key, attrs[key]);
+      }
+    });
+    (children || []).forEach(function (child) {
+      if (child) node.appendChild(child);
+    });
+    return node;
+  }
+
+  function resolve(schema) {
+    if (schema && schema.$ref) {
+      return spec.components.schemas[schema.$ref.split("/").pop()];
+    }
+    return schema || {};
+  }
+
+  // example builds a sample value from a schema, used as the request body
+  function example(schema, depth) {
+    var name = schema && schema.$ref ? schema.$ref.split("/").pop() : null;
+    schema = resolve(schema);
+    if ((depth || 0) > 4) return null;
+
+    switch (schema.type) {
+      case "object":
+        if (schema.additionalProperties) return {};
+        var value = {};
+        Object.keys(schema.properties || {}).sort().forEach(function (key) {
+          value[key] = example(schema.properties[key], (depth || 0) + 1);
+        });
+        return value;
+      case "array":
+        return [example(schema.items, (depth || 0) + 1)];
+      case "integer":
+      case "number":
+        return 0;
+      case "boolean":
+        return false;
+      case "string":
+        return schema.format === "date-time" ? new Date().toISOString() : "";
+      default:
+        return name ? {} : null;
+    }
+  }
+
+  function schemaText(schema) {
+    return JSON.stringify(example(schema), null, 2);
+  }
+
+  function renderOperation(path, method, op) {
+
+    var params = op.parameters || [];
+    var inputs = {};
+
+    var paramRows = params.map(function (param) {
+      var input = el("input", { type: "text", name: param.name, placeholder: param.schema.type });
+      inputs[param.in + ":" + param.name] = input;
+      return el("tr", {}, [
+        el("td", {}, [el("code", { text: param.name + (param.required ? " *" : "") })]),
+        el("td", { text: param.in }),
+        el("td", { text: param.description || "" }),
+        el("td", {}, [input])
+      ]);
+    });
+
+    var body, file;
+    var content = op.requestBody && op.requestBody.content;
+    if (content && content["application/json"]) {
+      body = el("textarea", { rows: 8, cols: 60 });
+      body.value = schemaText(content["application/json"].schema);
+    } else if (content && content["multipart/form-data"]) {
+      file = el("input", { type: "file", accept: "image/*" });
+      file.fieldName = Object.keys(content["multipart/form-data"].schema.properties)[0];
+    }
+
+    var success = op.responses["200"].content || {};
+    var responseType = Object.keys(success)[0];
+    var responseSchema = responseType === "application/json" && success[responseType].schema.type ? schemaText(success[responseType].schema) : responseType;
+
+    var output = el("pre", { class: "api-docs-output" });
+    var send = el("button", { type: "button", text: labels.send });
+    send.addEventListener("click", function () {
+      tryOperation(path, method, params, inputs, body, file, output);
+    });
+
+    return el("details", { class: "api-docs-operation", id: op.operationId }, [
+      el("summary", {}, [
+        el("span", { class: "api-docs-method api-docs-" + method, text: method.toUpperCase() }),
+        el("code", { text: path }),
+        el("span", { text: " " + (op.summary || "") })
+      ]),
+      op.description ? el("p", { text: op.description }) : null,
+      paramRows.length ? el("table", { class: "api-docs-params" }, paramRows) : null,
+      body ? el("h4", { text: labels.body }) : null,
+      body || file || null,
+      el("h4", { text: labels.response }),
+      el("pre", { text: responseSchema }),
+      send,
+      output
+    ]);
+  }
+
+  function tryOperation(path, method, params, inputs, body, file, output) {
+
+    var url = path;
+    var query = [];
+    params.forEach(function (param) {
+      var value = inputs[param.in + ":" + param.name].value;
+      if (param.in === "path") {
+        url = url.replace("{" + param.name + "}", encodeURIComponent(value));
+      } else if (value !== "") {
+        query.push(encodeURIComponent(param.name) + "=" + encodeURIComponent(value));
+      }
+    });
+    url = spec.servers[0].url + url + (query.length ? "?" + query.join("&") : "");
+
+    // la API pide JSON o este header a lo que viene con la cookie de sesion
+    var headers = { "X-Requested-With": "XMLHttpRequest" };
+    var token = document.getElementById("api-docs-token").value.trim();
+    if (token) headers.Authorization = "Bearer " + token;
+
+    var payload;
+    if (body) {
+      headers["Content-Type"] = "application/json";
+      payload = body.value;
+    } else if (file && file.files[0]) {
+      payload = new FormData();
+      payload.append(file.fieldName, file.files[0]);
+    }
+
+    output.textContent = "…";
+    fetch(url, { method: method.toUpperCase(), headers: headers, body: payload, credentials: "same-origin" })
+      .then(function (response) {
+        return response.text().then(function (text) {
+          try {
+            text = JSON.stringify(JSON.parse(text), null, 2);
+          } catch (e) {}
+          output.textContent = response.status + " " + response.statusText + "\n\n" + text;
+        });
+      })
+      .catch(function (err) {
+        output.textContent = String(err);
+      });
+  }
+
+  function render() {
+
+    var byTag = {};
+    Object.keys(spec.paths).sort().forEach(function (path) {
+      Object.keys(spec.paths[path]).forEach(function (method) {
+        var op = spec.paths[path][method];
+        var tag = (op.tags || ["api"])[0];
+        (byTag[tag] = byTag[tag] || []).push(renderOperation(path, method, op));
+      });
+    });
+
+    root.textContent = "";
+    spec.tags.forEach(function (tag) {
+      root.appendChild(el("section", {}, [el("h2", { text: tag.name })].concat(byTag[tag.name] || [])));
+    });
+  }
+
+  fetch(root.getAttribute("data-spec"), { credentials: "same-origin" })
+    .then(function (response) { return response.json(); })
+    .then(function (json) {
+      spec = json;
+      render();
+    })
+    .catch(function (err) {
+      root.textContent = String(err);
+    });
+})();
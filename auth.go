@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"server/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	currentUserKey    = "current_user_id"
+	currentUserObjKey = "current_user"
+	currentSessionKey = "current_session_id"
+	sessionCookie     = "picnic_session"
+)
+
+// authenticate loads the user behind the session cookie. It never rejects a
+// request, routes that need a user add requireUser.
+func authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(sessionCookie)
+		if err == nil && token != "" {
+			session, err := models.GetSessionByToken(token)
+			if err != nil {
+				authError(c, err)
+				return
+			}
+
+			if session.ID != 0 {
+				user, err := models.GetUserById(session.UserID)
+				if err != nil {
+					authError(c, err)
+					return
+				}
+
+				if user.Name != "" {
+					setCurrentUser(c, user)
+					c.Set(currentSessionKey, session.ID)
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// authError answers 500 when the session or token can't be looked up, a
+// busy database fails that request and not the whole server
+func authError(c *gin.Context, err error) {
+	log.Printf("auth: %v", err)
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to check your credentials")})
+		return
+	}
+	renderErrorPage(c, http.StatusInternalServerError, "Failed to check your credentials")
+}
+
+func setCurrentUser(c *gin.Context, user models.User) {
+	c.Set(currentUserKey, user.ID)
+	c.Set(currentUserObjKey, user)
+}
+
+func currentUser(c *gin.Context) (models.User, bool) {
+	user, ok := c.Get(currentUserObjKey)
+	if !ok {
+		return models.User{}, false
+	}
+	return user.(models.User), true
+}
+
+// SESSION_COOKIE_INSECURE=1 drops the Secure flag for plain http outside localhost
+func setSessionCookie(c *gin.Context, token string, maxAge int) {
+	secure := os.Getenv("SESSION_COOKIE_INSECURE") == ""
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, token, maxAge, "/", "", secure, true)
+}
+
+type credentials struct {
+	Name     string `json:"name" form:"name" binding:"required"`
+	Email    string `json:"email" form:"email"`
+	Password string `json:"password" form:"password" binding:"required"`
+	Locale   string `json:"locale" form:"locale"`
+}
+
+func startSession(c *gin.Context, user models.User) {
+
+	if err := openSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to create session")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": models.AccountOf(user)})
+}
+
+// openSession sets the session cookie, the caller writes the response
+func openSession(c *gin.Context, user models.User) error {
+
+	token, err := models.CreateSession(user.ID, c.Request.UserAgent())
+	if err != nil {
+		return err
+	}
+
+	setSessionCookie(c, token, int(models.SessionDuration.Seconds()))
+	return nil
+}
+
+func register(c *gin.Context) {
+
+	var json credentials
+
+	if err := c.ShouldBind(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	// sin locale los mails salen en el idioma en que se registro
+	if json.Locale == "" {
+		json.Locale = langOf(c)
+	}
+
+	user, err := models.RegisterUser(models.User{Name: json.Name, Email: json.Email, Locale: json.Locale}, json.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	startSession(c, user)
+}
+
+func login(c *gin.Context) {
+
+	var json credentials
+
+	if err := c.ShouldBind(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	user, err := models.CheckPassword(json.Name, json.Password)
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": trErr(c, err)})
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	startSession(c, user)
+}
+
+func logout(c *gin.Context) {
+
+	if err := closeSession(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": trErr(c, err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func closeSession(c *gin.Context) error {
+
+	userID, _ := currentUserID(c)
+
+	if sessionID, ok := c.Get(currentSessionKey); ok {
+		_, err := models.RevokeSession(sessionID.(int), userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	setSessionCookie(c, "", -1)
+	return nil
+}
+
+func readCurrentUser(c *gin.Context) {
+	user, _ := currentUser(c)
+	c.JSON(http.StatusOK, gin.H{"data": models.AccountOf(user)})
+}
+
+func readAllSessions(c *gin.Context) {
+
+	userID, _ := currentUserID(c)
+
+	sessions, err := models.GetSessionsByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve sessions")})
+		return
+	}
+
+	currentSession, _ := c.Get(currentSessionKey)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSession
+	}
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+func revokeSession(c *gin.Context) {
+
+	sessionID, err := strconv.Atoi(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid session ID")})
+		return
+	}
+
+	userID, _ := currentUserID(c)
+
+	success, err := models.RevokeSession(sessionID, userID)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusNotFound, gin.H{"error": trErr(c, err)})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"server/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxSuggestions = 20
+
+func addAvailability(c *gin.Context) {
+
+	var json models.Availability
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	json.UserID, _ = currentUserID(c)
+
+	availability, err := models.CreateAvailability(json)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": availability})
+}
+
+func readAllAvailabilityOfUser(c *gin.Context) {
+
+	userID, _ := currentUserID(c)
+
+	windows, err := models.GetAvailabilityByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve availability")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": windows})
+}
+
+func deleteAvailability(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("availability_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid availability ID")})
+		return
+	}
+
+	userID, _ := currentUserID(c)
+
+	success, err := models.DeleteAvailability(userID, id)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusNotFound, gin.H{"error": trErr(c, err)})
+	}
+}
+
+// GET /schedule/suggestions?user_ids=1,2,3&from=2026-11-01&to=2026-11-30&duration=3h&limit=5
+// A date-only to includes that whole day.
+func readScheduleSuggestions(c *gin.Context) {
+
+	userIDs, ok := parseUserIDs(c)
+	if !ok {
+		return
+	}
+
+	// solo se mira la agenda de gente con la que se comparte un picnic
+	if !requirePicnicMates(c, userIDs, "You can only see the availability of people who share a picnic with you") {
+		return
+	}
+
+	from, err := models.ParseDate(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from: " + trErr(c, err)})
+		return
+	}
+
+	to, err := models.ParseDate(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to: " + trErr(c, err)})
+		return
+	}
+	if len(c.Query("to")) == len("2006-01-02") {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	duration, err := time.ParseDuration(c.DefaultQuery("duration", "3h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid duration")})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit <= 0 || limit > maxSuggestions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "limit must be between 1 and %d", maxSuggestions)})
+		return
+	}
+
+	slots, err := models.SuggestSlots(userIDs, from.UTC(), to.UTC(), duration, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": slots})
+}
+
+// requirePicnicMates checks every user in userIDs but the caller shares a
+// picnic with the caller, forbidden is the message of the 403. It writes the
+// error response itself.
+func requirePicnicMates(c *gin.Context, userIDs []int, forbidden string) bool {
+
+	callerID, _ := currentUserID(c)
+	for _, userID := range userIDs {
+		if userID == callerID {
+			continue
+		}
+		shared, err := models.SharePicnic(callerID, userID)
+		if err != nil {
+			serverError(c, err)
+			return false
+		}
+		if !shared {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, forbidden)})
+			return false
+		}
+	}
+	return true
+}
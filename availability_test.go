@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestSuggestionsOnlyForPicnicMates(t *testing.T) {
+
+	p := newRolePicnic(t)
+	path := func(ids ...int) string {
+		return fmt.Sprintf("/api/v1/schedule/suggestions?from=2026-11-01&to=2026-11-02&user_ids=%s", joinIDs(ids))
+	}
+	attendee := p.users["attendee"]
+
+	if w := attendee.do(t, "GET", path(attendee.ID, p.users["owner"].ID, p.bringer.ID), nil); w.Code != http.StatusOK {
+		t.Errorf("picnic mates: got %d: %s", w.Code, w.Body)
+	}
+	for _, role := range []string{"outsider", "waitlisted", "declined"} {
+		if w := attendee.do(t, "GET", path(attendee.ID, p.users[role].ID), nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s: got %d, want 403", role, w.Code)
+		}
+	}
+	if w := p.users["outsider"].do(t, "GET", path(p.users["owner"].ID), nil); w.Code != http.StatusForbidden {
+		t.Errorf("outsider asking for the owner: got %d, want 403", w.Code)
+	}
+}
+
+func joinIDs(ids []int) string {
+	s := ""
+	for i, id := range ids {
+		if i > 0 {
+			s += ","
+		}
+		s += fmt.Sprint(id)
+	}
+	return s
+}
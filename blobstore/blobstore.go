@@ -0,0 +1,114 @@
+// Package blobstore guarda los archivos subidos (fotos por ahora). El server
+// usa Disk, otro backend solo tiene que implementar Store.
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Blob is an open stored file, the caller closes it
+type Blob interface {
+	io.ReadSeekCloser
+	ModTime() time.Time
+}
+
+// Keys are slash separated names like "images/ab12.jpg". Put overwrites.
+type Store interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (Blob, error)
+	Delete(key string) error
+}
+
+// FromEnv stores blobs on disk under BLOB_DIR, ./uploads if it isn't set.
+func FromEnv() Store {
+	dir := os.Getenv("BLOB_DIR")
+	if dir == "" {
+		dir = "./uploads"
+	}
+	return &Disk{Dir: dir}
+}
+
+type Disk struct {
+	Dir string
+}
+
+func (d *Disk) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(d.Dir, filepath.FromSlash(clean)), nil
+}
+
+// Put escribe a un temporal y lo renombra, nunca se sirve un archivo a medias
+func (d *Disk) Put(key string, r io.Reader) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+type diskBlob struct {
+	*os.File
+	modTime time.Time
+}
+
+func (b diskBlob) ModTime() time.Time { return b.modTime }
+
+func (d *Disk) Open(key string) (Blob, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return diskBlob{File: file, modTime: info.ModTime()}, nil
+}
+
+func (d *Disk) Delete(key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
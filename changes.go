@@ -0,0 +1,85 @@
+package main
+
+import (
+	"server/live"
+	"server/models"
+	"server/webhooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Lo que sigue a un cambio guardado: webhooks, stream del picnic y mails.
+// REST, las paginas, GraphQL y las sesiones en vivo llaman a estas mismas
+// funciones, asi ninguna forma de editar se queda sin avisar.
+
+func picnicCreated(picnic models.Picnic) {
+	webhooks.Emit(picnic.ID, models.EventPicnicCreated, picnic)
+}
+
+func picnicUpdated(before models.Picnic, after models.Picnic, editorID int) {
+	notifyPicnicChanged(before, after, editorID)
+	live.Publish(after.ID, models.EventPicnicUpdated, after)
+}
+
+// removePicnic deletes a picnic with everything in it. DeletePicnic takes
+// the memberships too, so who gets the webhook and which photos to remove
+// are looked up before.
+func removePicnic(picnicID int) (bool, error) {
+
+	recipients, err := models.GetWebhooksOfPicnic(picnicID)
+	if err != nil {
+		return false, err
+	}
+	images, err := models.GetImagesByPicnic(picnicID)
+	if err != nil {
+		return false, err
+	}
+
+	success, err := models.DeletePicnic(picnicID)
+	if !success {
+		return false, err
+	}
+
+	for _, image := range images {
+		removeImageFiles(image)
+	}
+	webhooks.EmitTo(recipients, models.EventPicnicDeleted, gin.H{"id": picnicID})
+	live.Publish(picnicID, models.EventPicnicDeleted, gin.H{"id": picnicID})
+	return true, nil
+}
+
+// membershipAdded takes the membership as saved, waitlisted if the picnic
+// was full
+func membershipAdded(membership models.UserPicnic) {
+	webhooks.Emit(membership.PicnicID, models.EventMembershipAdded, membership)
+	live.Publish(membership.PicnicID, models.EventMembershipAdded, membership)
+}
+
+// membershipLeft is for removed and declined users, event tells which
+func membershipLeft(picnicID int, userID int, event string) {
+	data := gin.H{"user_id": userID, "picnic_id": picnicID}
+	webhooks.Emit(picnicID, event, data)
+	live.Publish(picnicID, event, data)
+}
+
+func contributionCreated(contribution models.Contribution) {
+	webhooks.Emit(contribution.PicnicID, models.EventContributionCreated, contribution)
+	live.Publish(contribution.PicnicID, models.EventContributionCreated, contribution)
+}
+
+// contributionUpdated is what follows a saved edit. A contribution moved to
+// another picnic is gone for the old one.
+func contributionUpdated(before models.Contribution, after models.Contribution, editorID int) {
+
+	notifyContributionChanged(before, after, editorID)
+	webhooks.Emit(after.PicnicID, models.EventContributionUpdated, after)
+	live.Publish(after.PicnicID, models.EventContributionUpdated, after)
+	if before.PicnicID != after.PicnicID {
+		contributionDeleted(before)
+	}
+}
+
+func contributionDeleted(contribution models.Contribution) {
+	webhooks.Emit(contribution.PicnicID, models.EventContributionDeleted, contribution)
+	live.Publish(contribution.PicnicID, models.EventContributionDeleted, contribution)
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"server/models"
+)
+
+type credentials struct {
+	Name     string `json:"name"`
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+// Register creates a user with a password and keeps its session in c.Session
+func (c *Client) Register(ctx context.Context, user models.User, password string) (models.User, error) {
+	return c.startSession(ctx, "/auth/register", credentials{Name: user.Name, Email: user.Email, Password: password, Locale: user.Locale})
+}
+
+// Login keeps the session in c.Session, requests use it while c.Token is empty
+func (c *Client) Login(ctx context.Context, name string, password string) (models.User, error) {
+	return c.startSession(ctx, "/auth/login", credentials{Name: name, Password: password})
+}
+
+func (c *Client) startSession(ctx context.Context, path string, body credentials) (models.User, error) {
+
+	var account models.Account
+	result, err := c.do(ctx, http.MethodPost, path, nil, body, &account)
+	if err != nil {
+		return models.User{}, err
+	}
+	for _, cookie := range result.cookies {
+		if cookie.Name == SessionCookie {
+			c.Session = cookie.Value
+		}
+	}
+	return account.ToUser(), nil
+}
+
+func (c *Client) Logout(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodPost, "/auth/logout", nil, nil, nil)
+	if err == nil {
+		c.Session = ""
+	}
+	return err
+}
+
+// Me is the user of the token or session, the only one that comes with
+// its email
+func (c *Client) Me(ctx context.Context) (models.User, error) {
+	var account models.Account
+	_, err := c.do(ctx, http.MethodGet, "/auth/me", nil, nil, &account)
+	return account.ToUser(), err
+}
+
+// CreateToken returns the raw token, the API doesn't show it again.
+// expiresInDays 0 is a token that doesn't expire.
+func (c *Client) CreateToken(ctx context.Context, name string, scopes []string, expiresInDays int) (string, error) {
+
+	body := struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days,omitempty"`
+	}{name, scopes, expiresInDays}
+
+	var created struct {
+		Token string `json:"token"`
+	}
+	_, err := c.do(ctx, http.MethodPost, "/tokens/", nil, body, &created)
+	return created.Token, err
+}
+
+func (c *Client) ListTokens(ctx context.Context) ([]models.ApiToken, error) {
+	tokens := make([]models.ApiToken, 0)
+	_, err := c.do(ctx, http.MethodGet, "/tokens/", nil, nil, &tokens)
+	return tokens, err
+}
+
+func (c *Client) RevokeToken(ctx context.Context, tokenID int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/tokens/%d", tokenID), nil, nil, nil)
+	return err
+}
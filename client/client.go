@@ -0,0 +1,209 @@
+// Package client calls the /api/v1 routes from Go. Methods take a context,
+// return the models structs and report API failures as *Error:
+//
+//	api := client.New("https://picnics.example.com")
+//	api.Token = os.Getenv("PICNIC_TOKEN")
+//	picnic, err := api.CreatePicnic(ctx, models.Picnic{Name: "Parque", Date: "2026-11-21"})
+//	if errors.Is(err, client.ErrForbidden) { ... }
+//
+// Network errors, 429 and 502/503/504 are retried with backoff. Requests that
+// aren't idempotent (POST) are only retried on 429, when the server surely
+// didn't run them.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+// SessionCookie is the cookie Login and Register get back
+const SessionCookie = "picnic_session"
+
+type Client struct {
+	// BaseURL is where the server runs, without /api/v1
+	BaseURL    string
+	HTTPClient *http.Client
+	// Token is an API token from /tokens/, it wins over Session
+	Token string
+	// Session is set by Login and Register and sent as the session cookie
+	Session string
+	// Language of the error messages, "es" or "en"
+	Language string
+	// MaxRetries is how many times a failed request is sent again
+	MaxRetries int
+	// Backoff is the wait before retry number attempt (0 based)
+	Backoff func(attempt int) time.Duration
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		MaxRetries: 3,
+		Backoff:    Backoff,
+	}
+}
+
+// MaxBackoff is the longest wait between retries, a Retry-After above it
+// is cut down to it
+var MaxBackoff = 8 * time.Second
+
+// Backoff is 250ms, 500ms, 1s... capped at MaxBackoff, each with up to 50%
+// of jitter so clients that failed together don't retry together.
+func Backoff(attempt int) time.Duration {
+	backoff := 250 * time.Millisecond
+	for i := 0; i < attempt && backoff < MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxBackoff {
+		backoff = MaxBackoff
+	}
+	return backoff + time.Duration(rand.Int63n(int64(backoff/2)))
+}
+
+// envelope is every JSON answer of the API, {"message", "data"} or {"error"}
+type envelope struct {
+	Data      json.RawMessage `json:"data"`
+	NextAfter int             `json:"next_after"`
+	cookies   []*http.Cookie
+}
+
+// do sends body as JSON and decodes the "data" of the answer into out, out
+// and body can be nil.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) (*envelope, error) {
+
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	target := c.BaseURL + apiPrefix + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		status, header, answer, err := c.send(ctx, method, target, payload)
+		if err == nil && status >= 200 && status <= 299 {
+			return decode(answer, header, out)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil {
+			err = newError(method, path, status, answer)
+		}
+		if attempt >= c.MaxRetries || !retryable(method, status) {
+			return nil, err
+		}
+
+		wait := c.Backoff(attempt)
+		// un Retry-After de una hora no deja colgado al que llama
+		if seconds, parseErr := strconv.Atoi(header.Get("Retry-After")); parseErr == nil && seconds >= 0 {
+			wait = time.Duration(seconds) * time.Second
+			if wait > MaxBackoff {
+				wait = MaxBackoff
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, target string, payload []byte) (int, http.Header, []byte, error) {
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "picnic-client/1")
+	// writes with the session cookie need it, even without a body
+	req.Header.Set("X-Requested-With", "picnic-client")
+	if c.Language != "" {
+		req.Header.Set("Accept-Language", c.Language)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.Session != "" {
+		req.AddCookie(&http.Cookie{Name: SessionCookie, Value: c.Session})
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return resp.StatusCode, resp.Header, answer, nil
+}
+
+func decode(answer []byte, header http.Header, out interface{}) (*envelope, error) {
+
+	// Cookies solo esta en http.Response, el header trae los Set-Cookie
+	result := &envelope{cookies: (&http.Response{Header: header}).Cookies()}
+	if len(answer) == 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(answer, result); err != nil {
+		return nil, fmt.Errorf("client: invalid JSON answer: %w", err)
+	}
+	if out != nil && len(result.Data) > 0 && string(result.Data) != "null" {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return nil, fmt.Errorf("client: invalid data: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// retryable decides with the status of the last attempt, 0 is a network
+// error. A POST that failed on the network may have been run already.
+func retryable(method string, status int) bool {
+
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	if method == http.MethodPost {
+		return false
+	}
+	switch status {
+	case 0, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"server/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer answers status to the first failures requests and then a
+// picnic, hits counts them all
+func flakyServer(t *testing.T, failures int64, status int, retryAfter string) (*Client, *int64) {
+	t.Helper()
+
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt64(&hits, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error": "try later"}`))
+			return
+		}
+		w.Write([]byte(`{"message": "Success", "data": {"id": 7, "name": "Parque"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	api := New(server.URL)
+	api.Backoff = func(int) time.Duration { return time.Millisecond }
+	return api, &hits
+}
+
+func TestRetriesOn503(t *testing.T) {
+
+	api, hits := flakyServer(t, 2, http.StatusServiceUnavailable, "")
+
+	picnic, err := api.GetPicnic(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if picnic.Name != "Parque" || *hits != 3 {
+		t.Errorf("got %+v after %d requests", picnic, *hits)
+	}
+}
+
+// un POST que dio 503 pudo haberse hecho, no se repite
+func TestPostIsNotRetriedOn503(t *testing.T) {
+
+	api, hits := flakyServer(t, 1, http.StatusServiceUnavailable, "")
+
+	_, err := api.CreatePicnic(context.Background(), models.Picnic{Name: "Parque"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "try later" {
+		t.Errorf("got %v", err)
+	}
+	if *hits != 1 {
+		t.Errorf("sent %d times", *hits)
+	}
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+
+	api, hits := flakyServer(t, 10, http.StatusBadGateway, "")
+	api.MaxRetries = 2
+
+	if _, err := api.GetPicnic(context.Background(), 7); err == nil {
+		t.Fatal("no error after a 502")
+	}
+	if *hits != 3 {
+		t.Errorf("sent %d times, want 3", *hits)
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+
+	defer func(max time.Duration) { MaxBackoff = max }(MaxBackoff)
+	MaxBackoff = 10 * time.Millisecond
+
+	api, hits := flakyServer(t, 1, http.StatusTooManyRequests, "3600")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := api.GetPicnic(ctx, 7); err != nil {
+		t.Fatalf("waited for Retry-After: %v", err)
+	}
+	if *hits != 2 {
+		t.Errorf("sent %d times", *hits)
+	}
+}
+
+func TestCancelStopsTheBackoff(t *testing.T) {
+
+	api, hits := flakyServer(t, 10, http.StatusServiceUnavailable, "")
+	api.Backoff = func(int) time.Duration { return time.Hour }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := api.GetPicnic(ctx, 7)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if time.Since(start) > 5*time.Second || *hits != 1 {
+		t.Errorf("returned after %s and %d requests", time.Since(start), *hits)
+	}
+}
+
+func TestErrorsMatchTheSentinels(t *testing.T) {
+
+	api, _ := flakyServer(t, 1, http.StatusForbidden, "")
+
+	_, err := api.GetPicnic(context.Background(), 7)
+	if !errors.Is(err, ErrForbidden) || errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v", err)
+	}
+}
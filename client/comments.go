@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"server/models"
+	"strconv"
+)
+
+// CommentQuery filters Comments, the zero value is every comment of the
+// picnic in pages of 50.
+type CommentQuery struct {
+	// ContributionID only returns the comments on that contribution
+	ContributionID int
+	// After skips the comments up to that id
+	After int
+	// PageSize is 1 to 200, 0 is the server default
+	PageSize int
+}
+
+// CommentIterator walks the comments of a picnic oldest first, asking for
+// the next page when the current one runs out:
+//
+//	comments := api.Comments(ctx, picnicID, client.CommentQuery{})
+//	for comments.Next() {
+//		fmt.Println(comments.Comment().Body)
+//	}
+//	if err := comments.Err(); err != nil { ... }
+type CommentIterator struct {
+	client   *Client
+	ctx      context.Context
+	picnicID int
+	query    CommentQuery
+
+	page    []models.Comment
+	current models.Comment
+	done    bool
+	err     error
+}
+
+func (c *Client) Comments(ctx context.Context, picnicID int, query CommentQuery) *CommentIterator {
+	return &CommentIterator{client: c, ctx: ctx, picnicID: picnicID, query: query}
+}
+
+// Next moves to the next comment, false when there are no more or a page
+// failed, see Err.
+func (it *CommentIterator) Next() bool {
+
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *CommentIterator) fetch() {
+
+	query := url.Values{}
+	if it.query.ContributionID != 0 {
+		query.Set("contribution_id", strconv.Itoa(it.query.ContributionID))
+	}
+	if it.query.After != 0 {
+		query.Set("after", strconv.Itoa(it.query.After))
+	}
+	if it.query.PageSize != 0 {
+		query.Set("limit", strconv.Itoa(it.query.PageSize))
+	}
+
+	page := make([]models.Comment, 0)
+	result, err := it.client.do(it.ctx, http.MethodGet, fmt.Sprintf("/picnics/%d/comments", it.picnicID), query, nil, &page)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	// sin next_after era la ultima pagina
+	it.page = page
+	it.query.After = result.NextAfter
+	it.done = result.NextAfter == 0
+}
+
+func (it *CommentIterator) Comment() models.Comment {
+	return it.current
+}
+
+func (it *CommentIterator) Err() error {
+	return it.err
+}
+
+// All drains the iterator
+func (it *CommentIterator) All() ([]models.Comment, error) {
+	comments := make([]models.Comment, 0)
+	for it.Next() {
+		comments = append(comments, it.Comment())
+	}
+	return comments, it.Err()
+}
+
+func (c *Client) AddComment(ctx context.Context, picnicID int, comment models.Comment) (models.Comment, error) {
+	var created models.Comment
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/picnics/%d/comments", picnicID), nil, comment, &created)
+	return created, err
+}
+
+// UpdateComment edits the body, only the author can
+func (c *Client) UpdateComment(ctx context.Context, comment models.Comment) (models.Comment, error) {
+	var updated models.Comment
+	_, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/picnics/%d/comments/%d", comment.PicnicID, comment.ID), nil, comment, &updated)
+	return updated, err
+}
+
+func (c *Client) DeleteComment(ctx context.Context, picnicID int, commentID int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/picnics/%d/comments/%d", picnicID, commentID), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"server/models"
+)
+
+// AddContribution returns the contribution with its id and version 1
+func (c *Client) AddContribution(ctx context.Context, contribution models.Contribution) (models.Contribution, error) {
+	var created models.Contribution
+	_, err := c.do(ctx, http.MethodPost, "/contributions/", nil, contribution, &created)
+	return created, err
+}
+
+func (c *Client) GetContribution(ctx context.Context, contributionID int) (models.Contribution, error) {
+	var contribution models.Contribution
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/contributions/%d", contributionID), nil, nil, &contribution)
+	return contribution, err
+}
+
+func (c *Client) ListContributions(ctx context.Context) ([]models.Contribution, error) {
+	contributions := make([]models.Contribution, 0)
+	_, err := c.do(ctx, http.MethodGet, "/contributions/", nil, nil, &contributions)
+	return contributions, err
+}
+
+// UpdateContribution with a Version only saves if nobody saved after that
+// version, otherwise it fails with a *ConflictError holding the saved row.
+// Version 0 overwrites.
+func (c *Client) UpdateContribution(ctx context.Context, contribution models.Contribution) error {
+
+	_, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/contributions/%d", contribution.ID), nil, contribution, nil)
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		conflict := &ConflictError{Err: apiErr}
+		if len(apiErr.Data) > 0 {
+			json.Unmarshal(apiErr.Data, &conflict.Current)
+		}
+		return conflict
+	}
+	return err
+}
+
+func (c *Client) DeleteContribution(ctx context.Context, contributionID int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/contributions/%d", contributionID), nil, nil, nil)
+	return err
+}
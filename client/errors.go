@@ -0,0 +1,87 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server/models"
+	"sort"
+	"strings"
+)
+
+// Error is an answer of the API outside 2xx. Message is the "error" of the
+// body, in the language of Client.Language.
+type Error struct {
+	StatusCode int
+	Message    string
+	Method     string
+	Path       string
+	// Data is the "data" some errors carry, like the current row of a 409
+	Data json.RawMessage
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Is matches the sentinels by status, errors.Is(err, ErrNotFound)
+func (e *Error) Is(target error) bool {
+	sentinel, ok := target.(*Error)
+	return ok && sentinel.Message == "" && sentinel.Method == "" && sentinel.StatusCode == e.StatusCode
+}
+
+var (
+	ErrBadRequest   = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden    = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound     = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict     = &Error{StatusCode: http.StatusConflict}
+)
+
+func newError(method string, path string, status int, answer []byte) *Error {
+
+	apiErr := &Error{StatusCode: status, Method: method, Path: path}
+
+	var body map[string]json.RawMessage
+	if json.Unmarshal(answer, &body) != nil {
+		apiErr.Message = strings.TrimSpace(string(answer))
+		if len(apiErr.Message) > 200 {
+			apiErr.Message = apiErr.Message[:200]
+		}
+		return apiErr
+	}
+	apiErr.Data = body["data"]
+
+	// updateUser todavia contesta error_1, error_2...
+	keys := make([]string, 0, len(body))
+	for key := range body {
+		if strings.HasPrefix(key, "error") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if json.Unmarshal(body[key], &apiErr.Message) == nil {
+			break
+		}
+	}
+	return apiErr
+}
+
+// ConflictError is a contribution update sent with a stale version, Current
+// is what is saved now.
+type ConflictError struct {
+	Err     *Error
+	Current models.Contribution
+}
+
+func (e *ConflictError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
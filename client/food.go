@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"server/models"
+)
+
+// CreateFoodItem doesn't return the id either, look it up with ListFoodItems
+func (c *Client) CreateFoodItem(ctx context.Context, item models.FoodItem) error {
+	_, err := c.do(ctx, http.MethodPost, "/food-items/", nil, item, nil)
+	return err
+}
+
+func (c *Client) GetFoodItem(ctx context.Context, itemID int) (models.FoodItem, error) {
+	var item models.FoodItem
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/food-items/%d", itemID), nil, nil, &item)
+	return item, err
+}
+
+func (c *Client) ListFoodItems(ctx context.Context) ([]models.FoodItem, error) {
+	items := make([]models.FoodItem, 0)
+	_, err := c.do(ctx, http.MethodGet, "/food-items/", nil, nil, &items)
+	// la API contesta 404 cuando todavia no hay ninguno
+	if errors.Is(err, ErrNotFound) {
+		return items, nil
+	}
+	return items, err
+}
+
+func (c *Client) UpdateFoodItem(ctx context.Context, item models.FoodItem) error {
+	_, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/food-items/%d", item.ID), nil, item, nil)
+	return err
+}
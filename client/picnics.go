@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"server/models"
+	"time"
+)
+
+// CreatePicnic returns the picnic with its id, the caller becomes the owner
+func (c *Client) CreatePicnic(ctx context.Context, picnic models.Picnic) (models.Picnic, error) {
+	var created models.Picnic
+	_, err := c.do(ctx, http.MethodPost, "/picnics/", nil, picnic, &created)
+	return created, err
+}
+
+func (c *Client) GetPicnic(ctx context.Context, picnicID int) (models.Picnic, error) {
+	var picnic models.Picnic
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/picnics/%d", picnicID), nil, nil, &picnic)
+	return picnic, err
+}
+
+func (c *Client) ListPicnics(ctx context.Context) ([]models.Picnic, error) {
+	picnics := make([]models.Picnic, 0)
+	_, err := c.do(ctx, http.MethodGet, "/picnics/", nil, nil, &picnics)
+	return picnics, err
+}
+
+// UpdatePicnic saves every field of picnic under picnic.ID
+func (c *Client) UpdatePicnic(ctx context.Context, picnic models.Picnic) error {
+	_, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/picnics/%d", picnic.ID), nil, picnic, nil)
+	return err
+}
+
+func (c *Client) DeletePicnic(ctx context.Context, picnicID int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/picnics/%d", picnicID), nil, nil, nil)
+	return err
+}
+
+// AddUserToPicnic returns the membership, waitlisted if the picnic is full
+func (c *Client) AddUserToPicnic(ctx context.Context, picnicID int, userID int) (models.UserPicnic, error) {
+	var membership models.UserPicnic
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/picnics/%d/users/%d", picnicID, userID), nil, nil, &membership)
+	return membership, err
+}
+
+// ListUsersOfPicnic are the users attending, not the waitlist
+func (c *Client) ListUsersOfPicnic(ctx context.Context, picnicID int) ([]models.User, error) {
+	users := make([]models.User, 0)
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/picnics/%d/users", picnicID), nil, nil, &users)
+	return users, err
+}
+
+func (c *Client) ListPicnicsOfUser(ctx context.Context, userID int) ([]models.Picnic, error) {
+	picnics := make([]models.Picnic, 0)
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/users/%d/picnics", userID), nil, nil, &picnics)
+	return picnics, err
+}
+
+func (c *Client) GetMembership(ctx context.Context, userID int, picnicID int) (models.UserPicnic, error) {
+	var membership models.UserPicnic
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/users/%d/picnics/%d", userID, picnicID), nil, nil, &membership)
+	return membership, err
+}
+
+func (c *Client) RemoveUserFromPicnic(ctx context.Context, picnicID int, userID int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/picnics/%d/users/%d", picnicID, userID), nil, nil, nil)
+	return err
+}
+
+func (c *Client) ListWaitlist(ctx context.Context, picnicID int) ([]models.UserPicnic, error) {
+	waitlist := make([]models.UserPicnic, 0)
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/picnics/%d/waitlist", picnicID), nil, nil, &waitlist)
+	return waitlist, err
+}
+
+// Invite is a new invitation, Token and URL are only returned here
+type Invite struct {
+	Invitation models.Invitation `json:"invitation"`
+	Token      string            `json:"token"`
+	URL        string            `json:"url"`
+}
+
+// InviteToPicnic with userID 0 makes a link anyone can accept. expiresIn is
+// rounded to hours, 0 uses the server default.
+func (c *Client) InviteToPicnic(ctx context.Context, picnicID int, userID int, expiresIn time.Duration) (Invite, error) {
+
+	body := struct {
+		UserID         int `json:"user_id,omitempty"`
+		ExpiresInHours int `json:"expires_in_hours,omitempty"`
+	}{userID, int(expiresIn.Round(time.Hour) / time.Hour)}
+
+	var invite Invite
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/picnics/%d/invitations", picnicID), nil, body, &invite)
+	return invite, err
+}
+
+func (c *Client) ListInvitations(ctx context.Context, picnicID int) ([]models.Invitation, error) {
+	invitations := make([]models.Invitation, 0)
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/picnics/%d/invitations", picnicID), nil, nil, &invitations)
+	return invitations, err
+}
+
+func (c *Client) RevokeInvitation(ctx context.Context, picnicID int, invitationID int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/picnics/%d/invitations/%d", picnicID, invitationID), nil, nil, nil)
+	return err
+}
+
+// PicnicList is the packing list, food contributions and gear together
+func (c *Client) PicnicList(ctx context.Context, picnicID int) ([]models.ListItem, error) {
+	items := make([]models.ListItem, 0)
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/picnics/%d/list", picnicID), nil, nil, &items)
+	return items, err
+}
+
+// PicnicBalances is who paid what and the transfers that settle the picnic
+func (c *Client) PicnicBalances(ctx context.Context, picnicID int) (models.SettleUp, error) {
+	var settleUp models.SettleUp
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/picnics/%d/balances", picnicID), nil, nil, &settleUp)
+	return settleUp, err
+}
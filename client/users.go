@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"server/models"
+)
+
+// CreateUser adds a user without password, the API doesn't return its id.
+// The client must be logged in.
+func (c *Client) CreateUser(ctx context.Context, user models.User) error {
+	_, err := c.do(ctx, http.MethodPost, "/users/", nil, models.AccountOf(user), nil)
+	return err
+}
+
+// GetUser and ListUsers leave Email empty, the API only shows it in Me
+func (c *Client) GetUser(ctx context.Context, userID int) (models.User, error) {
+	var user models.User
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/users/%d", userID), nil, nil, &user)
+	return user, err
+}
+
+func (c *Client) ListUsers(ctx context.Context) ([]models.User, error) {
+	users := make([]models.User, 0)
+	_, err := c.do(ctx, http.MethodGet, "/users/", nil, nil, &users)
+	return users, err
+}
+
+// UpdateUser only works on the user of the token or session
+func (c *Client) UpdateUser(ctx context.Context, user models.User) error {
+	_, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/users/%d", user.ID), nil, models.AccountOf(user), nil)
+	return err
+}
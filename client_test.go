@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server/client"
+	"server/models"
+	"sync/atomic"
+	"testing"
+)
+
+// El cliente contra el router de verdad, por HTTP. Vive en main porque el
+// paquete client no puede importar el router.
+
+// apiClient is a client logged in as u on a server of testRouter, requests
+// counts what reaches the server
+func apiClient(t *testing.T, u testUser) (*client.Client, *int64) {
+	t.Helper()
+
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		testRouter.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	api := client.New(server.URL)
+	api.Session = u.session
+	return api, &requests
+}
+
+func TestClientPicnicAndContributions(t *testing.T) {
+
+	ctx := context.Background()
+	owner := newTestUser(t, "client-owner")
+	friend := newTestUser(t, "client-friend")
+	api, _ := apiClient(t, owner)
+
+	picnic, err := api.CreatePicnic(ctx, models.Picnic{Name: "Parque", Date: "2026-11-21 13:00", Location: "Retiro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if picnic.ID == 0 || picnic.Name != "Parque" {
+		t.Fatalf("created %+v", picnic)
+	}
+
+	if _, err := api.AddUserToPicnic(ctx, picnic.ID, friend.ID); err != nil {
+		t.Fatal(err)
+	}
+	users, err := api.ListUsersOfPicnic(ctx, picnic.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, user := range users {
+		names[user.Name] = true
+		if user.Email != "" {
+			t.Errorf("%s comes with its email", user.Name)
+		}
+	}
+	if len(users) != 2 || !names[owner.Name] || !names[friend.Name] {
+		t.Errorf("attending: %+v", users)
+	}
+
+	mustOK(t)(models.CreateFoodItem(models.FoodItem{Name: fmt.Sprintf("Tortilla %d", picnic.ID), Measure: "units"}))
+	items, err := models.GetFoodItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	foodItemID := items[len(items)-1].ID
+
+	friendAPI, _ := apiClient(t, friend)
+	created, err := friendAPI.AddContribution(ctx, models.Contribution{UserID: friend.ID, PicnicID: picnic.ID, FoodItemID: foodItemID, Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.Version != 1 {
+		t.Errorf("created %+v", created)
+	}
+	stored, err := models.GetContributionById(created.ID)
+	if err != nil || stored.Quantity != 2 || stored.UserID != friend.ID {
+		t.Errorf("stored %+v, %v", stored, err)
+	}
+}
+
+func TestClientForbidden(t *testing.T) {
+
+	p := newRolePicnic(t)
+	api, _ := apiClient(t, p.users["attendee"])
+
+	// el attendee no puede traer algo a nombre del bringer
+	_, err := api.AddContribution(context.Background(), models.Contribution{UserID: p.bringer.ID, PicnicID: p.picnicID, FoodItemID: p.contribution.FoodItemID, Quantity: 1})
+	if !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("got %v, want ErrForbidden", err)
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Message != "You can only manage your own contributions" || apiErr.Method != http.MethodPost {
+		t.Errorf("got %+v", apiErr)
+	}
+
+	anonymous, _ := apiClient(t, testUser{})
+	_, err = anonymous.AddContribution(context.Background(), models.Contribution{UserID: p.bringer.ID, PicnicID: p.picnicID, FoodItemID: p.contribution.FoodItemID, Quantity: 1})
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("anonymous: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClientCommentPages(t *testing.T) {
+
+	ctx := context.Background()
+	p := newRolePicnic(t)
+	api, requests := apiClient(t, p.users["attendee"])
+
+	for i := 1; i <= 5; i++ {
+		if _, err := api.AddComment(ctx, p.picnicID, models.Comment{Body: fmt.Sprintf("comment %d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	atomic.StoreInt64(requests, 0)
+	comments, err := api.Comments(ctx, p.picnicID, client.CommentQuery{PageSize: 2}).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 5 {
+		t.Fatalf("got %d comments", len(comments))
+	}
+	for i, comment := range comments {
+		if comment.Body != fmt.Sprintf("comment %d", i+1) {
+			t.Errorf("comment %d is %q", i, comment.Body)
+		}
+	}
+	// 2, 2 y 1: la ultima pagina no trae next_after
+	if n := atomic.LoadInt64(requests); n != 3 {
+		t.Errorf("%d pages, want 3", n)
+	}
+
+	if _, err := api.Comments(ctx, p.picnicID, client.CommentQuery{PageSize: 500}).All(); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("page of 500: got %v", err)
+	}
+}
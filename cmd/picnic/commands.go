@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"server/client"
+	"server/i18n"
+	"server/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const tokenName = "picnic cli"
+
+func loginFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+
+	url := fs.String("url", "", "server URL, "+defaultURL+" by default")
+	name := fs.String("name", "", "user name, the password is read from $PICNIC_PASSWORD or asked")
+	token := fs.String("token", "", "save this API token instead of logging in with a password")
+	language := fs.String("language", "", "language of the server errors, en or es")
+
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		if *url != "" {
+			c.config.URL = strings.TrimRight(*url, "/")
+			c.api.BaseURL = c.config.URL
+		}
+		if *language != "" {
+			c.config.Language = *language
+			c.api.Language = *language
+		}
+
+		if *token != "" {
+			c.api.Token = *token
+			user, err := c.api.Me(ctx)
+			if err != nil {
+				return err
+			}
+			c.config.Token = *token
+			c.config.TokenID = 0
+			fmt.Fprintf(os.Stderr, "Logged in to %s as %s\n", c.config.URL, user.Name)
+			return saveConfig(c.configPath, c.config)
+		}
+
+		if *name == "" {
+			return usageError("--name or --token is required")
+		}
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		// con la sesion se crea un token, el config guarda solo el token
+		c.api.Token = ""
+		user, err := c.api.Login(ctx, *name, password)
+		if err != nil {
+			return err
+		}
+		raw, err := c.api.CreateToken(ctx, tokenName, models.Scopes, 0)
+		if err != nil {
+			return err
+		}
+		c.config.Token = raw
+		c.config.TokenID = newestToken(ctx, c.api)
+		c.api.Logout(ctx)
+
+		fmt.Fprintf(os.Stderr, "Logged in to %s as %s\n", c.config.URL, user.Name)
+		return saveConfig(c.configPath, c.config)
+	}
+}
+
+func readPassword() (string, error) {
+
+	if password := os.Getenv("PICNIC_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+
+	// en una terminal sin eco, si viene de un pipe se lee la linea
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if len(password) == 0 {
+			return "", errors.New("no password given")
+		}
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// newestToken is the id of the token just created, so logout can revoke it.
+// 0 if it can't tell.
+func newestToken(ctx context.Context, api *client.Client) int {
+
+	tokens, err := api.ListTokens(ctx)
+	if err != nil {
+		return 0
+	}
+	newest := 0
+	for _, token := range tokens {
+		if token.Name == tokenName && token.ID > newest {
+			newest = token.ID
+		}
+	}
+	return newest
+}
+
+func logoutFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		if err := c.loggedIn(); err != nil {
+			return err
+		}
+		// un token pegado con --token no es nuestro, solo se olvida
+		if c.config.TokenID != 0 {
+			if err := c.api.RevokeToken(ctx, c.config.TokenID); err != nil && !errors.Is(err, client.ErrUnauthorized) {
+				return err
+			}
+		}
+
+		c.config.Token = ""
+		c.config.TokenID = 0
+		return saveConfig(c.configPath, c.config)
+	}
+}
+
+func picnicsFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		picnics, err := c.api.ListPicnics(ctx)
+		if err != nil {
+			return err
+		}
+		return c.print(picnicsTable(picnics))
+	}
+}
+
+func picnicsTable(picnics []models.Picnic) table {
+
+	t := table{header: []string{"id", "name", "date", "location", "capacity"}, data: picnics}
+	for _, picnic := range picnics {
+		capacity := ""
+		if picnic.Capacity > 0 {
+			capacity = strconv.Itoa(picnic.Capacity)
+		}
+		t.rows = append(t.rows, []string{strconv.Itoa(picnic.ID), picnic.Name, picnic.Date, picnic.Location, capacity})
+	}
+	return t
+}
+
+func createFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+
+	name := fs.String("name", "", "name of the picnic (required)")
+	location := fs.String("location", "", "where it is")
+	date := fs.String("date", "", "when, 2006-01-02 15:04")
+	capacity := fs.Int("capacity", 0, "maximum attendees, 0 is no limit")
+
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		if *name == "" {
+			return usageError("--name is required")
+		}
+		if err := c.loggedIn(); err != nil {
+			return err
+		}
+
+		picnic, err := c.api.CreatePicnic(ctx, models.Picnic{Name: *name, Location: *location, Date: *date, Capacity: *capacity})
+		if err != nil {
+			return err
+		}
+		table := picnicsTable([]models.Picnic{picnic})
+		table.data = picnic
+		return c.print(table)
+	}
+}
+
+func inviteFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+
+	var users intList
+	fs.Var(&users, "user", "id of the user to invite, can be repeated")
+	hours := fs.Int("hours", 0, "hours until the invitation expires, the server default if 0")
+
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		picnicID, err := picnicArg(args)
+		if err != nil {
+			return err
+		}
+		if err := c.loggedIn(); err != nil {
+			return err
+		}
+
+		// sin usuarios es un link que cualquiera puede aceptar
+		if len(users) == 0 {
+			users = intList{0}
+		}
+
+		invites := make([]client.Invite, 0)
+		t := table{header: []string{"id", "user_id", "expires_at", "url"}}
+		for _, userID := range users {
+			invite, err := c.api.InviteToPicnic(ctx, picnicID, userID, time.Duration(*hours)*time.Hour)
+			if err != nil {
+				return err
+			}
+			invite.URL = c.config.URL + invite.URL
+			invites = append(invites, invite)
+
+			user := ""
+			if userID != 0 {
+				user = strconv.Itoa(userID)
+			}
+			t.rows = append(t.rows, []string{strconv.Itoa(invite.Invitation.ID), user, invite.Invitation.ExpiresAt, invite.URL})
+		}
+		t.data = invites
+		return c.print(t)
+	}
+}
+
+// brings is one line of "who brings what"
+type brings struct {
+	UserID   int    `json:"user_id"`
+	User     string `json:"user"`
+	Kind     string `json:"kind"`
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+	Measure  string `json:"measure,omitempty"`
+}
+
+func whoFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		picnicID, err := picnicArg(args)
+		if err != nil {
+			return err
+		}
+		if err := c.loggedIn(); err != nil {
+			return err
+		}
+
+		list, err := whoBringsWhat(ctx, c.api, picnicID)
+		if err != nil {
+			return err
+		}
+
+		t := table{header: []string{"user", "kind", "item", "quantity", "measure"}, data: list}
+		for _, item := range list {
+			t.rows = append(t.rows, []string{item.User, item.Kind, item.Item, strconv.Itoa(item.Quantity), item.Measure})
+		}
+		return c.print(t)
+	}
+}
+
+func whoBringsWhat(ctx context.Context, api *client.Client, picnicID int) ([]brings, error) {
+
+	items, err := api.PicnicList(ctx, picnicID)
+	if err != nil {
+		return nil, err
+	}
+	names, err := userNames(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]brings, 0, len(items))
+	for _, item := range items {
+		list = append(list, brings{
+			UserID:   item.UserID,
+			User:     names.of(item.UserID),
+			Kind:     item.Kind,
+			Item:     item.Name,
+			Quantity: item.Quantity,
+			Measure:  item.Measure,
+		})
+	}
+	return list, nil
+}
+
+type nameIndex map[int]string
+
+func userNames(ctx context.Context, api *client.Client) (nameIndex, error) {
+
+	users, err := api.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(nameIndex, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+	return names, nil
+}
+
+func (n nameIndex) of(userID int) string {
+	if name, ok := n[userID]; ok {
+		return name
+	}
+	return "#" + strconv.Itoa(userID)
+}
+
+func contributeFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+
+	food := fs.String("food", "", "food item, its id or name (required)")
+	quantity := fs.Int("quantity", 1, "how many")
+	paid := fs.String("paid", "", "amount paid, 4.50")
+	paidBy := fs.Int("paid-by", 0, "id of who paid, you by default")
+	userID := fs.Int("user", 0, "id of who brings it, you by default")
+
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		picnicID, err := picnicArg(args)
+		if err != nil {
+			return err
+		}
+		if *food == "" {
+			return usageError("--food is required")
+		}
+		if *quantity <= 0 {
+			return usageError("--quantity must be positive")
+		}
+		amount, err := i18n.ParseMoney(i18n.English, *paid)
+		if err != nil {
+			return usageError(err.Error())
+		}
+		if err := c.loggedIn(); err != nil {
+			return err
+		}
+
+		item, err := findFoodItem(ctx, c.api, *food)
+		if err != nil {
+			return err
+		}
+
+		if *userID == 0 {
+			me, err := c.api.Me(ctx)
+			if err != nil {
+				return err
+			}
+			*userID = me.ID
+		}
+
+		contribution, err := c.api.AddContribution(ctx, models.Contribution{
+			UserID:     *userID,
+			PicnicID:   picnicID,
+			FoodItemID: item.ID,
+			Quantity:   *quantity,
+			PaidBy:     *paidBy,
+			AmountPaid: amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.print(table{
+			header: []string{"id", "user_id", "item", "quantity", "measure", "paid"},
+			rows: [][]string{{strconv.Itoa(contribution.ID), strconv.Itoa(contribution.UserID), item.Name,
+				strconv.Itoa(contribution.Quantity), item.Measure, money(contribution.AmountPaid)}},
+			data: contribution,
+		})
+	}
+}
+
+// findFoodItem takes an id or a name, names are matched without case
+func findFoodItem(ctx context.Context, api *client.Client, food string) (models.FoodItem, error) {
+
+	if id, err := strconv.Atoi(food); err == nil {
+		return api.GetFoodItem(ctx, id)
+	}
+
+	items, err := api.ListFoodItems(ctx)
+	if err != nil {
+		return models.FoodItem{}, err
+	}
+	for _, item := range items {
+		if strings.EqualFold(item.Name, food) {
+			return item, nil
+		}
+	}
+	return models.FoodItem{}, fmt.Errorf("no food item named %q, see picnic foods", food)
+}
+
+// summary is the JSON of picnic summary, the table and CSV have a row per
+// attendee
+type summary struct {
+	Picnic    models.Picnic     `json:"picnic"`
+	Attendees []attendee        `json:"attendees"`
+	Transfers []models.Transfer `json:"transfers"`
+	Items     []brings          `json:"items"`
+	names     nameIndex
+}
+
+type attendee struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Brings  []string `json:"brings"`
+	Paid    int      `json:"paid"`
+	Share   int      `json:"share"`
+	Balance int      `json:"balance"`
+}
+
+func summaryFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		picnicID, err := picnicArg(args)
+		if err != nil {
+			return err
+		}
+		if err := c.loggedIn(); err != nil {
+			return err
+		}
+
+		s, err := loadSummary(ctx, c.api, picnicID)
+		if err != nil {
+			return err
+		}
+
+		t := table{header: []string{"user", "brings", "paid", "share", "balance"}, data: s}
+		for _, a := range s.Attendees {
+			t.rows = append(t.rows, []string{a.Name, strings.Join(a.Brings, "; "), money(a.Paid), money(a.Share), money(a.Balance)})
+		}
+		if c.format != "table" {
+			return c.print(t)
+		}
+
+		fmt.Printf("%s\n%s  %s\n\n", s.Picnic.Name, s.Picnic.Date, s.Picnic.Location)
+		if err := c.print(t); err != nil {
+			return err
+		}
+		if len(s.Transfers) == 0 {
+			return nil
+		}
+
+		fmt.Println()
+		transfers := table{header: []string{"from", "to", "amount"}}
+		for _, transfer := range s.Transfers {
+			transfers.rows = append(transfers.rows, []string{s.names.of(transfer.FromUserID), s.names.of(transfer.ToUserID), money(transfer.Amount)})
+		}
+		return c.print(transfers)
+	}
+}
+
+func loadSummary(ctx context.Context, api *client.Client, picnicID int) (summary, error) {
+
+	s := summary{Attendees: make([]attendee, 0)}
+	var err error
+
+	if s.Picnic, err = api.GetPicnic(ctx, picnicID); err != nil {
+		return s, err
+	}
+	users, err := api.ListUsersOfPicnic(ctx, picnicID)
+	if err != nil {
+		return s, err
+	}
+	if s.Items, err = whoBringsWhat(ctx, api, picnicID); err != nil {
+		return s, err
+	}
+	settleUp, err := api.PicnicBalances(ctx, picnicID)
+	if err != nil {
+		return s, err
+	}
+	s.Transfers = settleUp.Transfers
+	if s.Transfers == nil {
+		s.Transfers = make([]models.Transfer, 0)
+	}
+
+	s.names = make(nameIndex)
+	for _, item := range s.Items {
+		s.names[item.UserID] = item.User
+	}
+	for _, user := range users {
+		s.names[user.ID] = user.Name
+	}
+
+	balances := make(map[int]models.Balance)
+	for _, balance := range settleUp.Balances {
+		balances[balance.UserID] = balance
+	}
+
+	for _, user := range users {
+		a := attendee{ID: user.ID, Name: user.Name, Brings: make([]string, 0)}
+		for _, item := range s.Items {
+			if item.UserID == user.ID {
+				a.Brings = append(a.Brings, fmt.Sprintf("%s (%s)", item.Item, strings.TrimSpace(fmt.Sprintf("%d %s", item.Quantity, item.Measure))))
+			}
+		}
+		balance := balances[user.ID]
+		a.Paid, a.Share, a.Balance = balance.Paid, balance.Share, balance.Balance
+		s.Attendees = append(s.Attendees, a)
+	}
+	return s, nil
+}
+
+func usersFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		users, err := c.api.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+		t := table{header: []string{"id", "name"}, data: users}
+		for _, user := range users {
+			t.rows = append(t.rows, []string{strconv.Itoa(user.ID), user.Name})
+		}
+		return c.print(t)
+	}
+}
+
+func foodsFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		items, err := c.api.ListFoodItems(ctx)
+		if err != nil {
+			return err
+		}
+		t := table{header: []string{"id", "name", "measure", "url"}, data: items}
+		for _, item := range items {
+			t.rows = append(t.rows, []string{strconv.Itoa(item.ID), item.Name, item.Measure, item.Url})
+		}
+		return c.print(t)
+	}
+}
+
+func picnicArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, usageError("expected the picnic id")
+	}
+	return parseID(args[0])
+}
+
+func parseID(value string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || id <= 0 {
+		return 0, usageError(fmt.Sprintf("%q is not a valid id", value))
+	}
+	return id, nil
+}
+
+// money prints cents as 4.50, the same in every format so the CSV can be
+// read back
+func money(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func completionFlags(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+
+		if len(args) != 1 {
+			return usageError("expected bash, zsh or fish")
+		}
+		switch args[0] {
+		case "bash":
+			writeBashCompletion(os.Stdout)
+		case "zsh":
+			// zsh entiende el script de bash con bashcompinit
+			fmt.Fprintln(os.Stdout, "autoload -U +X bashcompinit && bashcompinit")
+			writeBashCompletion(os.Stdout)
+		case "fish":
+			writeFishCompletion(os.Stdout)
+		default:
+			return usageError(fmt.Sprintf("unknown shell %q", args[0]))
+		}
+		return nil
+	}
+}
+
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// the scripts complete commands, their flags and the values --format and
+// completion take, ids are left to the user
+func writeBashCompletion(w io.Writer) {
+
+	fmt.Fprintln(w, "_picnic() {")
+	fmt.Fprintln(w, `  local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, "  if [ \"$COMP_CWORD\" -eq 1 ]; then")
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintln(w, "    return")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "  case \"$prev\" in")
+	fmt.Fprintln(w, "    --format|-format) COMPREPLY=($(compgen -W \"table json csv\" -- \"$cur\")); return ;;")
+	fmt.Fprintln(w, "    --config|-config) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;")
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "  case \"${COMP_WORDS[1]}\" in")
+	for _, cmd := range commands {
+		words := flagNames(cmd)
+		if cmd.name == "completion" {
+			words = append(words, "bash", "zsh", "fish")
+		}
+		fmt.Fprintf(w, "    %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", cmd.name, strings.Join(words, " "))
+	}
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _picnic picnic")
+}
+
+func writeFishCompletion(w io.Writer) {
+
+	fmt.Fprintln(w, "complete -c picnic -f")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c picnic -n __fish_use_subcommand -a %s -d %q\n", cmd.name, cmd.summary)
+		for _, name := range flagNames(cmd) {
+			fmt.Fprintf(w, "complete -c picnic -n '__fish_seen_subcommand_from %s' -l %s\n", cmd.name, strings.TrimPrefix(name, "--"))
+		}
+	}
+	fmt.Fprintln(w, "complete -c picnic -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'")
+	fmt.Fprintln(w, "complete -c picnic -l format -x -a 'table json csv'")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// config is the JSON file login writes:
+//
+//	{"url": "https://picnics.example.com", "token": "...", "language": "es"}
+type config struct {
+	URL   string `json:"url"`
+	Token string `json:"token,omitempty"`
+	// TokenID is set when login created the token, logout revokes it
+	TokenID  int    `json:"token_id,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// configPath is $PICNIC_CONFIG or picnic/config.json in the user config dir,
+// ~/.config/picnic/config.json on Linux.
+func configPath() (string, error) {
+	if path := os.Getenv("PICNIC_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "picnic", "config.json"), nil
+}
+
+// loadConfig returns the defaults when the file doesn't exist yet
+func loadConfig(path string) (config, error) {
+
+	cfg := config{URL: defaultURL}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, errors.New(path + ": " + err.Error())
+	}
+	if cfg.URL == "" {
+		cfg.URL = defaultURL
+	}
+	return cfg, nil
+}
+
+// saveConfig only lets the owner read the file, it holds the token
+func saveConfig(path string, cfg config) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,222 @@
+// picnic manages picnics from the terminal through the /api/v1 routes:
+//
+//	picnic login --url https://picnics.example.com --name ana
+//	picnic create --name "Parque" --date "2026-11-21 13:00" --capacity 20
+//	picnic invite 3 --user 7 --user 8
+//	picnic who 3
+//	picnic contribute 3 --food Pan --quantity 2 --paid 4.50
+//	picnic summary 3 --format csv > picnic.csv
+//
+// Every command takes --format table, json or csv. login saves an API token
+// in the config file, see configPath. Shell completion:
+//
+//	source <(picnic completion bash)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"server/client"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	flags   func(fs *flag.FlagSet) func(ctx context.Context, cli *cli, args []string) error
+}
+
+// commands is also what completion offers, keep the flags registered in
+// their flags func. Filled in init because completion reads it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"login", "", "Log in and save an API token in the config file", loginFlags},
+		{"logout", "", "Forget the saved token", logoutFlags},
+		{"picnics", "", "List picnics", picnicsFlags},
+		{"create", "", "Create a picnic, you become its owner", createFlags},
+		{"invite", "PICNIC_ID", "Invite users, without --user prints a link anyone can use", inviteFlags},
+		{"who", "PICNIC_ID", "List who brings what", whoFlags},
+		{"contribute", "PICNIC_ID", "Add a contribution", contributeFlags},
+		{"summary", "PICNIC_ID", "Export attendees, what they bring and who owes whom", summaryFlags},
+		{"users", "", "List users", usersFlags},
+		{"foods", "", "List food items", foodsFlags},
+		{"completion", "bash|zsh|fish", "Print the shell completion script", completionFlags},
+	}
+}
+
+// cli is what every command gets, built from the common flags
+type cli struct {
+	config     config
+	configPath string
+	format     string
+	api        *client.Client
+}
+
+func main() {
+
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := findCommand(os.Args[1])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "picnic: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	c := &cli{}
+	fs := flag.NewFlagSet("picnic "+cmd.name, flag.ExitOnError)
+	fs.StringVar(&c.format, "format", "table", "output format: table, json or csv")
+	fs.StringVar(&c.configPath, "config", "", "config file, $PICNIC_CONFIG or the user config dir by default")
+	run := cmd.flags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: picnic %s [flags] %s\n\n%s\n\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	args := parseInterspersed(fs, os.Args[2:])
+
+	if err := c.setup(); err != nil {
+		fmt.Fprintln(os.Stderr, "picnic:", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, c, args); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "picnic %s: %s\n\n", cmd.name, usageErr)
+			fs.Usage()
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "picnic:", err)
+		os.Exit(1)
+	}
+}
+
+func (c *cli) setup() error {
+
+	switch c.format {
+	case "table", "json", "csv":
+	default:
+		return usageError(fmt.Sprintf("unknown format %q", c.format))
+	}
+
+	if c.configPath == "" {
+		path, err := configPath()
+		if err != nil {
+			return err
+		}
+		c.configPath = path
+	}
+
+	var err error
+	c.config, err = loadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	c.api = client.New(c.config.URL)
+	c.api.Token = c.config.Token
+	c.api.Language = c.config.Language
+	return nil
+}
+
+// loggedIn fails early with a hint instead of a 401 from the server
+func (c *cli) loggedIn() error {
+	if c.config.Token == "" {
+		return fmt.Errorf("not logged in, run picnic login first")
+	}
+	return nil
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: picnic <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run picnic <command> -h for its flags.")
+}
+
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// parseInterspersed lets flags go after the positional args too,
+// "picnic who 3 --format csv", the flag package stops at the first one.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+
+	positional := make([]string, 0)
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// flagNames are the flags of a command for completion
+func flagNames(cmd command) []string {
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.String("format", "", "")
+	fs.String("config", "", "")
+	cmd.flags(fs)
+
+	names := make([]string, 0)
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, "--"+f.Name)
+	})
+	sort.Strings(names)
+	return names
+}
+
+// intList is a flag that can be repeated, --user 7 --user 8 or --user 7,8
+type intList []int
+
+func (l *intList) String() string {
+	parts := make([]string, len(*l))
+	for i, n := range *l {
+		parts[i] = fmt.Sprint(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *intList) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		id, err := parseID(part)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, id)
+	}
+	return nil
+}
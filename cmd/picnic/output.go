@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// table is what a command prints. JSON prints data as is, table and CSV
+// print header and rows.
+type table struct {
+	header []string
+	rows   [][]string
+	data   interface{}
+}
+
+func (c *cli) print(t table) error {
+	return printTo(os.Stdout, c.format, t)
+}
+
+func printTo(w io.Writer, format string, t table) error {
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(t.data)
+
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write(t.header)
+		writer.WriteAll(t.rows)
+		return writer.Error()
+
+	default:
+		if len(t.rows) == 0 {
+			_, err := fmt.Fprintln(w, "(none)")
+			return err
+		}
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(t.header, "\t")))
+		for _, row := range t.rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	}
+}
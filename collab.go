@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"server/collab"
+	"server/i18n"
+	"server/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var collabHub = newCollabHub()
+
+func newCollabHub() *collab.Hub {
+	hub := collab.NewHub()
+	hub.Handle = handleCollabMessage
+	hub.OnJoin = sendCollabSnapshot
+	return hub
+}
+
+// GET /picnics/:picnic_id/session upgrades to a WebSocket planning session.
+//
+// Clients send:
+//
+//	{"type": "editing", "contribution_id": 3}
+//	{"type": "update", "ref": "abc", "contribution": {"id": 3, "quantity": 4, "version": 2}}
+//	{"type": "snapshot"}
+//
+// and get "snapshot", "presence", "event" (everything live publishes for the
+// picnic), "ack", "conflict" and "error" messages back.
+func collabSession(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	user, _ := currentUser(c)
+
+	err = collabHub.Serve(c.Writer, c.Request, picnicID, collab.Member{UserID: user.ID, Name: user.Name, Lang: langOf(c)})
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": trErr(c, err)})
+	}
+}
+
+type collabMessage struct {
+	Type           string          `json:"type"`
+	Ref            string          `json:"ref,omitempty"`
+	ContributionID int             `json:"contribution_id,omitempty"`
+	Contribution   json.RawMessage `json:"contribution,omitempty"`
+}
+
+func handleCollabMessage(client *collab.Client, message []byte) {
+
+	var msg collabMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		client.Send(gin.H{"type": "error", "error": i18n.T(client.Lang(), "Invalid message")})
+		return
+	}
+
+	switch msg.Type {
+	case "editing":
+		client.SetEditing(msg.ContributionID)
+	case "snapshot":
+		sendCollabSnapshot(client)
+	case "update":
+		updateContributionLive(client, msg)
+	default:
+		client.Send(gin.H{"type": "error", "ref": msg.Ref, "error": i18n.T(client.Lang(), "Unknown message type %q", msg.Type)})
+	}
+}
+
+func sendCollabSnapshot(client *collab.Client) {
+
+	contributions, err := models.GetContributionsByPicnic(client.PicnicID)
+	if err != nil {
+		client.Send(gin.H{"type": "error", "error": i18n.T(client.Lang(), "Failed to retrieve contributions")})
+		return
+	}
+
+	lastEventID, err := models.LastPicnicEventId(client.PicnicID)
+	if err != nil {
+		client.Send(gin.H{"type": "error", "error": i18n.T(client.Lang(), "Failed to retrieve contributions")})
+		return
+	}
+
+	client.Send(gin.H{"type": "snapshot", "contributions": contributions, "last_event_id": lastEventID})
+}
+
+// updateContributionLive applies a partial edit on top of the stored row. The
+// edit carries the version it was made against, if somebody saved first the
+// client gets a conflict with the current row and nothing is written.
+func updateContributionLive(client *collab.Client, msg collabMessage) {
+
+	var patch struct {
+		ID      int `json:"id"`
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(msg.Contribution, &patch); err != nil || patch.ID == 0 || patch.Version == 0 {
+		client.Send(gin.H{"type": "error", "ref": msg.Ref, "error": i18n.T(client.Lang(), "update needs the contribution id and version")})
+		return
+	}
+
+	current, err := models.GetContributionById(patch.ID)
+	if err != nil || current.ID == 0 || current.PicnicID != client.PicnicID {
+		client.Send(gin.H{"type": "error", "ref": msg.Ref, "error": i18n.T(client.Lang(), "Contribution of that id not found")})
+		return
+	}
+
+	updated := current
+	if err := json.Unmarshal(msg.Contribution, &updated); err != nil {
+		client.Send(gin.H{"type": "error", "ref": msg.Ref, "error": i18n.Translate(client.Lang(), err)})
+		return
+	}
+	// en una sesion no se mueven contribuciones a otro picnic
+	updated.ID = current.ID
+	updated.PicnicID = current.PicnicID
+
+	for _, contribution := range []models.Contribution{current, updated} {
+		allowed, err := mayManageContribution(client.UserID(), contribution)
+		if err != nil || !allowed {
+			client.Send(gin.H{"type": "error", "ref": msg.Ref, "error": i18n.T(client.Lang(), "You can only manage your own contributions")})
+			return
+		}
+	}
+
+	saved, err := models.UpdateContributionAtVersion(updated, current.ID, patch.Version)
+	if err == models.ErrVersionConflict && saved.ID == 0 {
+		client.Send(gin.H{"type": "error", "ref": msg.Ref, "error": i18n.T(client.Lang(), "Contribution of that id not found")})
+		return
+	}
+	if err == models.ErrVersionConflict {
+		client.Send(gin.H{"type": "conflict", "ref": msg.Ref, "contribution": saved})
+		return
+	}
+	if err != nil {
+		client.Send(gin.H{"type": "error", "ref": msg.Ref, "error": i18n.Translate(client.Lang(), err)})
+		return
+	}
+
+	client.Send(gin.H{"type": "ack", "ref": msg.Ref, "contribution": saved})
+	contributionUpdated(current, saved, client.UserID())
+}
@@ -0,0 +1,351 @@
+// Package collab runs the WebSocket planning sessions, one room per picnic.
+// Everyone in a room sees who else is connected and what they are editing,
+// and every event published to the picnic through live is forwarded to them.
+//
+// What clients may send is up to the Hub's Handle func, the hub only moves
+// JSON messages around.
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"server/live"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+
+	// mensajes mas grandes que esto cierran la conexion
+	maxMessageSize = 8 * 1024
+	// un cliente que se atrasa mas de esto se desconecta
+	sendBuffer = 64
+)
+
+var ErrClosed = errors.New("collab: hub is shutting down")
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// Member is what the other people in the room see of a client
+type Member struct {
+	UserID  int    `json:"user_id"`
+	Name    string `json:"name"`
+	Editing int    `json:"editing,omitempty"`
+	// Lang is the language of the error messages sent to this member
+	Lang string `json:"-"`
+}
+
+type Client struct {
+	PicnicID int
+
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan []byte
+	member Member
+
+	mu   sync.Mutex
+	done bool
+}
+
+func (c *Client) UserID() int {
+	return c.member.UserID
+}
+
+func (c *Client) Lang() string {
+	return c.member.Lang
+}
+
+// Send queues v for this client only. A client whose buffer is full is
+// disconnected, it will get a fresh snapshot when it reconnects.
+func (c *Client) Send(v interface{}) {
+
+	message, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("collab: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done {
+		return
+	}
+	select {
+	case c.send <- message:
+	default:
+		c.conn.Close()
+	}
+}
+
+// SetEditing tells the room which contribution this client is working on,
+// 0 when none.
+func (c *Client) SetEditing(contributionID int) {
+	c.hub.mu.Lock()
+	c.member.Editing = contributionID
+	c.hub.mu.Unlock()
+
+	c.hub.broadcastPresence(c.PicnicID)
+}
+
+type room struct {
+	clients map[*Client]struct{}
+	stop    chan struct{}
+}
+
+type Hub struct {
+	// Handle gets every message a client sends, OnJoin runs once the client
+	// is in the room.
+	Handle func(client *Client, message []byte)
+	OnJoin func(client *Client)
+
+	mu     sync.Mutex
+	rooms  map[int]*room
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[int]*room)}
+}
+
+// Serve upgrades the request and blocks until the client leaves
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, picnicID int, member Member) error {
+
+	h.mu.Lock()
+	closed := h.closed
+	h.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade ya respondio con el error
+		return nil
+	}
+
+	client := &Client{
+		PicnicID: picnicID,
+		hub:      h,
+		conn:     conn,
+		send:     make(chan []byte, sendBuffer),
+		member:   member,
+	}
+
+	if !h.join(client) {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(writeWait))
+		conn.Close()
+		return nil
+	}
+	defer h.leave(client)
+
+	go client.writePump()
+
+	if h.OnJoin != nil {
+		h.OnJoin(client)
+	}
+	h.broadcastPresence(picnicID)
+
+	client.readPump()
+	return nil
+}
+
+func (h *Hub) join(client *Client) bool {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+
+	r := h.rooms[client.PicnicID]
+	if r == nil {
+		r = &room{clients: make(map[*Client]struct{}), stop: make(chan struct{})}
+		h.rooms[client.PicnicID] = r
+		h.wg.Add(1)
+		go h.forward(client.PicnicID, r.stop)
+	}
+	r.clients[client] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+func (h *Hub) leave(client *Client) {
+
+	h.mu.Lock()
+	if r := h.rooms[client.PicnicID]; r != nil {
+		delete(r.clients, client)
+		if len(r.clients) == 0 {
+			close(r.stop)
+			delete(h.rooms, client.PicnicID)
+		}
+	}
+	h.mu.Unlock()
+
+	client.mu.Lock()
+	client.done = true
+	close(client.send)
+	client.mu.Unlock()
+
+	h.broadcastPresence(client.PicnicID)
+	h.wg.Done()
+}
+
+// Members returns who is connected to picnicID right now
+func (h *Hub) Members(picnicID int) []Member {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	members := make([]Member, 0)
+	if r := h.rooms[picnicID]; r != nil {
+		for client := range r.clients {
+			members = append(members, client.member)
+		}
+	}
+	return members
+}
+
+// Broadcast sends v to everyone in the picnic's room
+func (h *Hub) Broadcast(picnicID int, v interface{}) {
+
+	h.mu.Lock()
+	clients := make([]*Client, 0)
+	if r := h.rooms[picnicID]; r != nil {
+		for client := range r.clients {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.Send(v)
+	}
+}
+
+func (h *Hub) broadcastPresence(picnicID int) {
+	h.Broadcast(picnicID, map[string]interface{}{"type": "presence", "members": h.Members(picnicID)})
+}
+
+// forward relays the picnic's live events to the room until it empties
+func (h *Hub) forward(picnicID int, stop chan struct{}) {
+	defer h.wg.Done()
+
+	subscriber := live.DefaultBroker.Subscribe(picnicID)
+	defer func() { live.DefaultBroker.Unsubscribe(subscriber) }()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-subscriber.Dropped():
+			// se perdieron eventos, los clientes tienen que pedir un snapshot
+			live.DefaultBroker.Unsubscribe(subscriber)
+			subscriber = live.DefaultBroker.Subscribe(picnicID)
+			h.Broadcast(picnicID, map[string]interface{}{"type": "resync"})
+		case event := <-subscriber.Events:
+			h.Broadcast(picnicID, map[string]interface{}{
+				"type":  "event",
+				"id":    event.ID,
+				"event": event.Event,
+				"data":  json.RawMessage(event.Data),
+			})
+		}
+	}
+}
+
+// Shutdown says goodbye to every client and waits for their goroutines
+func (h *Hub) Shutdown(ctx context.Context) error {
+
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*Client, 0)
+	for _, r := range h.rooms {
+		for client := range r.clients {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.Unlock()
+
+	goingAway := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, client := range clients {
+		client.conn.WriteControl(websocket.CloseMessage, goingAway, time.Now().Add(writeWait))
+		client.conn.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) readPump() {
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				log.Printf("collab: picnic %d user %d: %v", c.PicnicID, c.member.UserID, err)
+			}
+			return
+		}
+		if c.hub.Handle != nil {
+			c.hub.Handle(c, message)
+		}
+	}
+}
+
+func (c *Client) writePump() {
+
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialSession opens the planning session of picnicID as u
+func dialSession(t *testing.T, u testUser, picnicID int) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(testRouter)
+	t.Cleanup(server.Close)
+
+	url := fmt.Sprintf("ws%s/api/v1/picnics/%d/session", strings.TrimPrefix(server.URL, "http"), picnicID)
+	header := http.Header{"Cookie": {sessionCookie + "=" + u.session}}
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("dial: %v (%v)", err, resp)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntil reads messages until one makes done true
+func readUntil(t *testing.T, conn *websocket.Conn, done func(msg map[string]interface{}) bool) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if msg["type"] == "error" || msg["type"] == "conflict" {
+			t.Fatalf("got %v", msg)
+		}
+		if done(msg) {
+			return
+		}
+	}
+}
+
+func TestLiveEditNotifies(t *testing.T) {
+
+	p := newRolePicnic(t)
+
+	models.AllowPrivateWebhooks = true
+	webhook, err := models.CreateWebhook(models.Webhook{UserID: p.users["owner"].ID, URL: "http://127.0.0.1:9/hook", Events: []string{models.EventContributionUpdated}})
+	models.AllowPrivateWebhooks = false
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialSession(t, p.users["co-host"], p.picnicID)
+	err = conn.WriteJSON(map[string]interface{}{
+		"type":         "update",
+		"ref":          "q",
+		"contribution": map[string]int{"id": p.contribution.ID, "version": 1, "quantity": 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// el evento sale despues del mail y del webhook
+	acked, published := false, false
+	readUntil(t, conn, func(msg map[string]interface{}) bool {
+		acked = acked || msg["type"] == "ack" && msg["ref"] == "q"
+		published = published || msg["type"] == "event" && msg["event"] == models.EventContributionUpdated
+		return acked && published
+	})
+
+	if sent := sentMail(t, p.bringer); len(sent) != 1 || sent[0].Subject != "Your contribution to Roles changed" {
+		t.Errorf("the bringer got %d mails", len(sent))
+	}
+
+	deliveries, err := models.GetDeliveriesByWebhook(webhook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Event != models.EventContributionUpdated {
+		t.Errorf("got deliveries %v", deliveries)
+	}
+}
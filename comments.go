@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"server/live"
+	"server/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxCommentsPage = 200
+
+// requireComment loads :comment_id, it has to belong to :picnic_id. Only the
+// author may edit, owners and co-hosts may also delete.
+func requireComment(editing bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+			return
+		}
+
+		id, err := strconv.Atoi(c.Param("comment_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid comment ID")})
+			return
+		}
+
+		comment, err := models.GetCommentById(id)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if comment.ID == 0 || comment.PicnicID != picnicID {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": tr(c, "Comment of that id not found")})
+			return
+		}
+
+		userID, _ := currentUserID(c)
+		if comment.UserID != userID {
+			role, err := models.GetRole(userID, picnicID)
+			if err != nil {
+				serverError(c, err)
+				return
+			}
+
+			if editing || (role != models.RoleOwner && role != models.RoleCoHost) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only change your own comments")})
+				return
+			}
+		}
+
+		c.Set("comment", comment)
+		c.Next()
+	}
+}
+
+// GET /picnics/:picnic_id/comments?contribution_id=&after=&limit=
+// Oldest first, pass next_after back as after to get the next page.
+func readAllCommentsOfPicnic(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	contributionID, err := strconv.Atoi(c.DefaultQuery("contribution_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid contribution ID")})
+		return
+	}
+
+	after, err := strconv.Atoi(c.DefaultQuery("after", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid after")})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > maxCommentsPage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "limit must be between 1 and %d", maxCommentsPage)})
+		return
+	}
+
+	comments, err := models.GetCommentsByPicnic(picnicID, contributionID, after, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve comments")})
+		return
+	}
+
+	response := gin.H{"data": comments}
+	if len(comments) == limit {
+		response["next_after"] = comments[len(comments)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func addComment(c *gin.Context) {
+
+	var json models.Comment
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	json.PicnicID = picnicID
+	json.UserID, _ = currentUserID(c)
+
+	comment, err := models.CreateComment(json)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	live.Publish(picnicID, models.EventCommentCreated, comment)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": comment})
+}
+
+func updateComment(c *gin.Context) {
+
+	var json models.Comment
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	comment := c.MustGet("comment").(models.Comment)
+
+	updated, err := models.UpdateComment(json, comment.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	live.Publish(updated.PicnicID, models.EventCommentUpdated, updated)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": updated})
+}
+
+func deleteComment(c *gin.Context) {
+
+	comment := c.MustGet("comment").(models.Comment)
+
+	success, err := models.DeleteComment(comment.ID)
+
+	if success {
+		live.Publish(comment.PicnicID, models.EventCommentDeleted, gin.H{"id": comment.ID, "picnic_id": comment.PicnicID})
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"server/live"
+	"server/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// con mas eventos pendientes que esto el cliente recibe "reset" y recarga todo
+	maxEventReplay = 500
+	// un cliente que no lee en este tiempo se desconecta
+	eventWriteTimeout = 10 * time.Second
+	eventHeartbeat    = 25 * time.Second
+)
+
+// GET /picnics/:picnic_id/events, Server-Sent Events with Last-Event-ID resume.
+// Browsers send the header on reconnect, ?last_event_id= works for the first
+// connection. Without either the stream starts at the latest event, only a
+// resume replays the log.
+func streamPicnicEvents(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	lastEventID := 0
+	if lastID != "" {
+		lastEventID, err = strconv.Atoi(lastID)
+		if err != nil || lastEventID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid Last-Event-ID")})
+			return
+		}
+	}
+
+	// primero suscribirse y despues leer el log, asi no se pierde nada entre medio
+	subscriber := live.DefaultBroker.Subscribe(picnicID)
+	defer live.DefaultBroker.Unsubscribe(subscriber)
+
+	if lastID == "" {
+		lastEventID, err = models.LastPicnicEventId(picnicID)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+	}
+
+	backlog, err := models.GetPicnicEventsAfter(picnicID, lastEventID, maxEventReplay+1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve events")})
+		return
+	}
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	controller := http.NewResponseController(c.Writer)
+	writeRaw := func(data string) bool {
+		controller.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if _, err := c.Writer.WriteString(data); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+	write := func(event sse.Event) bool {
+		controller.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if err := sse.Encode(c.Writer, event); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+
+	if !writeRaw("retry: 3000\n\n") {
+		return
+	}
+
+	if len(backlog) > maxEventReplay {
+		last, err := models.LastPicnicEventId(picnicID)
+		if err != nil {
+			// ya se mando el 200, el cliente reconecta con Last-Event-ID
+			log.Printf("events of picnic %d: %v", picnicID, err)
+			return
+		}
+		backlog = nil
+		lastEventID = last
+		if !write(sse.Event{Id: strconv.Itoa(last), Event: "reset", Data: "{}"}) {
+			return
+		}
+	}
+
+	for _, event := range backlog {
+		if !write(picnicSSEvent(event)) {
+			return
+		}
+		lastEventID = event.ID
+	}
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-subscriber.Dropped():
+			// se queda atras, que reconecte con Last-Event-ID
+			return
+		case <-heartbeat.C:
+			if !writeRaw(": ping\n\n") {
+				return
+			}
+		case event := <-subscriber.Events:
+			if event.ID <= lastEventID {
+				continue
+			}
+			if !write(picnicSSEvent(event)) {
+				return
+			}
+			lastEventID = event.ID
+		}
+	}
+}
+
+func picnicSSEvent(event models.PicnicEvent) sse.Event {
+	return sse.Event{Id: strconv.Itoa(event.ID), Event: event.Event, Data: event.Data}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server/live"
+	"server/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamEvents connects to the event stream of picnicID and sends the event
+// names it reads, lastEventID empty is a fresh connection
+func streamEvents(t *testing.T, u testUser, picnicID int, lastEventID string) <-chan string {
+	t.Helper()
+
+	server := httptest.NewServer(testRouter)
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/picnics/%d/events", server.URL, picnicID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: u.session})
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got %d", resp.StatusCode)
+	}
+
+	events := make(chan string, 16)
+	scanner := bufio.NewScanner(resp.Body)
+	// el retry llega antes que cualquier evento, desde ahi ya esta suscripto
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "retry:") {
+		t.Fatalf("stream starts with %q", scanner.Text())
+	}
+	go func() {
+		defer close(events)
+		for scanner.Scan() {
+			if name, ok := strings.CutPrefix(scanner.Text(), "event:"); ok {
+				events <- name
+			}
+		}
+	}()
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan string) string {
+	t.Helper()
+
+	select {
+	case name := <-events:
+		return name
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return ""
+	}
+}
+
+func TestEventsResumeOnlyWhenAsked(t *testing.T) {
+
+	p := newRolePicnic(t)
+	attendee := p.users["attendee"]
+
+	live.Publish(p.picnicID, "first", nil)
+	first, err := models.LastPicnicEventId(p.picnicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live.Publish(p.picnicID, "second", nil)
+
+	// sin Last-Event-ID no se repite nada de lo que ya paso
+	fresh := streamEvents(t, attendee, p.picnicID, "")
+	live.Publish(p.picnicID, "third", nil)
+	if name := nextEvent(t, fresh); name != "third" {
+		t.Errorf("fresh connection got %q first, want third", name)
+	}
+
+	resumed := streamEvents(t, attendee, p.picnicID, strconv.Itoa(first))
+	for _, want := range []string{"second", "third"} {
+		if name := nextEvent(t, resumed); name != want {
+			t.Errorf("resume got %q, want %s", name, want)
+		}
+	}
+}
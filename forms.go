@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"os"
+	"server/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	csrfCookie   = "picnic_csrf"
+	csrfField    = "_csrf"
+	csrfHeader   = "X-CSRF-Token"
+	csrfTokenKey = "csrf_token"
+	methodField  = "_method"
+
+	requestedWithHeader = "X-Requested-With"
+)
+
+// los forms HTML solo pueden mandar GET y POST, _method dice lo que querian
+var overrideMethods = map[string]string{
+	"put":    http.MethodPut,
+	"update": http.MethodPut,
+	"patch":  http.MethodPatch,
+	"delete": http.MethodDelete,
+}
+
+// methodOverride turns a POST form with _method=put/update/patch/delete into
+// that method. It wraps the whole router because gin picks the route before
+// any middleware runs. The API under /api/v1 gets real methods and is left
+// alone, and so are multipart uploads.
+func methodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && !strings.HasPrefix(r.URL.Path, "/api/") {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType == "application/x-www-form-urlencoded" && r.ParseForm() == nil {
+				if method, ok := overrideMethods[strings.ToLower(r.PostForm.Get(methodField))]; ok {
+					r.Method = method
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfProtect keeps a random token in a cookie and expects it back in the
+// _csrf field (or the X-CSRF-Token header) of every request that changes
+// something. Another site can make the browser send the cookie but can't
+// read it to fill the field. renderPage puts the token in every page for
+// the csrf_field template. The API goes through apiCSRFProtect instead.
+func csrfProtect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			apiCSRFProtect(c)
+			return
+		}
+
+		token, err := c.Cookie(csrfCookie)
+		fresh := err != nil || len(token) != 64
+		if fresh {
+			token = newCSRFToken()
+			setCSRFCookie(c, token)
+		}
+		c.Set(csrfTokenKey, token)
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		sent := c.PostForm(csrfField)
+		if sent == "" {
+			sent = c.GetHeader(csrfHeader)
+		}
+		if fresh || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			renderErrorPage(c, http.StatusForbidden, "This form has expired or didn't come from this site. Go back, reload the page and try again.")
+			return
+		}
+		c.Next()
+	}
+}
+
+// apiCSRFProtect: the API also takes the session cookie, so a write without
+// a bearer token must be JSON or carry X-Requested-With. A form on another
+// site can send neither, a script needs CORS for them and the API has none.
+func apiCSRFProtect(c *gin.Context) {
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		c.Next()
+		return
+	}
+	if c.GetHeader("Authorization") != "" || c.GetHeader(requestedWithHeader) != "" {
+		c.Next()
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "application/json" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "Requests with the session cookie must be JSON or send X-Requested-With")})
+		return
+	}
+	c.Next()
+}
+
+func newCSRFToken() string {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		checkErr(err)
+	}
+	return hex.EncodeToString(random)
+}
+
+func setCSRFCookie(c *gin.Context, token string) {
+	secure := os.Getenv("SESSION_COOKIE_INSECURE") == ""
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(csrfCookie, token, int(models.SessionDuration.Seconds()), "/", "", secure, true)
+}
+
+// notFound answers unknown routes, JSON for the API and a page for the rest
+func notFound(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Not found")})
+		return
+	}
+	renderErrorPage(c, http.StatusNotFound, "There's nothing here.")
+}
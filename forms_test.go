@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"server/models"
+	"strings"
+	"testing"
+)
+
+// una pagina de otro sitio puede mandar un form con la cookie de sesion
+func TestAPIRejectsCookieForms(t *testing.T) {
+
+	p := newRolePicnic(t)
+	owner := p.users["owner"]
+	token, err := models.CreateApiToken(owner.ID, "test", []string{models.ScopeAdmin}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/v1/picnics/%d", p.picnicID)
+
+	send := func(contentType string, header string, value string) int {
+		form := url.Values{"name": {"Hacked"}, "date": {"2026-11-21 13:00"}, "capacity": {"4"}}
+		req := httptest.NewRequest("PUT", path, strings.NewReader(form.Encode()))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if header != "" {
+			req.Header.Set(header, value)
+		} else {
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: owner.session})
+		}
+		w := httptest.NewRecorder()
+		testRouter.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, contentType := range []string{"application/x-www-form-urlencoded", "text/plain", "multipart/form-data; boundary=x", ""} {
+		if code := send(contentType, "", ""); code != http.StatusForbidden {
+			t.Errorf("cookie with %q: got %d, want 403", contentType, code)
+		}
+	}
+
+	// con un token o un header que solo manda un script si pasa
+	if code := send("application/x-www-form-urlencoded", "Authorization", "Bearer "+token); code == http.StatusForbidden {
+		t.Errorf("bearer token: got 403")
+	}
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/picnics/%d/polls/0/close", p.picnicID), nil)
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: owner.session})
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code == http.StatusForbidden {
+		t.Errorf("X-Requested-With: got 403: %s", w.Body)
+	}
+
+	picnic, err := models.GetPicnicById(p.picnicID)
+	if err != nil || picnic.Name == "Hacked" {
+		t.Errorf("picnic is %q (%v)", picnic.Name, err)
+	}
+}
+
+// csrfToken is the token a page hands to u, as the browser keeps it
+func csrfToken(t *testing.T, u testUser) string {
+	t.Helper()
+
+	for _, cookie := range getPage(t, u, "/picnics").Result().Cookies() {
+		if cookie.Name == csrfCookie {
+			return cookie.Value
+		}
+	}
+	t.Fatal("no csrf cookie")
+	return ""
+}
+
+// submitForm posts form from a page of the site, with the session and the
+// csrf cookie of u
+func submitForm(t *testing.T, u testUser, path string, token string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: u.session})
+	req.AddCookie(&http.Cookie{Name: csrfCookie, Value: token})
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestFormsOverrideTheMethod(t *testing.T) {
+
+	p := newRolePicnic(t)
+	owner := p.users["owner"]
+	token := csrfToken(t, owner)
+	path := fmt.Sprintf("/picnics/%d", p.picnicID)
+
+	w := submitForm(t, owner, path, token, url.Values{"_method": {"put"}, "_csrf": {token}, "name": {"Renamed"}, "date": {"2026-11-22 13:00"}, "capacity": {"4"}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("put: got %d: %s", w.Code, w.Body)
+	}
+	picnic, err := models.GetPicnicById(p.picnicID)
+	if err != nil || picnic.Name != "Renamed" {
+		t.Errorf("picnic is %q (%v)", picnic.Name, err)
+	}
+
+	w = submitForm(t, p.bringer, fmt.Sprintf("/contributions/%d", p.contribution.ID), csrfToken(t, p.bringer), url.Values{"_method": {"delete"}})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("delete without _csrf: got %d", w.Code)
+	}
+	bringerToken := csrfToken(t, p.bringer)
+	w = submitForm(t, p.bringer, fmt.Sprintf("/contributions/%d", p.contribution.ID), bringerToken, url.Values{"_method": {"delete"}, "_csrf": {bringerToken}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("delete: got %d: %s", w.Code, w.Body)
+	}
+	contribution, err := models.GetContributionById(p.contribution.ID)
+	if err != nil || contribution.ID != 0 {
+		t.Errorf("contribution is still there: %+v (%v)", contribution, err)
+	}
+}
+
+func TestFormsWithoutTheCSRFTokenGetTheErrorPage(t *testing.T) {
+
+	p := newRolePicnic(t)
+	owner := p.users["owner"]
+	token := csrfToken(t, owner)
+	path := fmt.Sprintf("/picnics/%d", p.picnicID)
+
+	for name, sent := range map[string][]string{"missing": nil, "wrong": {strings.Repeat("0", 64)}} {
+		form := url.Values{"_method": {"put"}, "name": {"Hacked"}, "date": {"2026-11-22 13:00"}}
+		if sent != nil {
+			form["_csrf"] = sent
+		}
+		w := submitForm(t, owner, path, token, form)
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "This form has expired") {
+			t.Errorf("%s _csrf: got %d:\n%s", name, w.Code, w.Body)
+		}
+	}
+
+	picnic, err := models.GetPicnicById(p.picnicID)
+	if err != nil || picnic.Name == "Hacked" {
+		t.Errorf("picnic is %q (%v)", picnic.Name, err)
+	}
+}
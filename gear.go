@@ -0,0 +1,263 @@
+package main
+
+import (
+	"net/http"
+	"server/live"
+	"server/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func addGearItem(c *gin.Context) {
+
+	var json models.GearItem
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	id, err := models.CreateGearItem(json)
+
+	if err == nil {
+		json.ID = id
+		c.JSON(http.StatusOK, gin.H{"message": "Success", "data": json})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+func readGearItem(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("gear_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid gear ID")})
+		return
+	}
+
+	gearItem, err := models.GetGearItemById(id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if gearItem.Name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Gear item of that id not found")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gearItem})
+}
+
+func readAllGearItems(c *gin.Context) {
+
+	gearItems, err := models.GetGearItems()
+
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gearItems})
+}
+
+func updateGearItem(c *gin.Context) {
+
+	var json models.GearItem
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("gear_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid gear ID")})
+		return
+	}
+
+	success, err := models.UpdateGearItem(json, id)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+// requireGearAssignment loads :assignment_id of :picnic_id and checks the
+// current user may change it.
+func requireGearAssignment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+			return
+		}
+
+		id, err := strconv.Atoi(c.Param("assignment_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid assignment ID")})
+			return
+		}
+
+		assignment, err := models.GetGearAssignmentById(id)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if assignment.ID == 0 || assignment.PicnicID != picnicID {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": tr(c, "Gear assignment of that id not found")})
+			return
+		}
+
+		userID, _ := currentUserID(c)
+		allowed, err := mayManageItem(userID, picnicID, assignment.UserID)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only manage your own gear")})
+			return
+		}
+
+		c.Set("gear_assignment", assignment)
+		c.Next()
+	}
+}
+
+func readAllGearOfPicnic(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	assignments, err := models.GetGearAssignmentsByPicnic(picnicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve gear")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": assignments})
+}
+
+// addGearToPicnic defaults to the current user bringing one of the item
+func addGearToPicnic(c *gin.Context) {
+
+	var json models.GearAssignment
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	json.PicnicID = picnicID
+	if json.UserID == 0 {
+		json.UserID = userID
+	}
+	if json.Quantity == 0 {
+		json.Quantity = 1
+	}
+
+	allowed, err := mayManageItem(userID, picnicID, json.UserID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only manage your own gear")})
+		return
+	}
+
+	id, err := models.CreateGearAssignment(json)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	json.ID = id
+	live.Publish(picnicID, models.EventGearAssigned, json)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": json})
+}
+
+func updateGearOfPicnic(c *gin.Context) {
+
+	var json models.GearAssignment
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	assignment := c.MustGet("gear_assignment").(models.GearAssignment)
+	json.ID = assignment.ID
+	json.PicnicID = assignment.PicnicID
+	if json.UserID == 0 {
+		json.UserID = assignment.UserID
+	}
+
+	// tambien se revisa a quien se le pasa
+	userID, _ := currentUserID(c)
+	allowed, err := mayManageItem(userID, json.PicnicID, json.UserID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only manage your own gear")})
+		return
+	}
+
+	success, err := models.UpdateGearAssignment(json, assignment.ID)
+
+	if success {
+		live.Publish(json.PicnicID, models.EventGearUpdated, json)
+		c.JSON(http.StatusOK, gin.H{"message": "Success", "data": json})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+func deleteGearOfPicnic(c *gin.Context) {
+
+	assignment := c.MustGet("gear_assignment").(models.GearAssignment)
+
+	success, err := models.DeleteGearAssignment(assignment.ID)
+
+	if success {
+		live.Publish(assignment.PicnicID, models.EventGearRemoved, assignment)
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+// GET /picnics/:picnic_id/list, the whole bring list with food and gear
+func readPicnicList(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	list, err := models.GetPicnicList(picnicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve the list")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
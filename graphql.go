@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"server/loader"
+	"server/models"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// Una query mas profunda o mas cara que esto se rechaza antes de tocar la
+// base. En el costo cada campo vale 1 y lo que esta debajo de una lista
+// cuenta graphqlListCost veces, se supone que las listas traen unas 10 filas.
+const (
+	maxGraphQLDepth      = 8
+	maxGraphQLComplexity = 5000
+	graphqlListCost      = 10
+	maxGraphQLQuery      = 16 << 10
+)
+
+// graphqlBody is what clients POST, the usual {"query", "variables"}
+type graphqlBody struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type graphqlError struct {
+	Message string `json:"message"`
+}
+
+// graphqlResponse documents graphql.Result for the OpenAPI spec
+type graphqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []graphqlError         `json:"errors,omitempty"`
+}
+
+type graphqlRequestKey struct{}
+
+// graphqlRequest lives for one request. The loaders batch the lookups of
+// every resolver at the same level of the query, a list of 20 picnics
+// with their attendees is two queries and not 21.
+type graphqlRequest struct {
+	c *gin.Context
+
+	users                 *loader.Loader
+	picnics               *loader.Loader
+	foodItems             *loader.Loader
+	attendees             *loader.Loader
+	members               *loader.Loader
+	picnicsOfUser         *loader.Loader
+	contributionsOfPicnic *loader.Loader
+	contributionsOfUser   *loader.Loader
+	contributionsOfItem   *loader.Loader
+
+	// roles is where the current user attends, read once by viewerRoles
+	rolesOnce sync.Once
+	roles     map[int]string
+	rolesErr  error
+}
+
+func newGraphQLRequest(c *gin.Context) *graphqlRequest {
+	return &graphqlRequest{
+		c: c,
+		users: loader.New(func(ids []int) (map[int]interface{}, error) {
+			return byID(models.GetUsersByIds(ids))
+		}),
+		picnics: loader.New(func(ids []int) (map[int]interface{}, error) {
+			return byID(models.GetPicnicsByIds(ids))
+		}),
+		foodItems: loader.New(func(ids []int) (map[int]interface{}, error) {
+			return byID(models.GetFoodItemsByIds(ids))
+		}),
+		attendees: loader.New(func(ids []int) (map[int]interface{}, error) {
+			rows, err := models.GetUsersByPicnics(ids)
+			return grouped(ids, rows, err)
+		}),
+		members: loader.New(func(ids []int) (map[int]interface{}, error) {
+			rows, err := models.GetMembershipsByPicnics(ids)
+			return grouped(ids, rows, err)
+		}),
+		picnicsOfUser: loader.New(func(ids []int) (map[int]interface{}, error) {
+			rows, err := models.GetPicnicsByUsers(ids)
+			return grouped(ids, rows, err)
+		}),
+		contributionsOfPicnic: loader.New(func(ids []int) (map[int]interface{}, error) {
+			rows, err := models.GetContributionsByPicnics(ids)
+			return grouped(ids, rows, err)
+		}),
+		contributionsOfUser: loader.New(func(ids []int) (map[int]interface{}, error) {
+			rows, err := models.GetContributionsByUsers(ids)
+			return grouped(ids, rows, err)
+		}),
+		contributionsOfItem: loader.New(func(ids []int) (map[int]interface{}, error) {
+			rows, err := models.GetContributionsByFoodItems(ids)
+			return grouped(ids, rows, err)
+		}),
+	}
+}
+
+// byID adapts the models batch functions to loader.BatchFunc, a missing id
+// loads nil and resolves to null
+func byID[V any](rows map[int]V, err error) (map[int]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[int]interface{}, len(rows))
+	for id, row := range rows {
+		values[id] = row
+	}
+	return values, nil
+}
+
+// grouped is byID for lists, an id without rows loads an empty list
+func grouped[V any](ids []int, rows map[int][]V, err error) (map[int]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[int]interface{}, len(ids))
+	for _, id := range ids {
+		if rows[id] == nil {
+			rows[id] = make([]V, 0)
+		}
+		values[id] = rows[id]
+	}
+	return values, nil
+}
+
+func requestOf(p graphql.ResolveParams) *graphqlRequest {
+	return p.Context.Value(graphqlRequestKey{}).(*graphqlRequest)
+}
+
+// POST /api/v1/graphql
+func graphqlHandler(c *gin.Context) {
+
+	var json graphqlBody
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []graphqlError{{trErr(c, err)}}})
+		return
+	}
+
+	if err := checkGraphQLLimits(c, json.Query, json.OperationName); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": []graphqlError{{err.Error()}}})
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         graphqlSchema,
+		RequestString:  json.Query,
+		VariableValues: json.Variables,
+		OperationName:  json.OperationName,
+		Context:        context.WithValue(c.Request.Context(), graphqlRequestKey{}, newGraphQLRequest(c)),
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// checkGraphQLLimits measures the operation that will run. Syntax errors
+// are left to graphql.Do, it reports them with their location.
+func checkGraphQLLimits(c *gin.Context, query string, operationName string) error {
+
+	if len(query) > maxGraphQLQuery {
+		return errors.New(tr(c, "query is longer than %d bytes", maxGraphQLQuery))
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operation == nil || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return nil
+	}
+
+	root := graphqlSchema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = graphqlSchema.MutationType()
+	}
+
+	measure := &queryMeasure{fragments: fragments}
+	complexity := measure.selections(operation.SelectionSet, root, 1, map[string]bool{})
+
+	if measure.depth > maxGraphQLDepth {
+		return errors.New(tr(c, "query is %d levels deep, the limit is %d", measure.depth, maxGraphQLDepth))
+	}
+	if complexity > maxGraphQLComplexity {
+		return errors.New(tr(c, "query is too complex (%d), the limit is %d", complexity, maxGraphQLComplexity))
+	}
+	return nil
+}
+
+type queryMeasure struct {
+	fragments map[string]*ast.FragmentDefinition
+	depth     int
+}
+
+// selections returns the cost of a selection set and keeps the deepest
+// level seen. Fragments are measured where they are spread; spreading a
+// fragment inside itself is invalid and only counted once here.
+func (m *queryMeasure) selections(set *ast.SelectionSet, parent *graphql.Object, depth int, spreading map[string]bool) int {
+
+	if set == nil || parent == nil {
+		return 0
+	}
+
+	cost := 0
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+
+		case *ast.Field:
+			// la introspeccion (__schema, __typename) no cuenta
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			if depth > m.depth {
+				m.depth = depth
+			}
+			field, ok := parent.Fields()[selection.Name.Value]
+			if !ok {
+				continue
+			}
+
+			object, list := unwrapGraphQLType(field.Type)
+			children := m.selections(selection.SelectionSet, object, depth+1, spreading)
+			if list {
+				children *= graphqlListCost
+			}
+			cost += 1 + children
+
+		case *ast.InlineFragment:
+			cost += m.selections(selection.SelectionSet, parent, depth, spreading)
+
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || spreading[name] {
+				continue
+			}
+			spreading[name] = true
+			cost += m.selections(fragment.SelectionSet, parent, depth, spreading)
+			delete(spreading, name)
+		}
+	}
+	return cost
+}
+
+// unwrapGraphQLType returns the object under NonNull and List, nil for
+// scalars, and whether there was a list on the way
+func unwrapGraphQLType(t graphql.Type) (*graphql.Object, bool) {
+	list := false
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			list = true
+			t = wrapped.OfType
+		case *graphql.Object:
+			return wrapped, list
+		default:
+			return nil, list
+		}
+	}
+}
+
+// Los resolvers revisan lo mismo que los middlewares de las rutas REST, que
+// aca no corren porque todo entra por una sola ruta.
+
+func (r *graphqlRequest) requireScope(scope string) error {
+	if apiToken, ok := r.c.Get(currentTokenKey); ok && !apiToken.(models.ApiToken).HasScope(scope) {
+		return errors.New(tr(r.c, "Token is missing the %s scope", scope))
+	}
+	return nil
+}
+
+func (r *graphqlRequest) requireUser() (int, error) {
+	userID, ok := currentUserID(r.c)
+	if !ok {
+		return 0, errors.New(tr(r.c, "Authentication required"))
+	}
+	return userID, nil
+}
+
+// requirePicnicRole is requirePicnicRole and requireSelfOrPicnicRole,
+// selfID is the user the mutation acts on, 0 when there is none
+func (r *graphqlRequest) requirePicnicRole(picnicID int, selfID int, roles ...string) (int, error) {
+
+	userID, err := r.requireUser()
+	if err != nil {
+		return 0, err
+	}
+	if selfID != 0 && selfID == userID {
+		return userID, nil
+	}
+
+	role, err := models.GetRole(userID, picnicID)
+	if err != nil {
+		return 0, err
+	}
+	if !hasRole(role, roles) {
+		return 0, errors.New(tr(r.c, "You don't have permission to do that in this picnic"))
+	}
+	return userID, nil
+}
+
+// viewerRoles maps the picnics the current user attends to their role,
+// anonymous callers attend none
+func (r *graphqlRequest) viewerRoles() (map[int]string, error) {
+	r.rolesOnce.Do(func() {
+		r.roles = make(map[int]string)
+		if userID, ok := currentUserID(r.c); ok {
+			r.roles, r.rolesErr = models.GetRolesOfUser(userID)
+		}
+	})
+	return r.roles, r.rolesErr
+}
+
+// requireMember is the member middleware of the REST routes, any role of an
+// attending user
+func (r *graphqlRequest) requireMember(picnicID int) error {
+
+	if _, err := r.requireUser(); err != nil {
+		return err
+	}
+	roles, err := r.viewerRoles()
+	if err != nil {
+		return r.fail(err)
+	}
+	if roles[picnicID] == "" {
+		return errors.New(tr(r.c, "You don't have permission to do that in this picnic"))
+	}
+	return nil
+}
+
+// visibleContributions drops the contributions to picnics the current user
+// doesn't attend, for the lists that mix picnics
+func (r *graphqlRequest) visibleContributions(contributions []models.Contribution) ([]models.Contribution, error) {
+
+	roles, err := r.viewerRoles()
+	if err != nil {
+		return nil, r.fail(err)
+	}
+	visible := make([]models.Contribution, 0, len(contributions))
+	for _, contribution := range contributions {
+		if roles[contribution.PicnicID] != "" {
+			visible = append(visible, contribution)
+		}
+	}
+	return visible, nil
+}
+
+func (r *graphqlRequest) requireContributionAccess(contribution models.Contribution) (int, error) {
+
+	userID, err := r.requireUser()
+	if err != nil {
+		return 0, err
+	}
+
+	allowed, err := mayManageContribution(userID, contribution)
+	if err != nil {
+		return 0, err
+	}
+	if !allowed {
+		return 0, errors.New(tr(r.c, "You can only manage your own contributions"))
+	}
+	return userID, nil
+}
+
+// fail translates an error of the models like the REST handlers do
+func (r *graphqlRequest) fail(err error) error {
+	return errors.New(trErr(r.c, err))
+}
@@ -0,0 +1,855 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"server/models"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Los tipos repiten los de models con los mismos nombres que el JSON de la
+// API REST, los campos sin Resolve los llena graphql.DefaultResolveFn por
+// el tag json. Las relaciones pasan por los loaders de graphqlRequest.
+
+// The object types refer to each other, they are built in init.
+var userType, picnicType, membershipType, foodItemType, contributionType, queryType, mutationType *graphql.Object
+
+// attendee is a user reached through Picnic.attendees, their contributions
+// default to that picnic
+type attendee struct {
+	models.User
+	picnicID int
+}
+
+// Resolve lets DefaultResolveFn read the scalars of the embedded user
+func (a attendee) Resolve(p graphql.ResolveParams) (interface{}, error) {
+	p.Source = a.User
+	return graphql.DefaultResolveFn(p)
+}
+
+func userOf(source interface{}) models.User {
+	if a, ok := source.(attendee); ok {
+		return a.User
+	}
+	return source.(models.User)
+}
+
+func init() {
+	userType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"email": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Only visible to the user themselves",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						user := userOf(p.Source)
+						if userID, ok := currentUserID(requestOf(p).c); !ok || userID != user.ID {
+							return nil, nil
+						}
+						return user.Email, nil
+					},
+				},
+				"locale": &graphql.Field{Type: graphql.String},
+				"picnics": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(picnicType))),
+					Description: "Picnics the user belongs to, declined ones are left out",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return requestOf(p).picnicsOfUser.Load(userOf(p.Source).ID), nil
+					},
+				},
+				"contributions": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(contributionType))),
+					Description: "Contributions of the user to picnics the viewer attends, only to picnic_id when given. Attendees of a picnic default to that picnic.",
+					Args: graphql.FieldConfigArgument{
+						"picnic_id": &graphql.ArgumentConfig{Type: graphql.Int},
+					},
+					Resolve: resolveContributionsOfUser,
+				},
+			}
+		}),
+	})
+}
+
+func resolveContributionsOfUser(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	user := userOf(p.Source)
+
+	picnicID, ok := p.Args["picnic_id"].(int)
+	if a, isAttendee := p.Source.(attendee); isAttendee && !ok {
+		picnicID, ok = a.picnicID, true
+	}
+	if !ok {
+		return r.visible(r.contributionsOfUser.Load(user.ID)), nil
+	}
+
+	// se filtra lo del picnic, asi todos los asistentes comparten una query
+	thunk := r.contributionsOfPicnic.Load(picnicID)
+	return func() (interface{}, error) {
+		loaded, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		contributions := make([]models.Contribution, 0)
+		for _, contribution := range loaded.([]models.Contribution) {
+			if contribution.UserID == user.ID {
+				contributions = append(contributions, contribution)
+			}
+		}
+		return r.visibleContributions(contributions)
+	}, nil
+}
+
+// visible is visibleContributions for a loaded list
+func (r *graphqlRequest) visible(thunk func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		loaded, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		return r.visibleContributions(loaded.([]models.Contribution))
+	}
+}
+
+func init() {
+	picnicType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Picnic",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"location":   &graphql.Field{Type: graphql.String},
+				"date":       &graphql.Field{Type: graphql.String},
+				"capacity":   &graphql.Field{Type: graphql.Int, Description: "0 is no limit"},
+				"created_by": &graphql.Field{Type: graphql.Int},
+				"creator": &graphql.Field{
+					Type: userType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						picnic := p.Source.(models.Picnic)
+						if picnic.CreatedBy == 0 {
+							return nil, nil
+						}
+						return requestOf(p).users.Load(picnic.CreatedBy), nil
+					},
+				},
+				"attendees": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+					Description: "Users attending, without the waitlist",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						picnic := p.Source.(models.Picnic)
+						thunk := requestOf(p).attendees.Load(picnic.ID)
+						return func() (interface{}, error) {
+							loaded, err := thunk()
+							if err != nil {
+								return nil, err
+							}
+							users := loaded.([]models.User)
+							attendees := make([]attendee, len(users))
+							for i, user := range users {
+								attendees[i] = attendee{user, picnic.ID}
+							}
+							return attendees, nil
+						}, nil
+					},
+				},
+				"members": &graphql.Field{
+					Type:        graphql.NewList(graphql.NewNonNull(membershipType)),
+					Description: "Every membership, waitlisted and declined too. Only for people attending the picnic.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						r, picnicID := requestOf(p), p.Source.(models.Picnic).ID
+						if err := r.requireMember(picnicID); err != nil {
+							return nil, err
+						}
+						return r.members.Load(picnicID), nil
+					},
+				},
+				"contributions": &graphql.Field{
+					Type:        graphql.NewList(graphql.NewNonNull(contributionType)),
+					Description: "Only for people attending the picnic",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						r, picnicID := requestOf(p), p.Source.(models.Picnic).ID
+						if err := r.requireMember(picnicID); err != nil {
+							return nil, err
+						}
+						return r.contributionsOfPicnic.Load(picnicID), nil
+					},
+				},
+			}
+		}),
+	})
+}
+
+func init() {
+	membershipType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Membership",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":                &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"user_id":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"picnic_id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"status":            &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "attending, waitlisted or declined"},
+				"role":              &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "owner, co-host or attendee"},
+				"waitlist_position": &graphql.Field{Type: graphql.Int, Description: "1-based, 0 when not waitlisted"},
+				"user": &graphql.Field{
+					Type: userType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return requestOf(p).users.Load(p.Source.(models.UserPicnic).UserID), nil
+					},
+				},
+				"picnic": &graphql.Field{
+					Type: picnicType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return requestOf(p).picnics.Load(p.Source.(models.UserPicnic).PicnicID), nil
+					},
+				},
+			}
+		}),
+	})
+}
+
+func init() {
+	foodItemType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "FoodItem",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"url":     &graphql.Field{Type: graphql.String},
+				"measure": &graphql.Field{Type: graphql.String},
+				"contributions": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(contributionType))),
+					Description: "Only to picnics the viewer attends",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						r := requestOf(p)
+						return r.visible(r.contributionsOfItem.Load(p.Source.(models.FoodItem).ID)), nil
+					},
+				},
+			}
+		}),
+	})
+}
+
+func init() {
+	contributionType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Contribution",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"user_id":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"picnic_id":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"food_item_id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"quantity":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"paid_by":      &graphql.Field{Type: graphql.Int, Description: "0 means user_id paid"},
+				"amount_paid":  &graphql.Field{Type: graphql.Int, Description: "In cents"},
+				"version":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"user": &graphql.Field{
+					Type: userType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return requestOf(p).users.Load(p.Source.(models.Contribution).UserID), nil
+					},
+				},
+				"payer": &graphql.Field{
+					Type: userType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						contribution := p.Source.(models.Contribution)
+						payer := contribution.PaidBy
+						if payer == 0 {
+							payer = contribution.UserID
+						}
+						return requestOf(p).users.Load(payer), nil
+					},
+				},
+				"picnic": &graphql.Field{
+					Type: picnicType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return requestOf(p).picnics.Load(p.Source.(models.Contribution).PicnicID), nil
+					},
+				},
+				"food_item": &graphql.Field{
+					Type: foodItemType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return requestOf(p).foodItems.Load(p.Source.(models.Contribution).FoodItemID), nil
+					},
+				},
+			}
+		}),
+	})
+}
+
+var idArgs = graphql.FieldConfigArgument{
+	"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+}
+
+func init() {
+	queryType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r := requestOf(p)
+					userID, err := r.requireUser()
+					if err != nil {
+						return nil, err
+					}
+					return r.users.Load(userID), nil
+				},
+			},
+			"picnic": &graphql.Field{
+				Type: picnicType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return requestOf(p).picnics.Load(p.Args["id"].(int)), nil
+				},
+			},
+			"picnics": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(picnicType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return models.GetPicnics()
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return requestOf(p).users.Load(p.Args["id"].(int)), nil
+				},
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return models.GetUsers()
+				},
+			},
+			"food_item": &graphql.Field{
+				Type: foodItemType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return requestOf(p).foodItems.Load(p.Args["id"].(int)), nil
+				},
+			},
+			"food_items": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(foodItemType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return models.GetFoodItems()
+				},
+			},
+			"contribution": &graphql.Field{
+				Type: contributionType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					r := requestOf(p)
+					contribution, err := models.GetContributionById(p.Args["id"].(int))
+					if err != nil || contribution.ID == 0 {
+						return nil, err
+					}
+					if err := r.requireMember(contribution.PicnicID); err != nil {
+						return nil, err
+					}
+					return contribution, nil
+				},
+			},
+			"contributions": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(contributionType))),
+				Description: "Contributions to the picnics the viewer attends",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					contributions, err := models.GetContributions()
+					if err != nil {
+						return nil, err
+					}
+					return requestOf(p).visibleContributions(contributions)
+				},
+			},
+		},
+	})
+}
+
+var picnicInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "PicnicInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"location": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"date":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"capacity": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+var userInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"email":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"locale": &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+var foodItemInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "FoodItemInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"url":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"measure": &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+var contributionInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ContributionInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"user_id":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"picnic_id":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"food_item_id": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"quantity":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"paid_by":      &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"amount_paid":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"version":      &graphql.InputObjectFieldConfig{Type: graphql.Int, Description: "The version read, a newer stored version is a conflict"},
+	},
+})
+
+// decodeInput fills one of the models types from an input object, the
+// input fields are named like its json tags
+func decodeInput(input interface{}, v interface{}) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func inputArgs(input graphql.Input) graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(input)},
+	}
+}
+
+func idInputArgs(input graphql.Input) graphql.FieldConfigArgument {
+	args := inputArgs(input)
+	args["id"] = idArgs["id"]
+	return args
+}
+
+var membershipArgs = graphql.FieldConfigArgument{
+	"picnic_id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+	"user_id":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+}
+
+// Las mutations hacen lo mismo que las rutas REST, con sus scopes, roles,
+// webhooks y eventos en vivo.
+func init() {
+	mutationType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"create_picnic": &graphql.Field{
+				Type:    picnicType,
+				Args:    inputArgs(picnicInputType),
+				Resolve: createPicnicMutation,
+			},
+			"update_picnic": &graphql.Field{
+				Type:    picnicType,
+				Args:    idInputArgs(picnicInputType),
+				Resolve: updatePicnicMutation,
+			},
+			"delete_picnic": &graphql.Field{
+				Type:    graphql.Boolean,
+				Args:    idArgs,
+				Resolve: deletePicnicMutation,
+			},
+			"create_user": &graphql.Field{
+				Type:        graphql.Boolean,
+				Description: "Users without a password, register through /auth/register to log in",
+				Args:        inputArgs(userInputType),
+				Resolve:     createUserMutation,
+			},
+			"update_user": &graphql.Field{
+				Type:    userType,
+				Args:    idInputArgs(userInputType),
+				Resolve: updateUserMutation,
+			},
+			"create_food_item": &graphql.Field{
+				Type:    graphql.Boolean,
+				Args:    inputArgs(foodItemInputType),
+				Resolve: createFoodItemMutation,
+			},
+			"update_food_item": &graphql.Field{
+				Type:    foodItemType,
+				Args:    idInputArgs(foodItemInputType),
+				Resolve: updateFoodItemMutation,
+			},
+			"add_user_to_picnic": &graphql.Field{
+				Type:        membershipType,
+				Description: "The user is waitlisted when the picnic is full",
+				Args:        membershipArgs,
+				Resolve:     addUserToPicnicMutation,
+			},
+			"remove_user_from_picnic": &graphql.Field{
+				Type:    graphql.Boolean,
+				Args:    membershipArgs,
+				Resolve: removeUserFromPicnicMutation,
+			},
+			"add_contribution": &graphql.Field{
+				Type:    contributionType,
+				Args:    inputArgs(contributionInputType),
+				Resolve: addContributionMutation,
+			},
+			"update_contribution": &graphql.Field{
+				Type:    contributionType,
+				Args:    idInputArgs(contributionInputType),
+				Resolve: updateContributionMutation,
+			},
+			"delete_contribution": &graphql.Field{
+				Type:    graphql.Boolean,
+				Args:    idArgs,
+				Resolve: deleteContributionMutation,
+			},
+		},
+	})
+}
+
+var graphqlSchema graphql.Schema
+
+func init() {
+	var err error
+	graphqlSchema, err = graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queryType,
+		Mutation: mutationType,
+	})
+	checkErr(err)
+}
+
+func createPicnicMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+	userID, err := r.requireUser()
+	if err != nil {
+		return nil, err
+	}
+
+	var picnic models.Picnic
+	if err := decodeInput(p.Args["input"], &picnic); err != nil {
+		return nil, r.fail(err)
+	}
+	picnic.CreatedBy = userID
+
+	id, err := models.CreatePicnic(picnic)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+
+	picnic.ID = id
+	picnicCreated(picnic)
+	return picnic, nil
+}
+
+func updatePicnicMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	id := p.Args["id"].(int)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+	editorID, err := r.requirePicnicRole(id, 0, models.RoleOwner, models.RoleCoHost)
+	if err != nil {
+		return nil, err
+	}
+
+	var picnic models.Picnic
+	if err := decodeInput(p.Args["input"], &picnic); err != nil {
+		return nil, r.fail(err)
+	}
+
+	before, err := models.GetPicnicById(id)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+
+	success, err := models.UpdatePicnic(picnic, id)
+	if !success {
+		return nil, r.fail(err)
+	}
+
+	after, err := models.GetPicnicById(id)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+	picnicUpdated(before, after, editorID)
+	return after, nil
+}
+
+func deletePicnicMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	id := p.Args["id"].(int)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+	if _, err := r.requirePicnicRole(id, 0, models.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	success, err := removePicnic(id)
+	if !success {
+		return nil, r.fail(err)
+	}
+
+	return true, nil
+}
+
+func createUserMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+
+	var account models.Account
+	if err := decodeInput(p.Args["input"], &account); err != nil {
+		return nil, r.fail(err)
+	}
+
+	success, err := models.CreateUser(account.ToUser())
+	if !success {
+		return nil, r.fail(err)
+	}
+	return true, nil
+}
+
+func updateUserMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	id := p.Args["id"].(int)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+	userID, err := r.requireUser()
+	if err != nil {
+		return nil, err
+	}
+	if userID != id {
+		return nil, errors.New(tr(r.c, "You can only change your own account"))
+	}
+
+	var account models.Account
+	if err := decodeInput(p.Args["input"], &account); err != nil {
+		return nil, r.fail(err)
+	}
+	account.ID = id
+
+	success, err := models.UpdateUser(account.ToUser(), id)
+	if !success {
+		return nil, r.fail(err)
+	}
+
+	updated, err := models.GetUserById(id)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+	return updated, nil
+}
+
+func createFoodItemMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+
+	var item models.FoodItem
+	if err := decodeInput(p.Args["input"], &item); err != nil {
+		return nil, r.fail(err)
+	}
+
+	success, err := models.CreateFoodItem(item)
+	if !success {
+		return nil, r.fail(err)
+	}
+	return true, nil
+}
+
+func updateFoodItemMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	id := p.Args["id"].(int)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+
+	var item models.FoodItem
+	if err := decodeInput(p.Args["input"], &item); err != nil {
+		return nil, r.fail(err)
+	}
+	item.ID = id
+
+	success, err := models.UpdateFoodItem(item, id)
+	if !success {
+		return nil, r.fail(err)
+	}
+
+	updated, err := models.GetFoodItemById(id)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+	return updated, nil
+}
+
+func addUserToPicnicMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	picnicID, userID := p.Args["picnic_id"].(int), p.Args["user_id"].(int)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+	if _, err := r.requirePicnicRole(picnicID, 0, models.RoleOwner, models.RoleCoHost); err != nil {
+		return nil, err
+	}
+
+	success, err := models.AddUserToPicnic(userID, picnicID)
+	if !success {
+		return nil, r.fail(err)
+	}
+
+	// si el picnic esta lleno el usuario queda en lista de espera
+	membership, err := models.GetMembership(userID, picnicID)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+
+	membershipAdded(membership)
+	return membership, nil
+}
+
+func removeUserFromPicnicMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	picnicID, userID := p.Args["picnic_id"].(int), p.Args["user_id"].(int)
+	if err := r.requireScope(models.ScopeAdmin); err != nil {
+		return nil, err
+	}
+	if _, err := r.requirePicnicRole(picnicID, userID, models.RoleOwner, models.RoleCoHost); err != nil {
+		return nil, err
+	}
+
+	success, err := models.RemoveUserFromPicnic(userID, picnicID)
+	if !success {
+		return nil, r.fail(err)
+	}
+
+	membershipLeft(picnicID, userID, models.EventMembershipRemoved)
+	return true, nil
+}
+
+func addContributionMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	if err := r.requireScope(models.ScopeContributionsWrite); err != nil {
+		return nil, err
+	}
+
+	var contribution models.Contribution
+	if err := decodeInput(p.Args["input"], &contribution); err != nil {
+		return nil, r.fail(err)
+	}
+	if _, err := r.requireContributionAccess(contribution); err != nil {
+		return nil, err
+	}
+
+	id, err := models.CreateContribution(contribution)
+	if err != nil {
+		return nil, r.fail(err)
+	}
+
+	contribution.ID = id
+	contribution.Version = 1
+	contributionCreated(contribution)
+	return contribution, nil
+}
+
+// storedContribution is requireContributionAccess for the id argument
+func (r *graphqlRequest) storedContribution(id int) (models.Contribution, error) {
+
+	contribution, err := models.GetContributionById(id)
+	if err != nil {
+		return contribution, r.fail(err)
+	}
+	if contribution.ID == 0 {
+		return contribution, errors.New(tr(r.c, "Contribution of that id not found"))
+	}
+	if _, err := r.requireContributionAccess(contribution); err != nil {
+		return contribution, err
+	}
+	return contribution, nil
+}
+
+func updateContributionMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	id := p.Args["id"].(int)
+	if err := r.requireScope(models.ScopeContributionsWrite); err != nil {
+		return nil, err
+	}
+
+	before, err := r.storedContribution(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var contribution models.Contribution
+	if err := decodeInput(p.Args["input"], &contribution); err != nil {
+		return nil, r.fail(err)
+	}
+	contribution.ID = id
+
+	// tambien se revisa a donde se mueve la contribucion
+	editorID, err := r.requireContributionAccess(contribution)
+	if err != nil {
+		return nil, err
+	}
+
+	if contribution.Version > 0 {
+		// con version se rechaza el update si alguien guardo antes
+		saved, err := models.UpdateContributionAtVersion(contribution, id, contribution.Version)
+		if err != nil {
+			return nil, r.fail(err)
+		}
+		contribution = saved
+	} else {
+		success, err := models.UpdateContribution(contribution, id)
+		if !success {
+			return nil, r.fail(err)
+		}
+		contribution, err = models.GetContributionById(id)
+		if err != nil {
+			return nil, r.fail(err)
+		}
+	}
+
+	contributionUpdated(before, contribution, editorID)
+	return contribution, nil
+}
+
+func deleteContributionMutation(p graphql.ResolveParams) (interface{}, error) {
+
+	r := requestOf(p)
+	id := p.Args["id"].(int)
+	if err := r.requireScope(models.ScopeContributionsWrite); err != nil {
+		return nil, err
+	}
+
+	contribution, err := r.storedContribution(id)
+	if err != nil {
+		return nil, err
+	}
+
+	success, err := models.DeleteContribution(id)
+	if !success {
+		return nil, r.fail(err)
+	}
+
+	contributionDeleted(contribution)
+	return true, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"server/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+// runGraphQL runs query as u without the router and hands back the request,
+// so the test can count what its loaders did
+func runGraphQL(t *testing.T, u testUser, query string) (*graphql.Result, *graphqlRequest) {
+	t.Helper()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	if u.ID != 0 {
+		c.Set(currentUserKey, u.ID)
+	}
+
+	r := newGraphQLRequest(c)
+	result := graphql.Do(graphql.Params{
+		Schema:        graphqlSchema,
+		RequestString: query,
+		Context:       context.WithValue(c.Request.Context(), graphqlRequestKey{}, r),
+	})
+	return result, r
+}
+
+// graphqlData runs a query that must work and returns its data as JSON, to
+// search in it
+func graphqlData(t *testing.T, u testUser, query string) string {
+	t.Helper()
+
+	result, _ := runGraphQL(t, u, query)
+	return resultData(t, result)
+}
+
+func resultData(t *testing.T, result *graphql.Result) string {
+	t.Helper()
+
+	if result.HasErrors() {
+		t.Fatalf("errors: %v", result.Errors)
+	}
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGraphQLBatchesEachLevel(t *testing.T) {
+
+	first, second := newRolePicnic(t), newRolePicnic(t)
+	// el bringer esta en los dos para que vea ambos picnics
+	mustOK(t)(models.AddUserToPicnic(first.bringer.ID, second.picnicID))
+
+	query := fmt.Sprintf(`{
+		a: picnic(id: %d) { attendees { name contributions { quantity food_item { name } } } }
+		b: picnic(id: %d) { attendees { name contributions { quantity food_item { name } } } }
+	}`, first.picnicID, second.picnicID)
+	result, r := runGraphQL(t, first.bringer, query)
+	data := resultData(t, result)
+
+	for _, p := range []rolePicnic{first, second} {
+		if !strings.Contains(data, p.users["attendee"].Name) {
+			t.Errorf("%s is missing: %s", p.users["attendee"].Name, data)
+		}
+	}
+	if !strings.Contains(data, fmt.Sprintf(`"name":"Pan %d"`, first.picnicID)) {
+		t.Errorf("food item of the contribution is missing: %s", data)
+	}
+
+	// un batch por nivel, sin importar cuantos picnics o asistentes haya
+	batches := map[string]int{
+		"picnics":               r.picnics.Batches(),
+		"attendees":             r.attendees.Batches(),
+		"contributionsOfPicnic": r.contributionsOfPicnic.Batches(),
+		"foodItems":             r.foodItems.Batches(),
+		"contributionsOfUser":   r.contributionsOfUser.Batches(),
+		"users":                 r.users.Batches(),
+	}
+	want := map[string]int{"picnics": 1, "attendees": 1, "contributionsOfPicnic": 1, "foodItems": 1}
+	for name, got := range batches {
+		if got != want[name] {
+			t.Errorf("%s ran %d batches, want %d", name, got, want[name])
+		}
+	}
+}
+
+func TestGraphQLLimits(t *testing.T) {
+
+	u := newTestUser(t, "graphql-limits")
+
+	deep := "id"
+	for i := 0; i < 5; i++ {
+		deep = fmt.Sprintf("attendees { picnics { %s } }", deep)
+	}
+	wide := "{ picnics { attendees { picnics { attendees { picnics { id name location date capacity } } } } } }"
+
+	for name, query := range map[string]string{
+		"levels deep": "{ picnics { " + deep + " } }",
+		"too complex": wide,
+	} {
+		w := u.do(t, "POST", "/api/v1/graphql", graphqlBody{Query: query})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), name) {
+			t.Errorf("%s: got %d: %s", name, w.Code, w.Body)
+		}
+	}
+
+	if w := u.do(t, "POST", "/api/v1/graphql", graphqlBody{Query: "{ picnics { attendees { name } } }"}); w.Code != http.StatusOK {
+		t.Errorf("small query: got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestGraphQLMembersOnly(t *testing.T) {
+
+	p := newRolePicnic(t)
+	picnicQuery := fmt.Sprintf(`{ picnic(id: %d) { name members { status } contributions { id } } }`, p.picnicID)
+
+	// attendee ve la lista de espera y los declined
+	data := graphqlData(t, p.users["attendee"], picnicQuery)
+	for _, status := range []string{models.MembershipWaitlisted, models.MembershipDeclined} {
+		if !strings.Contains(data, status) {
+			t.Errorf("attendee doesn't see %s members: %s", status, data)
+		}
+	}
+	if !strings.Contains(data, fmt.Sprintf(`"id":%d`, p.contribution.ID)) {
+		t.Errorf("attendee doesn't see the contributions: %s", data)
+	}
+
+	for _, role := range []string{"outsider", "waitlisted", "declined"} {
+		result, _ := runGraphQL(t, p.users[role], picnicQuery)
+		data, _ := json.Marshal(result.Data)
+		if len(result.Errors) != 2 || strings.Contains(string(data), models.MembershipWaitlisted) || !strings.Contains(string(data), `"name":"Roles"`) {
+			t.Errorf("%s: %s %v", role, data, result.Errors)
+		}
+	}
+
+	// las listas que mezclan picnics solo traen los del que pregunta
+	listsQuery := fmt.Sprintf(`{ contributions { id } food_item(id: %d) { contributions { id } } user(id: %d) { contributions { id } } }`,
+		p.contribution.FoodItemID, p.bringer.ID)
+	contributionID := fmt.Sprintf(`"id":%d`, p.contribution.ID)
+
+	data = graphqlData(t, p.users["co-host"], listsQuery)
+	if strings.Count(data, contributionID) != 3 {
+		t.Errorf("co-host: %s", data)
+	}
+	for _, u := range []testUser{p.users["outsider"], {}} {
+		data = graphqlData(t, u, listsQuery)
+		if strings.Contains(data, contributionID) {
+			t.Errorf("%q sees the contribution: %s", u.Name, data)
+		}
+	}
+
+	result, _ := runGraphQL(t, p.users["outsider"], fmt.Sprintf(`{ contribution(id: %d) { id } }`, p.contribution.ID))
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, "permission") {
+		t.Errorf("outsider reads a contribution: %v", result.Errors)
+	}
+}
+
+// las mutations avisan igual que REST
+func TestGraphQLMutationsEmitWebhooks(t *testing.T) {
+
+	p := newRolePicnic(t)
+	owner := p.users["owner"]
+
+	models.AllowPrivateWebhooks = true
+	webhook, err := models.CreateWebhook(models.Webhook{UserID: owner.ID, URL: "http://127.0.0.1:9/hook", Events: []string{"*"}})
+	models.AllowPrivateWebhooks = false
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutations := []string{
+		fmt.Sprintf(`mutation { remove_user_from_picnic(picnic_id: %d, user_id: %d) }`, p.picnicID, p.users["attendee"].ID),
+		fmt.Sprintf(`mutation { delete_picnic(id: %d) }`, p.picnicID),
+	}
+	for _, mutation := range mutations {
+		if w := owner.do(t, "POST", "/api/v1/graphql", graphqlBody{Query: mutation}); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "errors") {
+			t.Fatalf("%s: got %d: %s", mutation, w.Code, w.Body)
+		}
+	}
+
+	deliveries, err := models.GetDeliveriesByWebhook(webhook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(map[string]bool)
+	for _, delivery := range deliveries {
+		events[delivery.Event] = true
+	}
+	for _, event := range []string{models.EventMembershipRemoved, models.EventPicnicDeleted} {
+		if !events[event] {
+			t.Errorf("no %s delivery, got %v", event, events)
+		}
+	}
+}
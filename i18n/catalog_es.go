@@ -0,0 +1,296 @@
+package i18n
+
+// spanish es el catalogo en espanol. La key es el texto en ingles tal como
+// aparece en el codigo o en los templates, go test ./i18n falla si falta
+// alguna o si sobra.
+var spanish = map[string]string{
+
+	// API: ids y parametros
+	"Invalid ID":              "ID inválido",
+	"Invalid Last-Event-ID":   "Last-Event-ID inválido",
+	"Invalid after":           "after inválido",
+	"Invalid assignment ID":   "ID de asignación inválido",
+	"Invalid availability ID": "ID de disponibilidad inválido",
+	"Invalid comment ID":      "ID de comentario inválido",
+	"Invalid contribution ID": "ID de contribución inválido",
+	"Invalid delivery ID":     "ID de entrega inválido",
+	"Invalid duration":        "Duración inválida",
+	"Invalid gear ID":         "ID de equipo inválido",
+	"Invalid image ID":        "ID de imagen inválido",
+	"Invalid invitation ID":   "ID de invitación inválido",
+	"Invalid item ID":         "ID de artículo inválido",
+	"Invalid limit":           "Límite inválido",
+	"Invalid message":         "Mensaje inválido",
+	"Invalid picnic ID":       "ID de picnic inválido",
+	"Invalid poll ID":         "ID de encuesta inválido",
+	"Invalid reservation ID":  "ID de reserva inválido",
+	"Invalid session ID":      "ID de sesión inválido",
+	"Invalid token ID":        "ID de token inválido",
+	"Invalid user ID":         "ID de usuario inválido",
+	"Invalid webhook ID":      "ID de webhook inválido",
+
+	"Unknown message type %q":                                                "Tipo de mensaje desconocido %q",
+	"update needs the contribution id and version":                           "update necesita el id y la versión de la contribución",
+	"limit must be between 1 and %d":                                         "limit tiene que estar entre 1 y %d",
+	"Expected a multipart form with an image file":                           "Se esperaba un formulario multipart con un archivo de imagen",
+	"Images can't be larger than %d MB":                                      "Las imágenes no pueden pesar más de %d MB",
+	"Authorization header must be a Bearer token":                            "El header Authorization tiene que ser un token Bearer",
+	"Invalid or expired token":                                               "Token inválido o vencido",
+	"Token is missing the %s scope":                                          "Al token le falta el scope %s",
+	"Authentication required":                                                "Tenés que iniciar sesión",
+	"Failed to check your credentials":                                       "No se pudieron revisar tus credenciales",
+	"Requests with the session cookie must be JSON or send X-Requested-With": "Los pedidos con la cookie de sesión tienen que ser JSON o mandar X-Requested-With",
+	"Failed to create session":                                               "No se pudo crear la sesión",
+	"User is not part of that picnic":                                        "El usuario no es parte de ese picnic",
+	"No food items found":                                                    "No se encontraron comidas",
+	"Only organizers can hand items to someone else":                         "Solo los organizadores pueden entregarle artículos a otra persona",
+
+	// API: no encontrado
+	"Comment of that id not found":         "No existe un comentario con ese id",
+	"Contribution of that id not found":    "No existe una contribución con ese id",
+	"Delivery of that id not found":        "No existe una entrega con ese id",
+	"Food item of that id not found":       "No existe una comida con ese id",
+	"Gear assignment of that id not found": "No existe una asignación de equipo con ese id",
+	"Gear item of that id not found":       "No existe un equipo con ese id",
+	"Image of that id not found":           "No existe una imagen con ese id",
+	"Inventory item of that id not found":  "No existe un artículo de inventario con ese id",
+	"Picnic of that id not found":          "No existe un picnic con ese id",
+	"Poll of that id not found":            "No existe una encuesta con ese id",
+	"Record of that id not found":          "No existe un registro con ese id",
+	"Reservation of that id not found":     "No existe una reserva con ese id",
+	"User of that id not found":            "No existe un usuario con ese id",
+	"Webhook of that id not found":         "No existe un webhook con ese id",
+	"Not found":                            "No encontrado",
+
+	// API: errores al leer
+	"Failed to check reservations":     "No se pudieron revisar las reservas",
+	"Failed to compute balances":       "No se pudieron calcular los saldos",
+	"Failed to retrieve availability":  "No se pudo obtener la disponibilidad",
+	"Failed to retrieve comments":      "No se pudieron obtener los comentarios",
+	"Failed to retrieve contributions": "No se pudieron obtener las contribuciones",
+	"Failed to retrieve deliveries":    "No se pudieron obtener las entregas",
+	"Failed to retrieve events":        "No se pudieron obtener los eventos",
+	"Failed to retrieve food items":    "No se pudieron obtener las comidas",
+	"Failed to retrieve gear":          "No se pudo obtener el equipo",
+	"Failed to retrieve history":       "No se pudo obtener el historial",
+	"Failed to retrieve invitations":   "No se pudieron obtener las invitaciones",
+	"Failed to retrieve polls":         "No se pudieron obtener las encuestas",
+	"Failed to retrieve reservations":  "No se pudieron obtener las reservas",
+	"Failed to retrieve sessions":      "No se pudieron obtener las sesiones",
+	"Failed to retrieve settlements":   "No se pudieron obtener los pagos",
+	"Failed to retrieve the list":      "No se pudo obtener la lista",
+	"Failed to retrieve tokens":        "No se pudieron obtener los tokens",
+	"Failed to retrieve users":         "No se pudieron obtener los usuarios",
+	"Failed to retrieve waitlist":      "No se pudo obtener la lista de espera",
+	"Failed to retrieve webhooks":      "No se pudieron obtener los webhooks",
+
+	// API: permisos
+	"Both users must be in the picnic":                                        "Los dos usuarios tienen que estar en el picnic",
+	"You can only record payments you made":                                   "Solo podés anotar pagos que hiciste vos",
+	"You can only see the availability of people who share a picnic with you": "Solo podés ver la disponibilidad de gente que comparte un picnic con vos",
+	"You can only see the balances of people who share a picnic with you":     "Solo podés ver los saldos de gente que comparte un picnic con vos",
+	"You can only change your own account":                                    "Solo podés cambiar tu propia cuenta",
+	"You can only change your own comments":                                   "Solo podés cambiar tus propios comentarios",
+	"You can only delete your own photos":                                     "Solo podés borrar tus propias fotos",
+	"You can only manage your own contributions":                              "Solo podés manejar tus propias contribuciones",
+	"You can only manage your own gear":                                       "Solo podés manejar tu propio equipo",
+	"You can only manage your own reservations":                               "Solo podés manejar tus propias reservas",
+	"You don't have permission to do that in this picnic":                     "No tenés permiso para hacer eso en este picnic",
+
+	// API: graphql
+	"query is %d levels deep, the limit is %d":   "la query tiene %d niveles, el límite es %d",
+	"query is longer than %d bytes":              "la query tiene más de %d bytes",
+	"query is too complex (%d), the limit is %d": "la query es demasiado compleja (%d), el límite es %d",
+
+	// models
+	"%q is not a valid amount":                                       "%q no es un monto válido",
+	"%q is not a valid date":                                         "%q no es una fecha válida",
+	"%s is not a public address":                                     "%s no es una dirección pública",
+	"a poll needs at least two options":                              "una encuesta necesita al menos dos opciones",
+	"a token needs at least one scope":                               "un token necesita al menos un scope",
+	"a user can't settle with themselves":                            "un usuario no puede saldar cuentas consigo mismo",
+	"a webhook needs at least one event":                             "un webhook necesita al menos un evento",
+	"amount must be positive":                                        "el monto tiene que ser positivo",
+	"availability %d not found":                                      "no existe la disponibilidad %d",
+	"can't parse date %q":                                            "no se entiende la fecha %q",
+	"can't resolve %s":                                               "no se encuentra %s",
+	"capacity must be a number, 0 for no limit":                      "la capacidad tiene que ser un número, 0 para no tener límite",
+	"comment %d not found":                                           "no existe el comentario %d",
+	"comment body can't be empty":                                    "el comentario no puede estar vacío",
+	"comment body is longer than %d characters":                      "el comentario tiene más de %d caracteres",
+	"contribution %d is not part of picnic %d":                       "la contribución %d no es parte del picnic %d",
+	"contribution was changed by someone else":                       "alguien más cambió la contribución",
+	"duration must be positive":                                      "la duración tiene que ser positiva",
+	"end_time must be after start_time":                              "end_time tiene que ser después de start_time",
+	"end_time must look like 15:04":                                  "end_time tiene que tener la forma 15:04",
+	"ends_at must be after starts_at":                                "ends_at tiene que ser después de starts_at",
+	"every option needs a date or a location":                        "cada opción necesita una fecha o un lugar",
+	"food item %d not found":                                         "no existe la comida %d",
+	"gear item %d not found":                                         "no existe el equipo %d",
+	"image %d not found":                                             "no existe la imagen %d",
+	"image is too large (%dx%d)":                                     "la imagen es demasiado grande (%dx%d)",
+	"image needs a picnic":                                           "la imagen necesita un picnic",
+	"invalid image: %v":                                              "imagen inválida: %v",
+	"invalid name or password":                                       "nombre o contraseña incorrectos",
+	"inventory item %d not found":                                    "no existe el artículo de inventario %d",
+	"invitation %d not found":                                        "no existe la invitación %d",
+	"invitation link is invalid or has expired":                      "el link de la invitación es inválido o ya venció",
+	"item %d hasn't been returned yet":                               "el artículo %d todavía no fue devuelto",
+	"item %d is already reserved for picnic %d on overlapping dates": "el artículo %d ya está reservado para el picnic %d en fechas que se superponen",
+	"item %d is already reserved for this picnic":                    "el artículo %d ya está reservado para este picnic",
+	"kind must be %s or %s":                                          "kind tiene que ser %s o %s",
+	"locale must be one of %s":                                       "locale tiene que ser uno de %s",
+	"name can't be empty":                                            "el nombre no puede estar vacío",
+	"no users given":                                                 "no se indicó ningún usuario",
+	"only jpeg, png and gif images are allowed":                      "solo se aceptan imágenes jpeg, png y gif",
+	"option %d is not part of poll %d":                               "la opción %d no es parte de la encuesta %d",
+	"password must be at least 8 characters":                         "la contraseña tiene que tener al menos 8 caracteres",
+	"pick something to bring":                                        "elegí algo para llevar",
+	"picnic %d not found":                                            "no existe el picnic %d",
+	"poll %d is closed":                                              "la encuesta %d está cerrada",
+	"poll %d is not open":                                            "la encuesta %d no está abierta",
+	"poll %d not found":                                              "no existe la encuesta %d",
+	"poll title can't be empty":                                      "el título de la encuesta no puede estar vacío",
+	"quantity must be at least 1":                                    "la cantidad tiene que ser al menos 1",
+	"reservation %d can't be cancelled, only reserved items can":     "la reserva %d no se puede cancelar, solo se cancelan artículos reservados",
+	"reservation %d is %s, only checked out items can be returned":   "la reserva %d está %s, solo se devuelven artículos retirados",
+	"reservation %d is %s, only reserved items can be checked out":   "la reserva %d está %s, solo se retiran artículos reservados",
+	"role must be %s or %s":                                          "role tiene que ser %s o %s",
+	"session %d not found":                                           "no existe la sesión %d",
+	"start_time must look like 15:04":                                "start_time tiene que tener la forma 15:04",
+	"the owner of picnic %d must transfer ownership before leaving":  "el dueño del picnic %d tiene que transferirlo antes de irse",
+	"the range can't be longer than %d days":                         "el rango no puede ser de más de %d días",
+	"this invitation is for another user":                            "esta invitación es para otro usuario",
+	"this invitation was already %s":                                 "esta invitación ya fue %s",
+	"to must be after from":                                          "to tiene que ser después de from",
+	"token %d not found":                                             "no existe el token %d",
+	"unknown event %q":                                               "evento desconocido %q",
+	"unknown scope %q":                                               "scope desconocido %q",
+	"url must be http or https":                                      "url tiene que ser http o https",
+	"user %d can't be made %s of picnic %d":                          "el usuario %d no puede ser %s del picnic %d",
+	"user %d is %s, only attending users can own picnic %d":          "el usuario %d está %s, solo los que asisten pueden ser dueños del picnic %d",
+	"user %d is already %s for picnic %d":                            "el usuario %d ya está %s en el picnic %d",
+	"user %d is not part of picnic %d":                               "el usuario %d no es parte del picnic %d",
+	"user_ids is required":                                           "user_ids es obligatorio",
+	"vote must be %s, %s or %s":                                      "vote tiene que ser %s, %s o %s",
+	"weekday must be between 0 (Sunday) and 6 (Saturday)":            "weekday tiene que estar entre 0 (domingo) y 6 (sábado)",
+
+	// paginas
+	"Picnics":                    "Picnics",
+	"Food":                       "Comida",
+	"People":                     "Gente",
+	"Log in":                     "Iniciar sesión",
+	"Log out":                    "Cerrar sesión",
+	"Sign up":                    "Registrarse",
+	"No account yet?":            "¿Todavía no tenés cuenta?",
+	"Name:":                      "Nombre:",
+	"Email:":                     "Email:",
+	"Password:":                  "Contraseña:",
+	"Language:":                  "Idioma:",
+	"Same as the browser":        "El del navegador",
+	"Location:":                  "Lugar:",
+	"Date:":                      "Fecha:",
+	"Capacity (0 for no limit):": "Capacidad (0 para no tener límite):",
+	"Food:":                      "Comida:",
+	"Quantity:":                  "Cantidad:",
+	"Amount paid:":               "Monto pagado:",
+	"Measure:":                   "Medida:",
+	"kg, bottles, loaves":        "kg, botellas, panes",
+	"Save":                       "Guardar",
+	"Edit":                       "Editar",
+	"Delete":                     "Borrar",
+	"Edit %s":                    "Editar %s",
+	"Edit Contribution":          "Editar contribución",
+	"Edit contribution":          "Editar contribución",
+	"Edit Item":                  "Editar comida",
+	"Edit Picnic":                "Editar picnic",
+	"Edit Profile":               "Editar perfil",
+	"Edit profile":               "Editar perfil",
+	"New Item":                   "Nueva comida",
+	"New item":                   "Nueva comida",
+	"New Picnic":                 "Nuevo picnic",
+	"New picnic":                 "Nuevo picnic",
+	"No picnics yet.":            "Todavía no hay picnics.",
+	"Nobody has signed up yet.":  "Todavía no se registró nadie.",
+	"Nobody yet.":                "Nadie todavía.",
+	"Nothing yet.":               "Nada todavía.",
+	"Who's coming":               "Quiénes vienen",
+	"What people bring":          "Qué lleva cada uno",
+	"Bring something":            "Llevar algo",
+	"%d people max":              "%d personas como máximo",
+	"by %s":                      "de %s",
+	"paid %s":                    "pagó %s",
+	"Name can't be empty":        "El nombre no puede estar vacío",
+	"Invitation":                 "Invitación",
+	"Accept":                     "Aceptar",
+	"Decline":                    "Rechazar",
+	"Log in to accept or decline this invitation.":           "Iniciá sesión para aceptar o rechazar esta invitación.",
+	"You're in! See you at the picnic.":                      "¡Estás adentro! Nos vemos en el picnic.",
+	"You declined this invitation.":                          "Rechazaste esta invitación.",
+	"The picnic is full, you are number %d on the waitlist.": "El picnic está lleno, sos el número %d en la lista de espera.",
+
+	"API docs": "Documentación de la API",
+	"Requests are sent with your session. To try a token paste it here:": "Los pedidos se mandan con tu sesión. Para probar un token pegalo acá:",
+	"Send":         "Enviar",
+	"Request body": "Cuerpo del pedido",
+	"Response":     "Respuesta",
+	"Loading…":     "Cargando…",
+
+	// paginas de error
+	"Back to the picnics":                                   "Volver a los picnics",
+	"Page not found":                                        "No se encontró la página",
+	"Not allowed":                                           "No permitido",
+	"Conflict":                                              "Conflicto",
+	"Something is wrong with that":                          "Algo anda mal con eso",
+	"Something went wrong":                                  "Algo salió mal",
+	"There's nothing here.":                                 "Acá no hay nada.",
+	"That contribution doesn't exist.":                      "Esa contribución no existe.",
+	"That food item doesn't exist.":                         "Esa comida no existe.",
+	"That person doesn't exist.":                            "Esa persona no existe.",
+	"That picnic doesn't exist.":                            "Ese picnic no existe.",
+	"We couldn't log you in, try again.":                    "No pudimos iniciar tu sesión, probá de nuevo.",
+	"We couldn't log you out, try again.":                   "No pudimos cerrar tu sesión, probá de nuevo.",
+	"You can only change what you bring.":                   "Solo podés cambiar lo que llevás vos.",
+	"You can only change your own profile.":                 "Solo podés cambiar tu propio perfil.",
+	"You don't have permission to do that in this picnic.":  "No tenés permiso para hacer eso en este picnic.",
+	"The form didn't say which picnic this is for.":         "El formulario no dice para qué picnic es.",
+	"Only people coming to the picnic can bring something.": "Solo los que van al picnic pueden llevar algo.",
+	"Only people coming to the picnic can see who else comes and what they bring.":                 "Solo los que van al picnic pueden ver quién más va y qué lleva cada uno.",
+	"Someone changed this while you were editing, these are the current values.":                   "Alguien cambió esto mientras lo editabas, estos son los valores actuales.",
+	"This form has expired or didn't come from this site. Go back, reload the page and try again.": "Este formulario venció o no vino de este sitio. Volvé atrás, recargá la página y probá de nuevo.",
+
+	// mails
+	"You're invited to %s":                  "Te invitaron a %s",
+	"%s has new plans":                      "%s tiene planes nuevos",
+	"Your contribution to %s changed":       "Cambió tu contribución a %s",
+	"%s is coming up":                       "Se acerca %s",
+	"Hi %s,":                                "Hola %s:",
+	"%s invited you to %s at %s on %s.":     "%s te invitó a %s en %s el %s.",
+	"Accept or decline the invitation":      "Aceptá o rechazá la invitación",
+	"%s changed the plans for %s:":          "%s cambió los planes de %s:",
+	"%s edited what you're bringing to %s:": "%s editó lo que llevás a %s:",
+	"%s starts in %s, at %s on %s.":         "%s empieza en %s, en %s el %s.",
+	"Don't forget to bring:":                "No te olvides de llevar:",
+	"%d days":                               "%d días",
+	"%d hours":                              "%d horas",
+	"%d minutes":                            "%d minutos",
+	"1 hour":                                "1 hora",
+
+	// unidades, ver Units
+	"bags":     "bolsas",
+	"bottles":  "botellas",
+	"boxes":    "cajas",
+	"cans":     "latas",
+	"cups":     "tazas",
+	"dozen":    "docena",
+	"g":        "g",
+	"jars":     "frascos",
+	"kg":       "kg",
+	"liters":   "litros",
+	"loaves":   "panes",
+	"packs":    "paquetes",
+	"pieces":   "piezas",
+	"portions": "porciones",
+	"trays":    "bandejas",
+	"units":    "unidades",
+}
@@ -0,0 +1,29 @@
+package i18n
+
+import "testing"
+
+// TestCatalogs scans the whole repo: every message needs a translation in
+// every language, and every translation needs someone using it.
+func TestCatalogs(t *testing.T) {
+
+	uses, err := Scan("..", DefaultCalls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uses) == 0 {
+		t.Fatal("the scan found no messages")
+	}
+
+	for _, lang := range Supported {
+		if lang == English {
+			continue
+		}
+
+		for _, use := range Missing(lang, uses) {
+			t.Errorf("%s: missing %s translation of %q", use.Pos, lang, use.Key)
+		}
+		for _, key := range Unused(lang, uses, Units...) {
+			t.Errorf("unused %s translation of %q", lang, key)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Use is a translatable string found in the source
+type Use struct {
+	Key string
+	Pos string
+}
+
+// DefaultCalls are the functions whose string argument (at the given
+// position) is a catalog key.
+var DefaultCalls = map[string]int{
+	"i18n.T":             1,
+	"i18n.Errorf":        0,
+	"i18n.Unit":          1,
+	"T":                  1,
+	"Errorf":             0,
+	"tr":                 1,
+	"renderErrorPage":    2,
+	"requirePicnicMates": 2,
+	"queueEmail":         3,
+}
+
+// en los templates: {{ t "Picnics" }} o (t "Edit %s" .Name)
+var templateCall = regexp.MustCompile(`(?:\{\{-?|\()\s*t\s+("(?:[^"\\]|\\.)*")`)
+
+// Scan finds the keys used under root: string literals passed to calls in
+// .go files and to the t func in .html and .txt templates. Calls without the
+// package name ("T", "Errorf") only count inside the i18n package.
+func Scan(root string, calls map[string]int) ([]Use, error) {
+
+	uses := make([]Use, 0)
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch filepath.Ext(path) {
+		case ".go":
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				return err
+			}
+			uses = append(uses, scanGo(fset, file, calls)...)
+		case ".html", ".txt":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			uses = append(uses, scanTemplate(path, string(data))...)
+		}
+		return nil
+	})
+	return uses, err
+}
+
+func scanGo(fset *token.FileSet, file *ast.File, calls map[string]int) []Use {
+
+	uses := make([]Use, 0)
+	inI18n := file.Name.Name == "i18n"
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		var name string
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			name = fun.Name
+			if (name == "T" || name == "Errorf") && !inI18n {
+				return true
+			}
+		case *ast.SelectorExpr:
+			pkg, ok := fun.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			name = pkg.Name + "." + fun.Sel.Name
+		default:
+			return true
+		}
+
+		index, ok := calls[name]
+		if !ok || index >= len(call.Args) {
+			return true
+		}
+		literal, ok := call.Args[index].(*ast.BasicLit)
+		if !ok || literal.Kind != token.STRING {
+			return true
+		}
+		key, err := strconv.Unquote(literal.Value)
+		if err != nil {
+			return true
+		}
+		uses = append(uses, Use{Key: key, Pos: fset.Position(literal.Pos()).String()})
+		return true
+	})
+	return uses
+}
+
+func scanTemplate(path string, text string) []Use {
+
+	uses := make([]Use, 0)
+	for _, match := range templateCall.FindAllStringSubmatchIndex(text, -1) {
+		key, err := strconv.Unquote(text[match[2]:match[3]])
+		if err != nil {
+			continue
+		}
+		line := strings.Count(text[:match[2]], "\n") + 1
+		uses = append(uses, Use{Key: key, Pos: path + ":" + strconv.Itoa(line)})
+	}
+	return uses
+}
+
+// Missing returns the uses that have no translation in lang
+func Missing(lang string, uses []Use) []Use {
+	missing := make([]Use, 0)
+	for _, use := range uses {
+		if _, ok := Lookup(lang, use.Key); !ok {
+			missing = append(missing, use)
+		}
+	}
+	return missing
+}
+
+// Unused returns the keys of lang's catalog that no use refers to. Units
+// are looked up with data from the database and never show up in a scan,
+// pass them in known.
+func Unused(lang string, uses []Use, known ...string) []string {
+
+	used := make(map[string]bool)
+	for _, use := range uses {
+		used[use.Key] = true
+	}
+	for _, key := range known {
+		used[key] = true
+	}
+
+	unused := make([]string, 0)
+	for key := range catalogs[lang] {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
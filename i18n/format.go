@@ -0,0 +1,123 @@
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type numberFormat struct {
+	thousands string
+	decimal   string
+}
+
+var numberFormats = map[string]numberFormat{
+	English: {thousands: ",", decimal: "."},
+	Spanish: {thousands: ".", decimal: ","},
+}
+
+func numberFormatOf(lang string) numberFormat {
+	if format, ok := numberFormats[lang]; ok {
+		return format
+	}
+	return numberFormats[Default]
+}
+
+// Number groups the thousands, 1234567 -> "1,234,567" or "1.234.567"
+func Number(lang string, n int) string {
+
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	digits := strconv.Itoa(n)
+
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteString(numberFormatOf(lang).thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String()
+}
+
+// Money shows an amount stored in cents, 123450 -> "1,234.50" or "1.234,50"
+func Money(lang string, cents int) string {
+
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%s%s%02d", sign, Number(lang, cents/100), numberFormatOf(lang).decimal, cents%100)
+}
+
+var spanishWeekdays = []string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"}
+var spanishMonths = []string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sep", "oct", "nov", "dic"}
+
+// Date formats a picnic date, the time is left out when it is midnight since
+// most picnics are saved with a date only.
+func Date(lang string, t time.Time) string {
+
+	withTime := t.Hour() != 0 || t.Minute() != 0
+
+	if lang == Spanish {
+		date := fmt.Sprintf("%s %d %s %d", spanishWeekdays[t.Weekday()], t.Day(), spanishMonths[t.Month()-1], t.Year())
+		if withTime {
+			date += t.Format(", 15:04")
+		}
+		return date
+	}
+
+	if withTime {
+		return t.Format("Mon, Jan 2, 2006 3:04 PM")
+	}
+	return t.Format("Mon, Jan 2, 2006")
+}
+
+// Unit translates a measure when the catalog knows it ("bottles" ->
+// "botellas"), anything else is shown as it was typed.
+func Unit(lang string, measure string) string {
+	if lang == English || measure == "" {
+		return measure
+	}
+	if translated, ok := Lookup(lang, strings.ToLower(measure)); ok {
+		return translated
+	}
+	return measure
+}
+
+// ParseMoney reads an amount typed in a form into cents, "1.234,50" in
+// Spanish and "1,234.50" in English. A single separator followed by one or
+// two digits is always the decimal one, so "12.5" works in both.
+func ParseMoney(lang string, value string) (int, error) {
+
+	typed := strings.TrimSpace(value)
+	if typed == "" {
+		return 0, nil
+	}
+	format := numberFormatOf(lang)
+	value = typed
+
+	if !strings.Contains(value, format.decimal) && strings.Count(value, format.thousands) == 1 {
+		if i := strings.Index(value, format.thousands); len(value)-i-1 <= 2 {
+			value = value[:i] + format.decimal + value[i+1:]
+		}
+	}
+	value = strings.ReplaceAll(value, format.thousands, "")
+	value = strings.Replace(value, format.decimal, ".", 1)
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount < 0 || math.IsInf(amount, 0) || math.IsNaN(amount) || amount > math.MaxInt32/100 {
+		return 0, Errorf("%q is not a valid amount", typed)
+	}
+	return int(math.Round(amount * 100)), nil
+}
+
+// Units are the measures Unit knows how to translate
+var Units = []string{
+	"bags", "bottles", "boxes", "cans", "cups", "dozen", "g", "jars", "kg",
+	"liters", "loaves", "packs", "pieces", "portions", "trays", "units",
+}
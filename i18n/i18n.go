@@ -0,0 +1,147 @@
+// Package i18n traduce los mensajes de la API, las paginas y los mails. Los
+// textos se escriben en ingles en el codigo y esa frase es la key del
+// catalogo, así que un texto sin traducir sale en ingles y no vacio.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	English = "en"
+	Spanish = "es"
+
+	Default = English
+)
+
+// Supported is the order a language picker shows them in
+var Supported = []string{English, Spanish}
+
+// Names are the languages in their own language
+var Names = map[string]string{
+	English: "English",
+	Spanish: "Español",
+}
+
+// catalogs tiene una entrada por idioma que no es el ingles
+var catalogs = map[string]map[string]string{
+	Spanish: spanish,
+}
+
+func IsSupported(lang string) bool {
+	for _, supported := range Supported {
+		if lang == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate picks the user's saved preference when there is one and
+// otherwise the best supported language of an Accept-Language header.
+func Negotiate(preference string, acceptLanguage string) string {
+
+	if IsSupported(preference) {
+		return preference
+	}
+
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	candidates := make([]candidate, 0)
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = value
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		// es-AR, es-419 y es son todos espanol
+		lang := strings.SplitN(tag, "-", 2)[0]
+		if tag == "*" {
+			lang = Default
+		}
+		if IsSupported(lang) {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].lang
+}
+
+// Lookup returns the translation of key, English is the key itself
+func Lookup(lang string, key string) (string, bool) {
+	if lang == English {
+		return key, true
+	}
+	translated, ok := catalogs[lang][key]
+	return translated, ok
+}
+
+// T translates key and, with args, uses it as a fmt format. Missing
+// translations fall back to English.
+func T(lang string, key string, args ...interface{}) string {
+	translated, ok := Lookup(lang, key)
+	if !ok {
+		translated = key
+	}
+	if len(args) == 0 {
+		return translated
+	}
+	return fmt.Sprintf(translated, args...)
+}
+
+// Error is an error whose message can be translated. Error() is the English
+// text, Translate gives it in another language.
+type Error struct {
+	Format string
+	Args   []interface{}
+}
+
+func (e *Error) Error() string {
+	if len(e.Args) == 0 {
+		return e.Format
+	}
+	return fmt.Sprintf(e.Format, e.Args...)
+}
+
+// Errorf is fmt.Errorf for messages users see. It doesn't wrap, %w isn't
+// supported.
+func Errorf(format string, args ...interface{}) error {
+	return &Error{Format: format, Args: args}
+}
+
+// Translate returns the message of err in lang. Errors that didn't come
+// from Errorf (database, JSON binding) keep their own text.
+func Translate(lang string, err error) string {
+	var translatable *Error
+	if errors.As(err, &translatable) {
+		return T(lang, translatable.Format, translatable.Args...)
+	}
+	return err.Error()
+}
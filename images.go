@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"server/blobstore"
+	"server/images"
+	"server/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxUploadSize is the largest photo accepted, before resizing
+const MaxUploadSize = 10 << 20
+
+var blobs blobstore.Store
+
+// receiveImage reads the "image" form file, processes it and stores both
+// files. The returned image still needs its owner fields and a row.
+func receiveImage(c *gin.Context) (models.Image, bool) {
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadSize+1<<20)
+
+	header, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Expected a multipart form with an image file")})
+		return models.Image{}, false
+	}
+	if header.Size > MaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tr(c, "Images can't be larger than %d MB", MaxUploadSize>>20)})
+		return models.Image{}, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return models.Image{}, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxUploadSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return models.Image{}, false
+	}
+
+	processed, err := images.Process(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, images.ErrUnsupported) {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, gin.H{"error": trErr(c, err)})
+		return models.Image{}, false
+	}
+
+	// nombre al azar: las URLs no se pueden adivinar y nunca cambian de contenido
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		serverError(c, err)
+		return models.Image{}, false
+	}
+	name := hex.EncodeToString(random)
+
+	image := models.Image{
+		Name:        name + processed.Ext,
+		ThumbName:   name + "_thumb" + processed.Ext,
+		ContentType: processed.ContentType,
+		Width:       processed.Width,
+		Height:      processed.Height,
+		Size:        len(processed.Full),
+	}
+	image.UserID, _ = currentUserID(c)
+
+	if err := blobs.Put(blobKey(image.Name), bytes.NewReader(processed.Full)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": trErr(c, err)})
+		return models.Image{}, false
+	}
+	if err := blobs.Put(blobKey(image.ThumbName), bytes.NewReader(processed.Thumb)); err != nil {
+		removeImageFiles(image)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": trErr(c, err)})
+		return models.Image{}, false
+	}
+	return image, true
+}
+
+func blobKey(name string) string {
+	return "images/" + name
+}
+
+func removeImageFiles(image models.Image) {
+	for _, name := range []string{image.Name, image.ThumbName} {
+		if err := blobs.Delete(blobKey(name)); err != nil {
+			log.Printf("images: removing %s: %v", name, err)
+		}
+	}
+}
+
+func uploadFoodItemImage(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid ID")})
+		return
+	}
+
+	foodItem, err := models.GetFoodItemById(id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if foodItem.Name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Record of that id not found")})
+		return
+	}
+
+	image, ok := receiveImage(c)
+	if !ok {
+		return
+	}
+	image.FoodItemID = id
+
+	image, replaced, err := models.SetFoodItemImage(image)
+	if err != nil {
+		removeImageFiles(image)
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+	for _, old := range replaced {
+		removeImageFiles(old)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": image})
+}
+
+func addPicnicImage(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	image, ok := receiveImage(c)
+	if !ok {
+		return
+	}
+	image.PicnicID = picnicID
+
+	created, err := models.CreateImage(image)
+	if err != nil {
+		removeImageFiles(image)
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": created})
+}
+
+func readAllImagesOfPicnic(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	images, err := models.GetImagesByPicnic(picnicID)
+
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": images})
+}
+
+// deletePicnicImage lets the uploader remove their photo, owners and co-hosts
+// can remove any.
+func deletePicnicImage(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid image ID")})
+		return
+	}
+
+	image, err := models.GetImageById(id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if image.ID == 0 || image.PicnicID != picnicID {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Image of that id not found")})
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	allowed, err := mayManageItem(userID, picnicID, image.UserID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only delete your own photos")})
+		return
+	}
+
+	success, err := models.DeleteImage(id)
+
+	if success {
+		removeImageFiles(image)
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+// serveImage serves uploaded files. A name is never reused for other content,
+// so browsers may keep them for a year without asking again.
+func serveImage(c *gin.Context) {
+
+	name := c.Param("name")
+
+	image, err := models.GetImageByName(name)
+	if err != nil {
+		log.Printf("images: %s: %v", name, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if image.ID == 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	blob, err := blobs.Open(blobKey(name))
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Content-Type", image.ContentType)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", `"`+name+`"`)
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	// ServeContent contesta If-None-Match / If-Modified-Since y los Range
+	modTime := blob.ModTime()
+	if modTime.IsZero() {
+		modTime = time.Unix(0, 0)
+	}
+	http.ServeContent(c.Writer, c.Request, name, modTime, blob)
+}
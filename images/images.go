@@ -0,0 +1,184 @@
+// Package images validates uploaded photos and re-encodes them. Re-encoding
+// drops every metadata block (EXIF, GPS, comments) and the EXIF orientation
+// is applied to the pixels first so phone photos don't end up sideways.
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"server/i18n"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	// MaxSize is the longest side of the stored image, ThumbSize the one of
+	// the thumbnail. Smaller images are never scaled up.
+	MaxSize   = 1600
+	ThumbSize = 320
+
+	// evita que un png chiquito se descomprima en gigas: 16MP ya son 64MB
+	// decodificados, mas las copias al escalar
+	maxPixels = 16 * 1000 * 1000
+
+	// cuantas imagenes se decodifican a la vez, el resto espera su turno
+	maxDecoding = 2
+)
+
+var decoding = make(chan struct{}, maxDecoding)
+
+var ErrUnsupported = i18n.Errorf("only jpeg, png and gif images are allowed")
+
+// Image is a processed upload, Full and Thumb are already encoded as
+// ContentType.
+type Image struct {
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Full        []byte
+	Thumb       []byte
+}
+
+// Process sniffs data (the client's Content-Type isn't trusted), decodes it
+// and returns the cleaned image and its thumbnail. jpeg stays jpeg, png and
+// gif become png; animated gifs keep only the first frame. Only maxDecoding
+// calls decode at a time, the others wait.
+func Process(data []byte) (Image, error) {
+
+	mime := mimetype.Detect(data)
+	var ext, contentType string
+	switch {
+	case mime.Is("image/jpeg"):
+		ext, contentType = ".jpg", "image/jpeg"
+	case mime.Is("image/png"), mime.Is("image/gif"):
+		ext, contentType = ".png", "image/png"
+	default:
+		return Image{}, ErrUnsupported
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, i18n.Errorf("invalid image: %v", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return Image{}, i18n.Errorf("image is too large (%dx%d)", config.Width, config.Height)
+	}
+
+	decoding <- struct{}{}
+	defer func() { <-decoding }()
+
+	var decoded image.Image
+	if mime.Is("image/gif") {
+		decoded, err = gif.Decode(bytes.NewReader(data))
+	} else {
+		decoded, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return Image{}, i18n.Errorf("invalid image: %v", err)
+	}
+
+	src := toRGBA(decoded)
+	if contentType == "image/jpeg" {
+		src = orient(src, exifOrientation(data))
+	}
+
+	full := fit(src, MaxSize)
+	thumb := fit(src, ThumbSize)
+
+	result := Image{
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       full.Bounds().Dx(),
+		Height:      full.Bounds().Dy(),
+	}
+	if result.Full, err = encode(full, contentType); err != nil {
+		return Image{}, err
+	}
+	if result.Thumb, err = encode(thumb, contentType); err != nil {
+		return Image{}, err
+	}
+	return result, nil
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// fit scales src down so its longest side is at most size, keeping the
+// aspect ratio.
+func fit(src *image.RGBA, size int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	if w >= h {
+		h = max(1, h*size/w)
+		w = size
+	} else {
+		w = max(1, w*size/h)
+		h = size
+	}
+	return resize(src, w, h)
+}
+
+// resize promedia el bloque de pixeles de src que cae en cada pixel de dst.
+// Solo se usa para achicar, para eso alcanza y no hace falta x/image.
+func resize(src *image.RGBA, dw, dh int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0 := y * sh / dh
+		y1 := max(y0+1, (y+1)*sh/dh)
+		for x := 0; x < dw; x++ {
+			x0 := x * sw / dw
+			x1 := max(x0+1, (x+1)*sw/dw)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,44 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngOfSize is a valid 1x1 png whose header claims width x height, enough
+// for DecodeConfig
+func pngOfSize(t *testing.T, width uint32, height uint32) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// firma de 8 bytes, largo y tipo del IHDR, despues ancho y alto
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	return data
+}
+
+func TestProcessRejectsHugeImages(t *testing.T) {
+
+	if _, err := Process(pngOfSize(t, 4001, 4000)); err == nil || err.Error() != "image is too large (4001x4000)" {
+		t.Errorf("a 16MP png: %v", err)
+	}
+
+	img, err := Process(pngOfSize(t, 1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 1 || img.Height != 1 || img.ContentType != "image/png" {
+		t.Errorf("got %+v", img)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"net/http"
+	"server/live"
+	"server/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func addInventoryItem(c *gin.Context) {
+
+	var json models.InventoryItem
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	item, err := models.CreateInventoryItem(json)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": item})
+}
+
+func readAllInventoryItems(c *gin.Context) {
+
+	items, err := models.GetInventoryItems()
+
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+func inventoryItemParam(c *gin.Context) (models.InventoryItem, bool) {
+
+	id, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid item ID")})
+		return models.InventoryItem{}, false
+	}
+
+	item, err := models.GetInventoryItemById(id)
+	if err != nil {
+		serverError(c, err)
+		return models.InventoryItem{}, false
+	}
+
+	if item.ID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Inventory item of that id not found")})
+		return models.InventoryItem{}, false
+	}
+	return item, true
+}
+
+func readInventoryItem(c *gin.Context) {
+
+	item, ok := inventoryItemParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+func updateInventoryItem(c *gin.Context) {
+
+	var json models.InventoryItem
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	item, ok := inventoryItemParam(c)
+	if !ok {
+		return
+	}
+
+	success, err := models.UpdateInventoryItem(json, item.ID)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+// GET /inventory/:item_id/history, newest first
+func readInventoryItemHistory(c *gin.Context) {
+
+	item, ok := inventoryItemParam(c)
+	if !ok {
+		return
+	}
+
+	history, err := models.GetItemHistory(item.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve history")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": history})
+}
+
+// GET /inventory/conflicts lists reservations that ended up on overlapping
+// dates, usually because a picnic was moved after reserving.
+func readInventoryConflicts(c *gin.Context) {
+
+	conflicts, err := models.GetDoubleBookings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to check reservations")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": conflicts})
+}
+
+func readAllReservationsOfPicnic(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	reservations, err := models.GetReservationsByPicnic(picnicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve reservations")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": reservations})
+}
+
+func reserveInventoryItem(c *gin.Context) {
+
+	var json models.Reservation
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	json.PicnicID = picnicID
+	json.UserID, _ = currentUserID(c)
+
+	reservation, err := models.ReserveItem(json)
+	if doubleBooking, ok := err.(*models.DoubleBookingError); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": trErr(c, err), "data": doubleBooking.Conflict})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	live.Publish(picnicID, models.EventInventoryReserved, reservation)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": reservation})
+}
+
+// requireReservation loads :reservation_id of :picnic_id. Whoever reserved or
+// holds the item can move it along, and so can owners and co-hosts.
+func requireReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+			return
+		}
+
+		id, err := strconv.Atoi(c.Param("reservation_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid reservation ID")})
+			return
+		}
+
+		reservation, err := models.GetReservationById(id)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if reservation.ID == 0 || reservation.PicnicID != picnicID {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": tr(c, "Reservation of that id not found")})
+			return
+		}
+
+		userID, _ := currentUserID(c)
+		allowed, err := mayManageItem(userID, picnicID, reservation.UserID)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only manage your own reservations")})
+			return
+		}
+
+		c.Set("reservation", reservation)
+		c.Next()
+	}
+}
+
+// checkoutInventoryItem gives the item to the current user, or to user_id
+// when an organizer hands it to someone else.
+func checkoutInventoryItem(c *gin.Context) {
+
+	var json struct {
+		UserID int `json:"user_id"`
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+			return
+		}
+	}
+
+	reservation := c.MustGet("reservation").(models.Reservation)
+
+	userID, _ := currentUserID(c)
+	holderID := userID
+	if json.UserID != 0 && json.UserID != userID {
+		allowed, err := mayManageItem(userID, reservation.PicnicID, json.UserID)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only organizers can hand items to someone else")})
+			return
+		}
+		holderID = json.UserID
+	}
+
+	reservation, err := models.CheckoutItem(reservation.ID, holderID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	live.Publish(reservation.PicnicID, models.EventInventoryCheckedOut, reservation)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": reservation})
+}
+
+func returnInventoryItem(c *gin.Context) {
+
+	reservation := c.MustGet("reservation").(models.Reservation)
+
+	reservation, err := models.ReturnItem(reservation.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	live.Publish(reservation.PicnicID, models.EventInventoryReturned, reservation)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": reservation})
+}
+
+func cancelReservation(c *gin.Context) {
+
+	reservation := c.MustGet("reservation").(models.Reservation)
+
+	success, err := models.CancelReservation(reservation.ID)
+
+	if success {
+		live.Publish(reservation.PicnicID, models.EventInventoryCancelled, gin.H{"id": reservation.ID, "item_id": reservation.ItemID})
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
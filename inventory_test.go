@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"server/models"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentReservationsDontDoubleBook(t *testing.T) {
+
+	owner := newTestUser(t, "gear-owner")
+	item, err := models.CreateInventoryItem(models.InventoryItem{Name: "Tent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// todos el mismo dia
+	const picnics = 8
+	picnicIDs := make([]int, picnics)
+	for i := range picnicIDs {
+		picnicIDs[i], err = models.CreatePicnic(models.Picnic{Name: fmt.Sprintf("Camp %d", i), Date: "2026-11-21 13:00", CreatedBy: owner.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, picnics)
+	for i, picnicID := range picnicIDs {
+		wg.Add(1)
+		go func(i int, picnicID int) {
+			defer wg.Done()
+			_, errs[i] = models.ReserveItem(models.Reservation{ItemID: item.ID, PicnicID: picnicID, UserID: owner.ID})
+		}(i, picnicID)
+	}
+	wg.Wait()
+
+	reserved := 0
+	for _, err := range errs {
+		var doubleBooking *models.DoubleBookingError
+		switch {
+		case err == nil:
+			reserved++
+		case !errors.As(err, &doubleBooking):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if reserved != 1 {
+		t.Errorf("%d picnics reserved the tent, want 1", reserved)
+	}
+}
+
+func TestDoubleBookingNamesThePicnic(t *testing.T) {
+
+	p := newRolePicnic(t)
+	owner := p.users["owner"]
+	item, err := models.CreateInventoryItem(models.InventoryItem{Name: "Grill"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/v1/picnics/%d/inventory", p.picnicID)
+
+	if w := owner.do(t, "POST", path, models.Reservation{ItemID: item.ID}); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+
+	w := owner.do(t, "POST", path, models.Reservation{ItemID: item.ID})
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "already reserved for this picnic") {
+		t.Errorf("same picnic: got %d: %s", w.Code, w.Body)
+	}
+
+	other, err := models.CreatePicnic(models.Picnic{Name: "Same day", Date: "2026-11-21 15:00", CreatedBy: owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = owner.do(t, "POST", fmt.Sprintf("/api/v1/picnics/%d/inventory", other), models.Reservation{ItemID: item.ID})
+	want := fmt.Sprintf("already reserved for picnic %d on overlapping dates", p.picnicID)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), want) {
+		t.Errorf("other picnic: got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestInventoryNeedsALoggedInUser(t *testing.T) {
+
+	u := newTestUser(t, "gear-keeper")
+	item, err := models.CreateInventoryItem(models.InventoryItem{Name: "Grill"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{"POST", "/api/v1/inventory/", models.InventoryItem{Name: "Cooler"}},
+		{"PUT", fmt.Sprintf("/api/v1/inventory/%d", item.ID), models.InventoryItem{Name: "Big grill"}},
+		{"GET", fmt.Sprintf("/api/v1/inventory/%d/history", item.ID), nil},
+		{"GET", "/api/v1/inventory/conflicts", nil},
+	}
+	for _, r := range requests {
+		if w := (testUser{}).do(t, r.method, r.path, r.body); w.Code != http.StatusUnauthorized {
+			t.Errorf("anonymous %s %s: got %d, want 401", r.method, r.path, w.Code)
+		}
+		if w := u.do(t, r.method, r.path, r.body); w.Code != http.StatusOK {
+			t.Errorf("%s %s: got %d: %s", r.method, r.path, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"server/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func addInvitation(c *gin.Context) {
+
+	var json struct {
+		UserID         int `json:"user_id"`
+		ExpiresInHours int `json:"expires_in_hours"`
+	}
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	if json.UserID != 0 {
+		user, err := models.GetUserById(json.UserID)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if user.Name == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User of that id not found")})
+			return
+		}
+	}
+
+	createdBy, _ := currentUserID(c)
+
+	invitation, token, err := models.CreateInvitation(models.Invitation{
+		PicnicID:  picnicID,
+		UserID:    json.UserID,
+		CreatedBy: createdBy,
+	}, time.Duration(json.ExpiresInHours)*time.Hour)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	notifyInvitation(invitation, token)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": gin.H{
+		"invitation": invitation,
+		"token":      token,
+		"url":        "/invitations/" + token,
+	}})
+}
+
+func readAllInvitationsOfPicnic(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	invitations, err := models.GetInvitationsByPicnic(picnicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve invitations")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": invitations})
+}
+
+func deleteInvitation(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	invitationID, err := strconv.Atoi(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid invitation ID")})
+		return
+	}
+
+	success, err := models.RevokeInvitation(invitationID, picnicID)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusNotFound, gin.H{"error": trErr(c, err)})
+	}
+}
+
+func readInvitation(c *gin.Context) {
+
+	invitation, err := models.GetInvitationByToken(c.Param("token"))
+	if errors.Is(err, models.ErrInvalidInvitation) {
+		c.JSON(http.StatusNotFound, gin.H{"error": trErr(c, err)})
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	picnic, err := models.GetPicnicById(invitation.PicnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"invitation": invitation, "picnic": picnic}})
+}
+
+func acceptInvitation(c *gin.Context) {
+	answerInvitation(c, acceptAndNotify)
+}
+
+// acceptAndNotify is AcceptInvitation plus membershipAdded
+func acceptAndNotify(token string, userID int) (models.Invitation, error) {
+
+	invitation, err := models.AcceptInvitation(token, userID)
+	if err != nil {
+		return invitation, err
+	}
+
+	membership, err := models.GetMembership(userID, invitation.PicnicID)
+	if err != nil {
+		return invitation, err
+	}
+	membershipAdded(membership)
+	return invitation, nil
+}
+
+func declineInvitation(c *gin.Context) {
+	answerInvitation(c, models.DeclineInvitation)
+}
+
+func answerInvitation(c *gin.Context, answer func(token string, userID int) (models.Invitation, error)) {
+
+	userID, _ := currentUserID(c)
+
+	invitation, err := answer(c.Param("token"), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	membership, err := models.GetMembership(userID, invitation.PicnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": gin.H{"invitation": invitation, "membership": membership}})
+}
+
+// Landing page de /invitations/:token, para quien abre el link en el navegador
+
+func showInvitationPage(c *gin.Context) {
+	renderInvitationPage(c, "")
+}
+
+func acceptInvitationPage(c *gin.Context) {
+	answerInvitationPage(c, acceptAndNotify, tr(c, "You're in! See you at the picnic."))
+}
+
+func declineInvitationPage(c *gin.Context) {
+	answerInvitationPage(c, models.DeclineInvitation, tr(c, "You declined this invitation."))
+}
+
+func answerInvitationPage(c *gin.Context, answer func(token string, userID int) (models.Invitation, error), message string) {
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		renderInvitationPage(c, "")
+		return
+	}
+
+	invitation, err := answer(c.Param("token"), userID)
+	if err != nil {
+		renderInvitationPage(c, trErr(c, err))
+		return
+	}
+
+	membership, err := models.GetMembership(userID, invitation.PicnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if membership.Status == models.MembershipWaitlisted {
+		message = tr(c, "The picnic is full, you are number %d on the waitlist.", membership.WaitlistPosition)
+	}
+	renderInvitationPage(c, message)
+}
+
+func renderInvitationPage(c *gin.Context, message string) {
+
+	token := c.Param("token")
+	user, _ := currentUser(c)
+
+	invitation, err := models.GetInvitationByToken(token)
+	if errors.Is(err, models.ErrInvalidInvitation) {
+		renderPage(c, http.StatusNotFound, "invitation_show", gin.H{"Title": tr(c, "Invitation"), "Error": trErr(c, err)})
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	picnic, err := models.GetPicnicById(invitation.PicnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	renderPage(c, http.StatusOK, "invitation_show", gin.H{
+		"Title":      tr(c, "Invitation"),
+		"Invitation": invitation,
+		"Picnic":     picnic,
+		"Token":      token,
+		"User":       user,
+		"Message":    message,
+	})
+}
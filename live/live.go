@@ -0,0 +1,106 @@
+// Package live fans picnic events out to the clients streaming
+// /picnics/:picnic_id/events. Every event is written to the picnic_events
+// log first, so a client that reconnects with Last-Event-ID can catch up.
+package live
+
+import (
+	"encoding/json"
+	"log"
+	"server/models"
+	"sync"
+)
+
+// Buffer is how many events a connection may fall behind before it is
+// dropped. The client reconnects and replays the rest from the log.
+const Buffer = 64
+
+type Subscriber struct {
+	PicnicID int
+	Events   chan models.PicnicEvent
+
+	dropped chan struct{}
+	once    sync.Once
+}
+
+// Dropped is closed when the subscriber was too slow to keep up
+func (s *Subscriber) Dropped() <-chan struct{} {
+	return s.dropped
+}
+
+func (s *Subscriber) drop() {
+	s.once.Do(func() { close(s.dropped) })
+}
+
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[int]map[*Subscriber]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[int]map[*Subscriber]struct{})}
+}
+
+var DefaultBroker = NewBroker()
+
+func (b *Broker) Subscribe(picnicID int) *Subscriber {
+
+	s := &Subscriber{
+		PicnicID: picnicID,
+		Events:   make(chan models.PicnicEvent, Buffer),
+		dropped:  make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[picnicID] == nil {
+		b.subscribers[picnicID] = make(map[*Subscriber]struct{})
+	}
+	b.subscribers[picnicID][s] = struct{}{}
+	return s
+}
+
+func (b *Broker) Unsubscribe(s *Subscriber) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers[s.PicnicID], s)
+	if len(b.subscribers[s.PicnicID]) == 0 {
+		delete(b.subscribers, s.PicnicID)
+	}
+}
+
+// Broadcast never blocks, a subscriber with a full buffer is dropped instead
+func (b *Broker) Broadcast(event models.PicnicEvent) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers[event.PicnicID] {
+		select {
+		case s.Events <- event:
+		default:
+			s.drop()
+			delete(b.subscribers[event.PicnicID], s)
+		}
+	}
+}
+
+// Publish logs event for picnicID and sends it to everyone listening. Like
+// webhooks.Emit, errors are only logged.
+func Publish(picnicID int, event string, data interface{}) {
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("live: %s: %v", event, err)
+		return
+	}
+
+	logged, err := models.AddPicnicEvent(picnicID, event, string(body))
+	if err != nil {
+		log.Printf("live: %s: %v", event, err)
+		return
+	}
+	DefaultBroker.Broadcast(logged)
+}
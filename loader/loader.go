@@ -0,0 +1,93 @@
+// Package loader batches lookups by id. Load only queues the key and
+// returns a thunk, the first thunk that runs fetches every queued key with
+// one call to the batch function:
+//
+//	users := loader.New(fetchUsers)
+//	a, b := users.Load(1), users.Load(2)
+//	user, err := a() // una sola query para 1 y 2
+//
+// GraphQL resolvers return the thunks, the executor runs them once every
+// sibling has queued its key. A Loader lives for one request, its results
+// are never invalidated.
+package loader
+
+import "sync"
+
+// BatchFunc returns the values of keys, a key missing from the map loads nil
+type BatchFunc func(keys []int) (map[int]interface{}, error)
+
+type Loader struct {
+	mu      sync.Mutex
+	batch   BatchFunc
+	queued  []int
+	pending map[int]bool
+	loaded  map[int]interface{}
+	errs    map[int]error
+	batches int
+}
+
+func New(batch BatchFunc) *Loader {
+	return &Loader{
+		batch:   batch,
+		pending: make(map[int]bool),
+		loaded:  make(map[int]interface{}),
+		errs:    make(map[int]error),
+	}
+}
+
+// Load queues key for the next batch
+func (l *Loader) Load(key int) func() (interface{}, error) {
+
+	l.mu.Lock()
+	if !l.known(key) && !l.pending[key] {
+		l.queued = append(l.queued, key)
+		l.pending[key] = true
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if !l.known(key) {
+			l.flush()
+		}
+		return l.loaded[key], l.errs[key]
+	}
+}
+
+// Batches is how many times the batch function ran
+func (l *Loader) Batches() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.batches
+}
+
+func (l *Loader) known(key int) bool {
+	if _, ok := l.loaded[key]; ok {
+		return true
+	}
+	_, ok := l.errs[key]
+	return ok
+}
+
+// flush runs with the lock held
+func (l *Loader) flush() {
+
+	keys := l.queued
+	l.queued = nil
+	l.pending = make(map[int]bool)
+	if len(keys) == 0 {
+		return
+	}
+
+	l.batches++
+	values, err := l.batch(keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		l.loaded[key] = values[key]
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"server/i18n"
+	"server/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const langKey = "lang"
+
+// localize picks the language of the response: the user's saved locale, or
+// the Accept-Language header for everyone else. It runs after authenticate.
+func localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		setLang(c)
+		c.Next()
+	}
+}
+
+// setLang runs again when an API token changes the current user
+func setLang(c *gin.Context) {
+	user, _ := currentUser(c)
+	lang := i18n.Negotiate(user.Locale, c.GetHeader("Accept-Language"))
+
+	c.Set(langKey, lang)
+	c.Header("Content-Language", lang)
+}
+
+func langOf(c *gin.Context) string {
+	if lang := c.GetString(langKey); lang != "" {
+		return lang
+	}
+	return i18n.Default
+}
+
+// tr translates a message for the current request
+func tr(c *gin.Context, key string, args ...interface{}) string {
+	return i18n.T(langOf(c), key, args...)
+}
+
+// trErr is err.Error() in the language of the request
+func trErr(c *gin.Context, err error) string {
+	if err == nil {
+		return ""
+	}
+	return i18n.Translate(langOf(c), err)
+}
+
+// userLang is the language of the emails sent to user
+func userLang(user models.User) string {
+	return i18n.Negotiate(user.Locale, "")
+}
@@ -0,0 +1,183 @@
+// Package mailer sends the emails queued in the outbox. The server picks a
+// backend from the environment, tests use Capture.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Mailer interface {
+	Send(message Message) error
+}
+
+// FromEnv uses SMTP when SMTP_HOST is set. Without it mails are kept in a
+// Capture and logged, so local development never needs a mail server.
+func FromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return &Capture{Log: true}
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "picnic@" + host
+	}
+
+	return &SMTP{
+		Addr:     host + ":" + port,
+		Host:     host,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}
+}
+
+type SMTP struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTP) Send(message Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	body, err := buildMIME(s.From, message)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, []string{message.To}, body)
+}
+
+// buildMIME arma un multipart/alternative con la version texto y la html.
+// El asunto va codificado segun RFC 2047 porque puede traer acentos.
+func buildMIME(from string, message Message) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	messageID, err := newMessageID(from)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := []string{
+		"From: " + headerValue(from),
+		"To: " + headerValue(message.To),
+		"Subject: " + mime.QEncoding.Encode("utf-8", headerValue(message.Subject)),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write([]byte(part.body))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// headerValue keeps a value on one line, a \r or \n would let it add headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+// newMessageID is a random <id@domain>, the domain is the one of from
+func newMessageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.Trim(headerValue(from[at+1:]), "<> ")
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">", nil
+}
+
+// Capture keeps every message in memory. Set Fail to make Send return an
+// error, e.g. to exercise the outbox retries.
+type Capture struct {
+	Log  bool
+	Fail error
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (c *Capture) Send(message Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Fail != nil {
+		return c.Fail
+	}
+	c.messages = append(c.messages, message)
+	if c.Log {
+		log.Printf("mail to %s: %s", message.To, message.Subject)
+	}
+	return nil
+}
+
+func (c *Capture) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.messages...)
+}
+
+// SentTo returns the messages addressed to to, handy for assertions.
+func (c *Capture) SentTo(to string) []Message {
+	sent := make([]Message, 0)
+	for _, m := range c.Messages() {
+		if m.To == to {
+			sent = append(sent, m)
+		}
+	}
+	return sent
+}
@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"bytes"
+	"mime"
+	"net/mail"
+	"testing"
+)
+
+func TestBuildMIMEHeaders(t *testing.T) {
+
+	body, err := buildMIME("Picnic <picnic@example.com>", Message{
+		To:      "ana@example.com\r\nBcc: eve@example.com",
+		Subject: "Cambió el picnic\r\nBcc: eve@example.com",
+		Text:    "hola",
+		HTML:    "<p>hola</p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bcc := msg.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("a header value added Bcc: %q", bcc)
+	}
+
+	subject := msg.Header.Get("Subject")
+	if subject == "Cambió el picnic  Bcc: eve@example.com" {
+		t.Errorf("subject is not encoded: %q", subject)
+	}
+	decoded, err := new(mime.WordDecoder).DecodeHeader(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "Cambió el picnic  Bcc: eve@example.com" {
+		t.Errorf("subject decodes to %q", decoded)
+	}
+
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+	if id := msg.Header.Get("Message-ID"); len(id) < len("<@example.com>") || id[len(id)-len("@example.com>"):] != "@example.com>" {
+		t.Errorf("Message-ID is %q", id)
+	}
+}
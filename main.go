@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"server/blobstore"
+	"server/mailer"
 	"server/models"
+	"server/reminders"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,52 +25,58 @@ func main() {
 	err := models.ConnectDatabase()
 	checkErr(err)
 
-	r := gin.Default()
-
-	// API v1
-	v1 := r.Group("/api/v1")
-	{
-		v1.POST("/picnics/", addPicnic)
-		v1.GET("/picnics/:picnic_id", readPicnic)
-		v1.GET("/picnics/", readAllPicnics)
-		v1.PUT("/picnics/:picnic_id", updatePicnic)
-		v1.DELETE("/picnics/:picnic_id", deletePicnic)
-
-		v1.POST("/users/", addUser)
-		v1.GET("/users/:user_id", readUser)
-		v1.GET("/users/", readAllUsers)
-		v1.PUT("users/:user_id", updateUser)
-
-		v1.POST("/picnics/:picnic_id/users/:user_id", addUserToPicnic)
-		v1.GET("/picnics/:picnic_id/users", readAllUsersOfPicnic)
-		v1.GET("/users/:user_id/picnics", readAllPicnicsOfUser)
-		// v1.DELETE("/picnics/:picnic_id/users/:user_id", deletePicnicFromUser)
-		// v1.DELETE("/users/:user_id/picnics/:picnic_id", deleteUserFromPicnic)
-
-		v1.POST("/food-items/", addFoodItem)
-		v1.GET("/food-items/:item_id", readFoodItem)
-		v1.GET("/food-items/", readAllFoodItems)
-		v1.PUT("/food-items/:item_id", updateFoodItem)
-		//v1.DELETE("/food-items/:item_id", deleteFoodItem)
+	// SIGINT/SIGTERM cancela ctx y el server se apaga ordenadamente
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		v1.POST("/contributions/", addContribution)
-		v1.GET("/contributions/:contribution_id", readContribution)
-		v1.GET("/contributions/", readAllContributions)
-		v1.PUT("/contributions/:contribution_id", updateContribution)
-		v1.DELETE("/contributions/:contribution_id", deleteContribution)
-		// TODO: Crear pruebas en postman, implementar delete, read all contibutions
+	mail = mailer.FromEnv()
+	go runOutbox(mail, 10*time.Second)
 
+	offsets, err := reminders.OffsetsFromEnv()
+	checkErr(err)
+	reminderEngine := &reminders.Engine{
+		Offsets:  offsets,
+		Channels: []reminders.Channel{reminderEmail{}, reminders.LogChannel{}},
+		Interval: time.Minute,
 	}
+	go reminderEngine.Run(ctx.Done())
+
+	startWebhooks(ctx)
+
+	blobs = blobstore.FromEnv()
 
 	// By default it serves on :8080 unless a
 	// PORT environment variable was defined.
+	addr := ":8080"
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+
+	// los streams SSE usan el contexto del request, se cortan con ctx
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     newHandler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
 
-	r.Run()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
 
-	// picnic routes GET (main page)
-	// router.GET("/", picnicIndex)
-	// router.GET("/picnics", picnicIndex)
-	// router.GET("/picnics/:picnic_id/edit", picnicEdit)
+	<-ctx.Done()
+	log.Println("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := collabHub.Shutdown(shutdownCtx); err != nil {
+		log.Printf("collab: %v", err)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
 
 	// // picnic endpoints
 	// router.GET("/picnics/:picnic_id", readPicnic)
@@ -91,22 +107,226 @@ func main() {
 
 }
 
+// newHandler is what the server serves: the router behind methodOverride,
+// which has to change the method before gin picks the route. The tests
+// serve it with httptest.
+func newHandler() http.Handler {
+
+	r := newRouter()
+	if err := setupOpenAPI(r); err != nil {
+		log.Printf("openapi: %v", err)
+	}
+	return methodOverride(r)
+}
+
+// newRouter registers every page and API route
+func newRouter() *gin.Engine {
+
+	r := gin.Default()
+	r.HTMLRender = templateRender{}
+	r.GET("/assets/*filepath", serveAsset)
+	r.HEAD("/assets/*filepath", serveAsset)
+	r.GET(models.ImagePath+":name", serveImage)
+	r.Use(authenticate())
+	r.Use(localize())
+	r.Use(csrfProtect())
+	r.NoRoute(notFound)
+
+	r.GET("/invitations/:token", showInvitationPage)
+	r.POST("/invitations/:token/accept", acceptInvitationPage)
+	r.POST("/invitations/:token/decline", declineInvitationPage)
+
+	// paginas HTML, la API JSON esta en /api/v1. Los forms mandan PUT y
+	// DELETE con _method, ver methodOverride
+	pageUser := requirePageUser()
+
+	r.GET("/", homePage)
+	r.GET("/login", loginPage)
+	r.POST("/login", loginPageSubmit)
+	r.POST("/logout", logoutPage)
+
+	r.GET("/picnics", picnicsIndexPage)
+	r.GET("/picnics/new", pageUser, newPicnicPage)
+	r.POST("/picnics", pageUser, createPicnicPage)
+	r.GET("/picnics/:picnic_id", showPicnicPage)
+	r.GET("/picnics/:picnic_id/edit", pageUser, requirePagePicnicRole(models.RoleOwner, models.RoleCoHost), editPicnicPage)
+	r.PUT("/picnics/:picnic_id", pageUser, requirePagePicnicRole(models.RoleOwner, models.RoleCoHost), updatePicnicPage)
+	r.DELETE("/picnics/:picnic_id", pageUser, requirePagePicnicRole(models.RoleOwner), deletePicnicPage)
+
+	r.GET("/users", usersIndexPage)
+	r.GET("/users/new", newUserPage)
+	r.POST("/users", createUserPage)
+	r.GET("/users/:user_id", showUserPage)
+	r.GET("/users/:user_id/edit", pageUser, requirePageSelf(), editUserPage)
+	r.PUT("/users/:user_id", pageUser, requirePageSelf(), updateUserPage)
+
+	r.GET("/food-items", foodItemsIndexPage)
+	r.GET("/food-items/new", pageUser, newFoodItemPage)
+	r.POST("/food-items", pageUser, createFoodItemPage)
+	r.GET("/food-items/:item_id", showFoodItemPage)
+	r.GET("/food-items/:item_id/edit", pageUser, editFoodItemPage)
+	r.PUT("/food-items/:item_id", pageUser, updateFoodItemPage)
+
+	r.POST("/contributions", pageUser, createContributionPage)
+	r.GET("/contributions/:contribution_id/edit", pageUser, requirePageContribution(), editContributionPage)
+	r.PUT("/contributions/:contribution_id", pageUser, requirePageContribution(), updateContributionPage)
+	r.DELETE("/contributions/:contribution_id", pageUser, requirePageContribution(), deleteContributionPage)
+
+	// API v1
+	v1 := r.Group("/api/v1")
+	v1.Use(authenticateToken())
+	{
+		// scopes only restrict token requests, sessions can do everything
+		read := requireScope(models.ScopePicnicsRead)
+		writeContributions := requireScope(models.ScopeContributionsWrite)
+		admin := requireScope(models.ScopeAdmin)
+		// cualquier rol, los declined no cuentan
+		member := requirePicnicRole(models.RoleOwner, models.RoleCoHost, models.RoleAttendee)
+
+		v1.GET("/openapi.json", readOpenAPI)
+		v1.GET("/docs", apiDocsPage)
+
+		v1.POST("/auth/register", register)
+		v1.POST("/auth/login", login)
+		v1.POST("/auth/logout", admin, requireUser(), logout)
+		v1.GET("/auth/me", read, requireUser(), readCurrentUser)
+		v1.GET("/auth/sessions", admin, requireUser(), readAllSessions)
+		v1.DELETE("/auth/sessions/:session_id", admin, requireUser(), revokeSession)
+
+		v1.POST("/tokens/", admin, requireUser(), addToken)
+		v1.GET("/tokens/", admin, requireUser(), readAllTokens)
+		v1.DELETE("/tokens/:token_id", admin, requireUser(), revokeToken)
+
+		v1.POST("/webhooks/", admin, requireUser(), addWebhook)
+		v1.GET("/webhooks/", admin, requireUser(), readAllWebhooks)
+		v1.GET("/webhooks/:webhook_id", admin, requireUser(), requireWebhookOwner(), readWebhook)
+		v1.PUT("/webhooks/:webhook_id", admin, requireUser(), requireWebhookOwner(), updateWebhook)
+		v1.DELETE("/webhooks/:webhook_id", admin, requireUser(), requireWebhookOwner(), deleteWebhook)
+		v1.GET("/webhooks/:webhook_id/deliveries", admin, requireUser(), requireWebhookOwner(), readAllDeliveriesOfWebhook)
+		v1.POST("/webhooks/:webhook_id/deliveries/:delivery_id/replay", admin, requireUser(), requireWebhookOwner(), replayDelivery)
+
+		v1.POST("/picnics/", admin, requireUser(), addPicnic)
+		v1.GET("/picnics/:picnic_id", read, readPicnic)
+		v1.GET("/picnics/", read, readAllPicnics)
+		v1.PUT("/picnics/:picnic_id", admin, requirePicnicRole(models.RoleOwner, models.RoleCoHost), updatePicnic)
+		v1.DELETE("/picnics/:picnic_id", admin, requirePicnicRole(models.RoleOwner), deletePicnic)
+		v1.POST("/picnics/:picnic_id/owner", admin, requirePicnicRole(models.RoleOwner), transferOwnership)
+
+		v1.POST("/users/", admin, requireUser(), addUser)
+		v1.GET("/users/:user_id", read, readUser)
+		v1.GET("/users/", read, readAllUsers)
+		v1.PUT("users/:user_id", admin, requireSelf(), updateUser)
+
+		v1.POST("/picnics/:picnic_id/users/:user_id", admin, requirePicnicRole(models.RoleOwner, models.RoleCoHost), addUserToPicnic)
+		v1.GET("/picnics/:picnic_id/users", read, readAllUsersOfPicnic)
+		v1.GET("/users/:user_id/picnics", read, readAllPicnicsOfUser)
+		v1.GET("/users/:user_id/settlements", read, requireSelf(), readAllSettlementsOfUser)
+		v1.DELETE("/picnics/:picnic_id/users/:user_id", admin, requireSelfOrPicnicRole(models.RoleOwner, models.RoleCoHost), deleteUserFromPicnic)
+		v1.POST("/picnics/:picnic_id/users/:user_id/decline", admin, requireSelfOrPicnicRole(), declinePicnic)
+		v1.PUT("/picnics/:picnic_id/users/:user_id/role", admin, requirePicnicRole(models.RoleOwner), updateRole)
+		v1.GET("/picnics/:picnic_id/session", writeContributions, member, collabSession)
+		v1.GET("/picnics/:picnic_id/events", read, member, streamPicnicEvents)
+		v1.GET("/picnics/:picnic_id/comments", read, member, readAllCommentsOfPicnic)
+		v1.POST("/picnics/:picnic_id/comments", admin, member, addComment)
+		v1.PUT("/picnics/:picnic_id/comments/:comment_id", admin, member, requireComment(true), updateComment)
+		v1.DELETE("/picnics/:picnic_id/comments/:comment_id", admin, member, requireComment(false), deleteComment)
+
+		v1.POST("/picnics/:picnic_id/polls", admin, requirePicnicRole(models.RoleOwner, models.RoleCoHost), addPoll)
+		v1.GET("/picnics/:picnic_id/polls", read, member, readAllPollsOfPicnic)
+		v1.GET("/picnics/:picnic_id/polls/:poll_id", read, member, requirePoll(), readPoll)
+		v1.PUT("/picnics/:picnic_id/polls/:poll_id/votes", admin, member, requirePoll(), votePoll)
+		v1.POST("/picnics/:picnic_id/polls/:poll_id/close", admin, requirePicnicRole(models.RoleOwner, models.RoleCoHost), requirePoll(), closePoll)
+
+		v1.POST("/picnics/:picnic_id/images", admin, member, addPicnicImage)
+		v1.GET("/picnics/:picnic_id/images", read, member, readAllImagesOfPicnic)
+		v1.DELETE("/picnics/:picnic_id/images/:image_id", admin, member, deletePicnicImage)
+
+		v1.GET("/picnics/:picnic_id/waitlist", read, member, readWaitlistOfPicnic)
+		v1.GET("/users/:user_id/picnics/:picnic_id", read, requireSelfOrPicnicRole(models.RoleOwner, models.RoleCoHost), readMembership)
+
+		v1.POST("/picnics/:picnic_id/invitations", admin, requirePicnicRole(models.RoleOwner, models.RoleCoHost), addInvitation)
+		v1.GET("/picnics/:picnic_id/invitations", read, requirePicnicRole(models.RoleOwner, models.RoleCoHost), readAllInvitationsOfPicnic)
+		v1.DELETE("/picnics/:picnic_id/invitations/:invitation_id", admin, requirePicnicRole(models.RoleOwner, models.RoleCoHost), deleteInvitation)
+		v1.GET("/invitations/:token", read, readInvitation)
+		v1.POST("/invitations/:token/accept", admin, requireUser(), acceptInvitation)
+		v1.POST("/invitations/:token/decline", admin, requireUser(), declineInvitation)
+
+		v1.POST("/food-items/", admin, addFoodItem)
+		v1.GET("/food-items/:item_id", read, readFoodItem)
+		v1.GET("/food-items/", read, readAllFoodItems)
+		v1.PUT("/food-items/:item_id", admin, updateFoodItem)
+		v1.POST("/food-items/:item_id/image", admin, requireUser(), uploadFoodItemImage)
+		//v1.DELETE("/food-items/:item_id", deleteFoodItem)
+
+		v1.POST("/gear/", admin, addGearItem)
+		v1.GET("/gear/:gear_id", read, readGearItem)
+		v1.GET("/gear/", read, readAllGearItems)
+		v1.PUT("/gear/:gear_id", admin, updateGearItem)
+
+		v1.GET("/picnics/:picnic_id/gear", read, member, readAllGearOfPicnic)
+		v1.POST("/picnics/:picnic_id/gear", writeContributions, member, addGearToPicnic)
+		v1.PUT("/picnics/:picnic_id/gear/:assignment_id", writeContributions, member, requireGearAssignment(), updateGearOfPicnic)
+		v1.DELETE("/picnics/:picnic_id/gear/:assignment_id", writeContributions, member, requireGearAssignment(), deleteGearOfPicnic)
+		v1.GET("/picnics/:picnic_id/list", read, member, readPicnicList)
+
+		v1.POST("/inventory/", admin, requireUser(), addInventoryItem)
+		v1.GET("/inventory/", read, readAllInventoryItems)
+		v1.GET("/inventory/conflicts", read, requireUser(), readInventoryConflicts)
+		v1.GET("/inventory/:item_id", read, readInventoryItem)
+		v1.PUT("/inventory/:item_id", admin, requireUser(), updateInventoryItem)
+		v1.GET("/inventory/:item_id/history", read, requireUser(), readInventoryItemHistory)
+
+		v1.GET("/picnics/:picnic_id/inventory", read, member, readAllReservationsOfPicnic)
+		v1.POST("/picnics/:picnic_id/inventory", writeContributions, member, reserveInventoryItem)
+		v1.POST("/picnics/:picnic_id/inventory/:reservation_id/checkout", writeContributions, member, requireReservation(), checkoutInventoryItem)
+		v1.POST("/picnics/:picnic_id/inventory/:reservation_id/return", writeContributions, member, requireReservation(), returnInventoryItem)
+		v1.DELETE("/picnics/:picnic_id/inventory/:reservation_id", writeContributions, member, requireReservation(), cancelReservation)
+
+		v1.POST("/contributions/", writeContributions, requireUser(), addContribution)
+		v1.GET("/contributions/:contribution_id", read, readContribution)
+		v1.GET("/contributions/", read, readAllContributions)
+		v1.PUT("/contributions/:contribution_id", writeContributions, requireContributionAccess(), updateContribution)
+		v1.DELETE("/contributions/:contribution_id", writeContributions, requireContributionAccess(), deleteContribution)
+		// TODO: Crear pruebas en postman, implementar delete, read all contibutions
+
+		// GraphQL trae un picnic con sus asistentes y lo que llevan en un solo
+		// request, las mutations revisan los scopes de escritura ellas mismas
+		v1.POST("/graphql", read, graphqlHandler)
+
+		v1.POST("/settlements/", admin, requireUser(), addSettlement)
+		v1.GET("/picnics/:picnic_id/balances", read, member, readPicnicBalances)
+		v1.GET("/balances", read, requireUser(), readSharedBalances)
+
+		v1.POST("/users/:user_id/availability", admin, requireSelf(), addAvailability)
+		v1.GET("/users/:user_id/availability", read, requireSelf(), readAllAvailabilityOfUser)
+		v1.DELETE("/users/:user_id/availability/:availability_id", admin, requireSelf(), deleteAvailability)
+		v1.GET("/schedule/suggestions", read, requireUser(), readScheduleSuggestions)
+
+	}
+	return r
+}
+
 func addPicnic(c *gin.Context) {
 
 	var json models.Picnic
 
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 		return
 	}
 	fmt.Println("json: ", json)
 
-	success, err := models.CreatePicnic(json)
+	// quien lo crea queda como owner
+	json.CreatedBy, _ = currentUserID(c)
 
-	if success {
-		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	id, err := models.CreatePicnic(json)
+
+	if err == nil {
+		json.ID = id
+		picnicCreated(json)
+		c.JSON(http.StatusOK, gin.H{"message": "Success", "data": json})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 }
 
@@ -121,7 +341,7 @@ func readPicnic(c *gin.Context) {
 	checkErr(err)
 	// if the name is blank we can assume nothing is found
 	if picnic.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Picnic of that id not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Picnic of that id not found")})
 		return
 	} else {
 		c.JSON(http.StatusOK, gin.H{"data": picnic})
@@ -143,7 +363,7 @@ func updatePicnic(c *gin.Context) {
 	// grab the Id of the record we want to retrieve
 
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 		return
 	}
 
@@ -152,15 +372,20 @@ func updatePicnic(c *gin.Context) {
 	fmt.Println("json: ", json)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid ID")})
 	}
 
+	before, err := models.GetPicnicById(id)
+	checkErr(err)
+
 	success, err := models.UpdatePicnic(json, id)
 
 	if success {
+		editorID, _ := currentUserID(c)
+		picnicUpdated(before, json, editorID)
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 }
 
@@ -169,34 +394,34 @@ func deletePicnic(c *gin.Context) {
 	picnicId, err := strconv.Atoi(c.Param("picnic_id"))
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid ID")})
+		return
 	}
 
-	success, err := models.DeletePicnic(picnicId)
+	success, err := removePicnic(picnicId)
 
 	if success {
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 }
 
 func addUser(c *gin.Context) {
 
-	var json models.User
+	var json models.Account
 
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 		return
 	}
-	fmt.Println("json: ", json)
 
-	success, err := models.CreateUser(json)
+	success, err := models.CreateUser(json.ToUser())
 
 	if success {
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 
 }
@@ -212,7 +437,7 @@ func readUser(c *gin.Context) {
 	checkErr(err)
 	// if the name is blank we can assume nothing is found
 	if user.Name == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User of that id not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User of that id not found")})
 		return
 	} else {
 		c.JSON(http.StatusOK, gin.H{"data": user})
@@ -229,29 +454,28 @@ func readAllUsers(c *gin.Context) {
 
 func updateUser(c *gin.Context) {
 
-	var json models.User
+	var json models.Account
 
 	// grab the Id of the record we want to retrieve
 
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error_1": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error_1": trErr(c, err)})
 		return
 	}
 
 	id, err := strconv.Atoi(c.Param("user_id"))
 	json.ID = id
-	fmt.Println("json: ", json)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error_2": "Invalid ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error_2": tr(c, "Invalid ID")})
 	}
 
-	success, err := models.UpdateUser(json, id)
+	success, err := models.UpdateUser(json.ToUser(), id)
 
 	if success {
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error_3": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error_3": trErr(c, err)})
 	}
 }
 
@@ -261,30 +485,48 @@ func checkErr(err error) {
 	}
 }
 
+// serverError answers 500 when a lookup fails in the middle of a request,
+// JSON under /api/ and the error page elsewhere. checkErr is only for
+// startup, a busy database must fail the request and not the server.
+func serverError(c *gin.Context, err error) {
+	log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Something went wrong")})
+		return
+	}
+	renderErrorPage(c, http.StatusInternalServerError, "Something went wrong")
+}
+
 func addUserToPicnic(c *gin.Context) {
 
 	// Get the picnic ID from the request URL parameter
 	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid picnic ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
 		return
 	}
 
 	// Get the user ID from the request URL parameter
 	userID, err := strconv.Atoi(c.Param("user_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid user ID")})
 		return
 	}
 
 	// Call AddUserToPicnic
 	success, err := models.AddUserToPicnic(userID, picnicID)
-	if success {
-		c.JSON(http.StatusOK, gin.H{"message": "Success"})
-	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+	if !success {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
 	}
 
+	// si el picnic esta lleno el usuario queda en lista de espera
+	membership, err := models.GetMembership(userID, picnicID)
+	checkErr(err)
+
+	membershipAdded(membership)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": membership})
+
 }
 
 func readAllUsersOfPicnic(c *gin.Context) {
@@ -292,7 +534,7 @@ func readAllUsersOfPicnic(c *gin.Context) {
 	// Get the picnic ID from the request URL parameter
 	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid picnic ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
 		return
 	}
 
@@ -300,17 +542,14 @@ func readAllUsersOfPicnic(c *gin.Context) {
 	checkErr(err)
 
 	if picnic.Name == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Picnic of that id not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Picnic of that id not found")})
 		return
-	} else {
-		c.JSON(http.StatusOK, gin.H{"data": picnic})
-
 	}
 
 	// Call a function to retrieve the users by picnic ID from the database
 	users, err := models.GetUsersByPicnic(picnic.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve users")})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": users})
@@ -322,7 +561,7 @@ func readAllPicnicsOfUser(c *gin.Context) {
 
 	userID, err := strconv.Atoi(c.Param("user_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid user ID")})
 		return
 	}
 
@@ -330,12 +569,12 @@ func readAllPicnicsOfUser(c *gin.Context) {
 	checkErr(err)
 
 	if user.Name == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User of that id not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User of that id not found")})
 	}
 
 	picnics, err := models.GetPicnicsByUser(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve users")})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": picnics})
@@ -346,7 +585,7 @@ func addFoodItem(c *gin.Context) {
 	var json models.FoodItem
 
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 		return
 	}
 	fmt.Println("json: ", json)
@@ -356,7 +595,7 @@ func addFoodItem(c *gin.Context) {
 	if success {
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 }
 
@@ -371,7 +610,7 @@ func readFoodItem(c *gin.Context) {
 	checkErr(err)
 
 	if foodItem.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Food item of that id not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Food item of that id not found")})
 		return
 	} else {
 		c.JSON(http.StatusOK, gin.H{"data": foodItem})
@@ -383,12 +622,12 @@ func readAllFoodItems(c *gin.Context) {
 	foodItems, err := models.GetFoodItems()
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve food items"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve food items")})
 		return
 	}
 
 	if len(foodItems) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No food items found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "No food items found")})
 		return
 	}
 
@@ -402,7 +641,7 @@ func updateFoodItem(c *gin.Context) {
 	// grab the Id of the record we want to retrieve
 
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 		return
 	}
 
@@ -411,7 +650,7 @@ func updateFoodItem(c *gin.Context) {
 	fmt.Println("json: ", json)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid ID")})
 	}
 
 	success, err := models.UpdateFoodItem(json, id)
@@ -419,7 +658,7 @@ func updateFoodItem(c *gin.Context) {
 	if success {
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 }
 
@@ -428,17 +667,24 @@ func addContribution(c *gin.Context) {
 	var json models.Contribution
 
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 		return
 	}
 	fmt.Println("json: ", json)
 
-	success, err := models.CreateContribution(json)
+	if !canManageContribution(c, json) {
+		return
+	}
 
-	if success {
-		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	id, err := models.CreateContribution(json)
+
+	if err == nil {
+		json.ID = id
+		json.Version = 1
+		contributionCreated(json)
+		c.JSON(http.StatusOK, gin.H{"message": "Success", "data": json})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 
 }
@@ -454,7 +700,7 @@ func readContribution(c *gin.Context) {
 	checkErr(err)
 	// if the name is blank we can assume nothing is found
 	if contribution.Quantity == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Contribution of that id not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Contribution of that id not found")})
 		return
 	} else {
 		c.JSON(http.StatusOK, gin.H{"data": contribution})
@@ -476,7 +722,7 @@ func updateContribution(c *gin.Context) {
 	// grab the Id of the record we want to retrieve & update
 
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 		return
 	}
 
@@ -485,15 +731,41 @@ func updateContribution(c *gin.Context) {
 	fmt.Println("json: ", json)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid ID")})
 	}
 
-	success, err := models.UpdateContribution(json, id)
+	// tambien se revisa a donde se mueve la contribucion
+	if !canManageContribution(c, json) {
+		return
+	}
+
+	before, err := models.GetContributionById(id)
+	checkErr(err)
+
+	var success bool
+
+	if json.Version > 0 {
+		// con version se rechaza el update si alguien guardo antes
+		saved, err := models.UpdateContributionAtVersion(json, id, json.Version)
+		if err == models.ErrVersionConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": trErr(c, err), "data": saved})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+			return
+		}
+		success, json = true, saved
+	} else {
+		success, err = models.UpdateContribution(json, id)
+	}
 
 	if success {
+		editorID, _ := currentUserID(c)
+		contributionUpdated(before, json, editorID)
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 }
 
@@ -502,15 +774,19 @@ func deleteContribution(c *gin.Context) {
 	contributionId, err := strconv.Atoi(c.Param("contribution_id"))
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid ID")})
 	}
 
+	contribution, err := models.GetContributionById(contributionId)
+	checkErr(err)
+
 	success, err := models.DeleteContribution(contributionId)
 
 	if success {
+		contributionDeleted(contribution)
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
 	}
 
 }
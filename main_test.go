@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"server/models"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Los tests usan el router de verdad con una base temporal, compartida por
+// todo el paquete: cada test crea sus propios usuarios y picnics.
+var (
+	testRouter http.Handler
+	testDB     string
+)
+
+func TestMain(m *testing.M) {
+
+	gin.SetMode(gin.TestMode)
+	models.PasswordCost = bcrypt.MinCost
+
+	dir, err := os.MkdirTemp("", "picnic-test")
+	checkErr(err)
+	testDB = filepath.Join(dir, "test.db")
+	checkErr(models.OpenDatabase(testDB))
+
+	testRouter = newHandler()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+var testUsers int64
+
+// testUser is a registered user with an open session, the zero value is
+// an anonymous caller
+type testUser struct {
+	models.User
+	session string
+}
+
+func newTestUser(t *testing.T, name string) testUser {
+	t.Helper()
+
+	name = fmt.Sprintf("%s-%d", name, atomic.AddInt64(&testUsers, 1))
+	user, err := models.RegisterUser(models.User{Name: name, Email: name + "@example.com", Locale: "en"}, "secretpw1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := models.CreateSession(user.ID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testUser{user, session}
+}
+
+// do sends body as JSON through the router
+func (u testUser) do(t *testing.T, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if u.session != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: u.session})
+	}
+
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+// mustOK returns a check for the (bool, error) of the models functions,
+// must(models.AddUserToPicnic(userID, picnicID))
+func mustOK(t *testing.T) func(ok bool, err error) {
+	return func(ok bool, err error) {
+		t.Helper()
+		if err != nil || !ok {
+			t.Fatalf("ok=%v err=%v", ok, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"server/i18n"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const CREATE_SESSIONS_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS sessions (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  user_id     INTEGER NOT NULL,
+  token_hash  VARCHAR UNIQUE NOT NULL,
+  user_agent  VARCHAR NOT NULL DEFAULT '',
+  created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at  DATETIME NOT NULL,
+  revoked_at  DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_sessions_on_user_id ON sessions (user_id);
+
+`
+
+const SessionDuration = 30 * 24 * time.Hour
+
+// mismo formato que CURRENT_TIMESTAMP, asi se puede comparar con datetime('now')
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+var ErrInvalidCredentials = i18n.Errorf("invalid name or password")
+
+// PasswordCost is the bcrypt cost of new passwords, tests lower it
+var PasswordCost = bcrypt.DefaultCost
+
+// se compara contra este hash cuando el usuario no existe, para no revelar por
+// tiempo de respuesta que nombres estan registrados
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("picnic"), bcrypt.DefaultCost)
+
+type Session struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	Current   bool   `json:"current"`
+}
+
+// RegisterUser creates a user that can log in. Users created through
+// CreateUser have no password and can only be referenced by others.
+func RegisterUser(newUser User, password string) (User, error) {
+
+	if len(password) < 8 {
+		return User{}, i18n.Errorf("password must be at least 8 characters")
+	}
+	if newUser.Locale != "" && !i18n.IsSupported(newUser.Locale) {
+		return User{}, i18n.Errorf("locale must be one of %s", strings.Join(i18n.Supported, ", "))
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+	if err != nil {
+		return User{}, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO users (name, email, locale, password_hash) VALUES (?, ?, ?, ?)", newUser.Name, newUser.Email, newUser.Locale, string(hash))
+	if err != nil {
+		return User{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return User{}, err
+	}
+
+	tx.Commit()
+
+	newUser.ID = int(id)
+	return newUser, nil
+}
+
+func CheckPassword(name string, password string) (User, error) {
+
+	user := User{}
+	var hash sql.NullString
+
+	err := DB.QueryRow("SELECT id, name, email, locale, password_hash FROM users WHERE name = ?", name).Scan(&user.ID, &user.Name, &user.Email, &user.Locale, &hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return User{}, ErrInvalidCredentials
+		}
+		return User{}, err
+	}
+
+	if !hash.Valid {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return User{}, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(password)) != nil {
+		return User{}, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateSession returns the raw token for the cookie, only its hash is stored.
+func CreateSession(userID int, userAgent string) (string, error) {
+
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO sessions (user_id, token_hash, user_agent, expires_at) VALUES (?, ?, ?, ?)")
+
+	if err != nil {
+		return "", err
+	}
+
+	defer stmt.Close()
+
+	expiresAt := time.Now().UTC().Add(SessionDuration).Format(sqliteTimeFormat)
+
+	_, err = stmt.Exec(userID, hashToken(token), userAgent, expiresAt)
+
+	if err != nil {
+		return "", err
+	}
+
+	tx.Commit()
+
+	return token, nil
+}
+
+// GetSessionByToken returns an empty Session when the token is unknown,
+// expired or revoked.
+func GetSessionByToken(token string) (Session, error) {
+
+	session := Session{}
+
+	err := DB.QueryRow("SELECT id, user_id, user_agent, created_at, expires_at FROM sessions WHERE token_hash = ? AND revoked_at IS NULL AND expires_at > datetime('now')", hashToken(token)).Scan(&session.ID, &session.UserID, &session.UserAgent, &session.CreatedAt, &session.ExpiresAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Session{}, nil
+		}
+		return Session{}, err
+	}
+	return session, nil
+}
+
+func GetSessionsByUser(userID int) ([]Session, error) {
+
+	rows, err := DB.Query("SELECT id, user_id, user_agent, created_at, expires_at FROM sessions WHERE user_id = ? AND revoked_at IS NULL AND expires_at > datetime('now') ORDER BY id DESC", userID)
+	sessions := make([]Session, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		session := Session{}
+		err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.CreatedAt, &session.ExpiresAt)
+		if err != nil {
+			return make([]Session, 0), err
+		}
+		sessions = append(sessions, session)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Session, 0), err
+	}
+
+	return sessions, err
+}
+
+// RevokeSession only touches sessions of userID so nobody can log others out.
+func RevokeSession(sessionID int, userID int) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("session %d not found", sessionID)
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
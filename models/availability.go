@@ -0,0 +1,420 @@
+package models
+
+import (
+	"database/sql"
+	"server/i18n"
+	"sort"
+	"time"
+)
+
+const CREATE_AVAILABILITY_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS availability (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  user_id     INTEGER NOT NULL,
+  kind        VARCHAR NOT NULL,
+  weekday     INTEGER NOT NULL DEFAULT 0,
+  start_time  VARCHAR NOT NULL DEFAULT '',
+  end_time    VARCHAR NOT NULL DEFAULT '',
+  starts_at   DATETIME,
+  ends_at     DATETIME,
+  created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_availability_on_user_id ON availability (user_id);
+
+`
+
+const (
+	AvailabilityRecurring = "recurring"
+	AvailabilityOnce      = "once"
+)
+
+// Los picnics no tienen hora de fin, se asume que ocupan esto
+const PicnicDuration = 4 * time.Hour
+
+// SlotStep is how far apart the candidate start times are
+const SlotStep = 30 * time.Minute
+
+// MaxScheduleRange keeps the suggestion search bounded
+const MaxScheduleRange = 62 * 24 * time.Hour
+
+// Availability is a window when the user is free, in UTC like every other
+// time in the API. Recurring windows repeat every Weekday (0 is Sunday)
+// from StartTime to EndTime ("15:04"), one-off windows go from StartsAt to
+// EndsAt.
+type Availability struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	Kind      string `json:"kind" binding:"required"`
+	Weekday   int    `json:"weekday"`
+	StartTime string `json:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty"`
+	StartsAt  string `json:"starts_at,omitempty"`
+	EndsAt    string `json:"ends_at,omitempty"`
+}
+
+// Slot is a suggested time, Available lists who can make it
+type Slot struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Count     int    `json:"count"`
+	Available []int  `json:"available"`
+}
+
+// los picnics se guardan como los manda el cliente, se aceptan los formatos
+// que SQLite entiende en datetime()
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func ParseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, i18n.Errorf("can't parse date %q", value)
+}
+
+func validateAvailability(availability Availability) error {
+
+	switch availability.Kind {
+	case AvailabilityRecurring:
+		if availability.Weekday < 0 || availability.Weekday > 6 {
+			return i18n.Errorf("weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, err := time.Parse("15:04", availability.StartTime)
+		if err != nil {
+			return i18n.Errorf("start_time must look like 15:04")
+		}
+		end, err := time.Parse("15:04", availability.EndTime)
+		if err != nil {
+			return i18n.Errorf("end_time must look like 15:04")
+		}
+		if !end.After(start) {
+			return i18n.Errorf("end_time must be after start_time")
+		}
+	case AvailabilityOnce:
+		start, err := ParseDate(availability.StartsAt)
+		if err != nil {
+			return err
+		}
+		end, err := ParseDate(availability.EndsAt)
+		if err != nil {
+			return err
+		}
+		if !end.After(start) {
+			return i18n.Errorf("ends_at must be after starts_at")
+		}
+	default:
+		return i18n.Errorf("kind must be %s or %s", AvailabilityRecurring, AvailabilityOnce)
+	}
+	return nil
+}
+
+func CreateAvailability(newAvailability Availability) (Availability, error) {
+
+	if err := validateAvailability(newAvailability); err != nil {
+		return Availability{}, err
+	}
+
+	var startsAt, endsAt interface{}
+	if newAvailability.Kind == AvailabilityOnce {
+		start, _ := ParseDate(newAvailability.StartsAt)
+		end, _ := ParseDate(newAvailability.EndsAt)
+		startsAt = start.UTC().Format(sqliteTimeFormat)
+		endsAt = end.UTC().Format(sqliteTimeFormat)
+	} else {
+		newAvailability.StartsAt, newAvailability.EndsAt = "", ""
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Availability{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO availability (user_id, kind, weekday, start_time, end_time, starts_at, ends_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		newAvailability.UserID, newAvailability.Kind, newAvailability.Weekday, newAvailability.StartTime, newAvailability.EndTime, startsAt, endsAt)
+	if err != nil {
+		return Availability{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Availability{}, err
+	}
+
+	tx.Commit()
+
+	return GetAvailabilityById(int(id))
+}
+
+const availabilityColumns = "id, user_id, kind, weekday, start_time, end_time, starts_at, ends_at"
+
+func scanAvailability(row scanner) (Availability, error) {
+	availability := Availability{}
+	var startsAt, endsAt sql.NullString
+	err := row.Scan(&availability.ID, &availability.UserID, &availability.Kind, &availability.Weekday, &availability.StartTime, &availability.EndTime, &startsAt, &endsAt)
+	availability.StartsAt = startsAt.String
+	availability.EndsAt = endsAt.String
+	return availability, err
+}
+
+func GetAvailabilityById(id int) (Availability, error) {
+
+	availability, err := scanAvailability(DB.QueryRow("SELECT "+availabilityColumns+" FROM availability WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Availability{}, nil
+		}
+		return Availability{}, err
+	}
+	return availability, nil
+}
+
+func GetAvailabilityByUser(userID int) ([]Availability, error) {
+
+	rows, err := DB.Query("SELECT "+availabilityColumns+" FROM availability WHERE user_id = ? ORDER BY kind, weekday, start_time, starts_at", userID)
+	windows := make([]Availability, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		availability, err := scanAvailability(rows)
+		if err != nil {
+			rows.Close()
+			return make([]Availability, 0), err
+		}
+		windows = append(windows, availability)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Availability, 0), err
+	}
+
+	return windows, err
+}
+
+func DeleteAvailability(userID int, availabilityID int) (bool, error) {
+
+	result, err := DB.Exec("DELETE FROM availability WHERE id = ? AND user_id = ?", availabilityID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("availability %d not found", availabilityID)
+	}
+	return true, nil
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+// freeIntervals expands the user's windows inside [from, to) and takes out
+// the time already taken by their picnics.
+func freeIntervals(userID int, from time.Time, to time.Time) ([]interval, error) {
+
+	windows, err := GetAvailabilityByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	free := make([]interval, 0)
+	for _, window := range windows {
+		switch window.Kind {
+		case AvailabilityOnce:
+			start, err := ParseDate(window.StartsAt)
+			if err != nil {
+				return nil, err
+			}
+			end, err := ParseDate(window.EndsAt)
+			if err != nil {
+				return nil, err
+			}
+			free = append(free, interval{start, end})
+		case AvailabilityRecurring:
+			startClock, _ := time.Parse("15:04", window.StartTime)
+			endClock, _ := time.Parse("15:04", window.EndTime)
+			day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+			for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+				if int(day.Weekday()) != window.Weekday {
+					continue
+				}
+				start := day.Add(time.Duration(startClock.Hour())*time.Hour + time.Duration(startClock.Minute())*time.Minute)
+				end := day.Add(time.Duration(endClock.Hour())*time.Hour + time.Duration(endClock.Minute())*time.Minute)
+				free = append(free, interval{start, end})
+			}
+		}
+	}
+
+	free = mergeIntervals(free)
+
+	picnics, err := GetPicnicsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, picnic := range picnics {
+		busy, err := picnicInterval(picnic)
+		if err != nil {
+			continue
+		}
+		free = subtractInterval(free, busy)
+	}
+	return free, nil
+}
+
+// picnicInterval is the time a picnic takes up
+func picnicInterval(picnic Picnic) (interval, error) {
+
+	date, err := ParseDate(picnic.Date)
+	if err != nil {
+		return interval{}, err
+	}
+
+	busy := interval{date, date.Add(PicnicDuration)}
+	// un picnic sin hora (medianoche) ocupa todo el dia
+	if date.Hour() == 0 && date.Minute() == 0 {
+		busy.end = date.AddDate(0, 0, 1)
+	}
+	return busy, nil
+}
+
+// mergeIntervals joins windows that touch or overlap, so a slot can span a
+// recurring window and a one-off one next to it.
+func mergeIntervals(intervals []interval) []interval {
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	merged := make([]interval, 0, len(intervals))
+	for _, window := range intervals {
+		last := len(merged) - 1
+		if last >= 0 && !window.start.After(merged[last].end) {
+			if window.end.After(merged[last].end) {
+				merged[last].end = window.end
+			}
+			continue
+		}
+		merged = append(merged, window)
+	}
+	return merged
+}
+
+func subtractInterval(free []interval, busy interval) []interval {
+
+	result := make([]interval, 0, len(free))
+	for _, window := range free {
+		if !busy.start.Before(window.end) || !busy.end.After(window.start) {
+			result = append(result, window)
+			continue
+		}
+		if window.start.Before(busy.start) {
+			result = append(result, interval{window.start, busy.start})
+		}
+		if busy.end.Before(window.end) {
+			result = append(result, interval{busy.end, window.end})
+		}
+	}
+	return result
+}
+
+func covers(free []interval, start time.Time, end time.Time) bool {
+	for _, window := range free {
+		if !window.start.After(start) && !window.end.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// SuggestSlots tries every start time in [from, to) SlotStep apart and
+// returns the limit best non-overlapping slots of the given duration, ranked
+// by how many of userIDs are free for all of it. Earlier slots win ties.
+func SuggestSlots(userIDs []int, from time.Time, to time.Time, duration time.Duration, limit int) ([]Slot, error) {
+
+	if !to.After(from) {
+		return nil, i18n.Errorf("to must be after from")
+	}
+	if to.Sub(from) > MaxScheduleRange {
+		return nil, i18n.Errorf("the range can't be longer than %d days", int(MaxScheduleRange.Hours()/24))
+	}
+	if duration <= 0 {
+		return nil, i18n.Errorf("duration must be positive")
+	}
+
+	free := make(map[int][]interval)
+	for _, userID := range userIDs {
+		intervals, err := freeIntervals(userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		free[userID] = intervals
+	}
+
+	type candidate struct {
+		start     time.Time
+		available []int
+	}
+	candidates := make([]candidate, 0)
+	for start := from; !start.Add(duration).After(to); start = start.Add(SlotStep) {
+		available := make([]int, 0)
+		for _, userID := range userIDs {
+			if covers(free[userID], start, start.Add(duration)) {
+				available = append(available, userID)
+			}
+		}
+		if len(available) > 0 {
+			candidates = append(candidates, candidate{start, available})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].available) > len(candidates[j].available)
+	})
+
+	slots := make([]Slot, 0)
+	taken := make([]interval, 0)
+	for _, c := range candidates {
+		if len(slots) == limit {
+			break
+		}
+		end := c.start.Add(duration)
+		if overlaps(taken, c.start, end) {
+			continue
+		}
+		taken = append(taken, interval{c.start, end})
+		slots = append(slots, Slot{
+			Start:     c.start.Format(time.RFC3339),
+			End:       end.Format(time.RFC3339),
+			Count:     len(c.available),
+			Available: c.available,
+		})
+	}
+	return slots, nil
+}
+
+func overlaps(intervals []interval, start time.Time, end time.Time) bool {
+	for _, i := range intervals {
+		if start.Before(i.end) && end.After(i.start) {
+			return true
+		}
+	}
+	return false
+}
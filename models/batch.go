@@ -0,0 +1,214 @@
+package models
+
+import "strings"
+
+// Las funciones de este archivo traen de una vez las filas de varios ids,
+// las usa GraphQL para no hacer una query por cada picnic o usuario.
+
+// inList returns "?,?,?" and the args for an IN clause
+func inList(ids []int) (string, []interface{}) {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
+
+func GetUsersByIds(ids []int) (map[int]User, error) {
+
+	users := make(map[int]User)
+	if len(ids) == 0 {
+		return users, nil
+	}
+	placeholders, args := inList(ids)
+
+	rows, err := DB.Query("SELECT id, name, email, locale FROM users WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := User{}
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Locale); err != nil {
+			return nil, err
+		}
+		users[user.ID] = user
+	}
+	return users, rows.Err()
+}
+
+func GetPicnicsByIds(ids []int) (map[int]Picnic, error) {
+
+	picnics := make(map[int]Picnic)
+	if len(ids) == 0 {
+		return picnics, nil
+	}
+	placeholders, args := inList(ids)
+
+	rows, err := DB.Query("SELECT id, name, location, date, capacity, COALESCE(created_by, 0) FROM picnics WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		picnic := Picnic{}
+		if err := rows.Scan(&picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date, &picnic.Capacity, &picnic.CreatedBy); err != nil {
+			return nil, err
+		}
+		picnics[picnic.ID] = picnic
+	}
+	return picnics, rows.Err()
+}
+
+func GetFoodItemsByIds(ids []int) (map[int]FoodItem, error) {
+
+	items := make(map[int]FoodItem)
+	if len(ids) == 0 {
+		return items, nil
+	}
+	placeholders, args := inList(ids)
+
+	rows, err := DB.Query("SELECT id, name, url, measure FROM food_items WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := FoodItem{}
+		if err := rows.Scan(&item.ID, &item.Name, &item.Url, &item.Measure); err != nil {
+			return nil, err
+		}
+		items[item.ID] = item
+	}
+	return items, rows.Err()
+}
+
+// GetUsersByPicnics is GetUsersByPicnic for several picnics, only the users
+// attending
+func GetUsersByPicnics(picnicIds []int) (map[int][]User, error) {
+
+	users := make(map[int][]User)
+	if len(picnicIds) == 0 {
+		return users, nil
+	}
+	placeholders, args := inList(picnicIds)
+
+	rows, err := DB.Query("SELECT users_picnics.picnic_id, users.id, users.name, users.email, users.locale FROM users INNER JOIN users_picnics ON users.id = users_picnics.user_id WHERE users_picnics.status = ? AND users_picnics.picnic_id IN ("+placeholders+") ORDER BY users_picnics.id", append([]interface{}{MembershipAttending}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var picnicID int
+		user := User{}
+		if err := rows.Scan(&picnicID, &user.ID, &user.Name, &user.Email, &user.Locale); err != nil {
+			return nil, err
+		}
+		users[picnicID] = append(users[picnicID], user)
+	}
+	return users, rows.Err()
+}
+
+// GetPicnicsByUsers is GetPicnicsByUser for several users, declined
+// picnics are left out
+func GetPicnicsByUsers(userIds []int) (map[int][]Picnic, error) {
+
+	picnics := make(map[int][]Picnic)
+	if len(userIds) == 0 {
+		return picnics, nil
+	}
+	placeholders, args := inList(userIds)
+
+	rows, err := DB.Query("SELECT users_picnics.user_id, picnics.id, picnics.name, picnics.location, picnics.date, picnics.capacity, COALESCE(picnics.created_by, 0) FROM picnics INNER JOIN users_picnics ON picnics.id = users_picnics.picnic_id WHERE users_picnics.status != ? AND users_picnics.user_id IN ("+placeholders+") ORDER BY picnics.id", append([]interface{}{MembershipDeclined}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int
+		picnic := Picnic{}
+		if err := rows.Scan(&userID, &picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date, &picnic.Capacity, &picnic.CreatedBy); err != nil {
+			return nil, err
+		}
+		picnics[userID] = append(picnics[userID], picnic)
+	}
+	return picnics, rows.Err()
+}
+
+// GetMembershipsByPicnics returns every membership, declined ones too, in
+// the order people joined. WaitlistPosition is filled like GetMembership.
+func GetMembershipsByPicnics(picnicIds []int) (map[int][]UserPicnic, error) {
+
+	memberships := make(map[int][]UserPicnic)
+	if len(picnicIds) == 0 {
+		return memberships, nil
+	}
+	placeholders, args := inList(picnicIds)
+
+	rows, err := DB.Query("SELECT id, user_id, picnic_id, status, role FROM users_picnics WHERE picnic_id IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	waiting := make(map[int]int)
+	for rows.Next() {
+		membership := UserPicnic{}
+		if err := rows.Scan(&membership.ID, &membership.UserID, &membership.PicnicID, &membership.Status, &membership.Role); err != nil {
+			return nil, err
+		}
+		if membership.Status == MembershipWaitlisted {
+			waiting[membership.PicnicID]++
+			membership.WaitlistPosition = waiting[membership.PicnicID]
+		}
+		memberships[membership.PicnicID] = append(memberships[membership.PicnicID], membership)
+	}
+	return memberships, rows.Err()
+}
+
+const contributionColumns = "id, user_id, picnic_id, food_item_id, quantity, COALESCE(paid_by, 0), amount_paid, version"
+
+// GetContributionsByPicnics groups the contributions of several picnics by
+// picnic id
+func GetContributionsByPicnics(picnicIds []int) (map[int][]Contribution, error) {
+	return contributionsGroupedBy("picnic_id", picnicIds, func(contribution Contribution) int { return contribution.PicnicID })
+}
+
+func GetContributionsByUsers(userIds []int) (map[int][]Contribution, error) {
+	return contributionsGroupedBy("user_id", userIds, func(contribution Contribution) int { return contribution.UserID })
+}
+
+func GetContributionsByFoodItems(foodItemIds []int) (map[int][]Contribution, error) {
+	return contributionsGroupedBy("food_item_id", foodItemIds, func(contribution Contribution) int { return contribution.FoodItemID })
+}
+
+// column comes from the functions above, never from a request
+func contributionsGroupedBy(column string, ids []int, key func(Contribution) int) (map[int][]Contribution, error) {
+
+	contributions := make(map[int][]Contribution)
+	if len(ids) == 0 {
+		return contributions, nil
+	}
+	placeholders, args := inList(ids)
+
+	rows, err := DB.Query("SELECT "+contributionColumns+" FROM contributions WHERE "+column+" IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		contribution := Contribution{}
+		err := rows.Scan(&contribution.ID, &contribution.UserID, &contribution.PicnicID, &contribution.FoodItemID, &contribution.Quantity, &contribution.PaidBy, &contribution.AmountPaid, &contribution.Version)
+		if err != nil {
+			return nil, err
+		}
+		contributions[key(contribution)] = append(contributions[key(contribution)], contribution)
+	}
+	return contributions, rows.Err()
+}
@@ -0,0 +1,338 @@
+package models
+
+import (
+	"database/sql"
+	"server/i18n"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const CREATE_COMMENTS_TABLES_SQL = `
+
+CREATE TABLE IF NOT EXISTS comments (
+  id               INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  picnic_id        INTEGER NOT NULL,
+  contribution_id  INTEGER,
+  user_id          INTEGER NOT NULL,
+  body             TEXT NOT NULL,
+  created_at       DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  edited_at        DATETIME,
+  FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE,
+  FOREIGN KEY (contribution_id) REFERENCES contributions(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_comments_on_picnic_id ON comments (picnic_id, id);
+
+CREATE TABLE IF NOT EXISTS comment_mentions (
+  comment_id  INTEGER NOT NULL,
+  user_id     INTEGER NOT NULL,
+  PRIMARY KEY (comment_id, user_id),
+  FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+`
+
+const MaxCommentLength = 4000
+
+// ContributionID is 0 for comments on the picnic itself. Mentions are the ids
+// of the attendees @mentioned in Body.
+type Comment struct {
+	ID             int    `json:"id"`
+	PicnicID       int    `json:"picnic_id"`
+	ContributionID int    `json:"contribution_id,omitempty"`
+	UserID         int    `json:"user_id"`
+	Body           string `json:"body" binding:"required"`
+	Mentions       []int  `json:"mentions"`
+	CreatedAt      string `json:"created_at"`
+	EditedAt       string `json:"edited_at,omitempty"`
+}
+
+// FindMentions returns the users whose "@Name" appears in body. Names are
+// matched case-insensitively and the longest name wins, so "@Ana Maria" is
+// not also a mention of "Ana".
+func FindMentions(body string, users []User) []int {
+
+	candidates := append([]User(nil), users...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].Name) > len(candidates[j].Name)
+	})
+
+	text := strings.ToLower(body)
+	mentions := make([]int, 0)
+
+	for _, user := range candidates {
+		if user.Name == "" {
+			continue
+		}
+		mention := "@" + strings.ToLower(user.Name)
+		found := false
+
+		for offset := 0; ; {
+			i := strings.Index(text[offset:], mention)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			end := start + len(mention)
+			offset = end
+
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if end < len(text) && (unicode.IsLetter(next) || unicode.IsDigit(next)) {
+				continue
+			}
+			found = true
+			// se borran todas para que un nombre mas corto no las vuelva a encontrar
+			text = text[:start] + strings.Repeat(" ", end-start) + text[end:]
+		}
+		if found {
+			mentions = append(mentions, user.ID)
+		}
+	}
+	sort.Ints(mentions)
+	return mentions
+}
+
+func validateComment(comment Comment) error {
+
+	if strings.TrimSpace(comment.Body) == "" {
+		return i18n.Errorf("comment body can't be empty")
+	}
+	if utf8.RuneCountInString(comment.Body) > MaxCommentLength {
+		return i18n.Errorf("comment body is longer than %d characters", MaxCommentLength)
+	}
+	if comment.ContributionID == 0 {
+		return nil
+	}
+
+	contribution, err := GetContributionById(comment.ContributionID)
+	if err != nil {
+		return err
+	}
+	if contribution.ID == 0 || contribution.PicnicID != comment.PicnicID {
+		return i18n.Errorf("contribution %d is not part of picnic %d", comment.ContributionID, comment.PicnicID)
+	}
+	return nil
+}
+
+func setMentions(tx *sql.Tx, comment Comment) ([]int, error) {
+
+	users, err := GetUsersByPicnic(comment.PicnicID)
+	if err != nil {
+		return nil, err
+	}
+	mentions := FindMentions(comment.Body, users)
+
+	_, err = tx.Exec("DELETE FROM comment_mentions WHERE comment_id = ?", comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range mentions {
+		_, err = tx.Exec("INSERT INTO comment_mentions (comment_id, user_id) VALUES (?, ?)", comment.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mentions, nil
+}
+
+func CreateComment(newComment Comment) (Comment, error) {
+
+	if err := validateComment(newComment); err != nil {
+		return Comment{}, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Comment{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO comments (picnic_id, contribution_id, user_id, body) VALUES (?, ?, ?, ?)", newComment.PicnicID, nullableID(newComment.ContributionID), newComment.UserID, newComment.Body)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Comment{}, err
+	}
+	newComment.ID = int(id)
+
+	_, err = setMentions(tx, newComment)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	tx.Commit()
+
+	return GetCommentById(newComment.ID)
+}
+
+// UpdateComment only changes the body, the comment stays where it was posted
+func UpdateComment(updatedComment Comment, idToUpdate int) (Comment, error) {
+
+	current, err := GetCommentById(idToUpdate)
+	if err != nil {
+		return Comment{}, err
+	}
+	if current.ID == 0 {
+		return Comment{}, i18n.Errorf("comment %d not found", idToUpdate)
+	}
+
+	current.Body = updatedComment.Body
+	if err := validateComment(current); err != nil {
+		return Comment{}, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Comment{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE comments SET body = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ?", current.Body, idToUpdate)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	_, err = setMentions(tx, current)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	tx.Commit()
+
+	return GetCommentById(idToUpdate)
+}
+
+func DeleteComment(commentID int) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM comment_mentions WHERE comment_id = ?", commentID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec("DELETE FROM comments WHERE id = ?", commentID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("comment %d not found", commentID)
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
+
+const commentColumns = "id, picnic_id, COALESCE(contribution_id, 0), user_id, body, created_at, edited_at"
+
+func scanComment(row scanner) (Comment, error) {
+	comment := Comment{}
+	var editedAt sql.NullString
+	err := row.Scan(&comment.ID, &comment.PicnicID, &comment.ContributionID, &comment.UserID, &comment.Body, &comment.CreatedAt, &editedAt)
+	comment.EditedAt = editedAt.String
+	return comment, err
+}
+
+func GetCommentById(id int) (Comment, error) {
+
+	comment, err := scanComment(DB.QueryRow("SELECT "+commentColumns+" FROM comments WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Comment{}, nil
+		}
+		return Comment{}, err
+	}
+
+	comment.Mentions, err = getMentions(comment.ID)
+	if err != nil {
+		return Comment{}, err
+	}
+	return comment, nil
+}
+
+// GetCommentsByPicnic pages oldest first: up to limit comments with an id
+// greater than afterID. contributionID 0 returns the whole thread.
+func GetCommentsByPicnic(picnicID int, contributionID int, afterID int, limit int) ([]Comment, error) {
+
+	query := "SELECT " + commentColumns + " FROM comments WHERE picnic_id = ? AND id > ?"
+	args := []interface{}{picnicID, afterID}
+	if contributionID != 0 {
+		query += " AND contribution_id = ?"
+		args = append(args, contributionID)
+	}
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := DB.Query(query, args...)
+	comments := make([]Comment, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			rows.Close()
+			return make([]Comment, 0), err
+		}
+		comments = append(comments, comment)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Comment, 0), err
+	}
+
+	for i := range comments {
+		comments[i].Mentions, err = getMentions(comments[i].ID)
+		if err != nil {
+			return make([]Comment, 0), err
+		}
+	}
+
+	return comments, err
+}
+
+func getMentions(commentID int) ([]int, error) {
+
+	rows, err := DB.Query("SELECT user_id FROM comment_mentions WHERE comment_id = ? ORDER BY user_id", commentID)
+	mentions := make([]int, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return make([]int, 0), err
+		}
+		mentions = append(mentions, userID)
+	}
+	return mentions, rows.Err()
+}
+
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFindMentions(t *testing.T) {
+
+	users := []User{{ID: 1, Name: "Ana"}, {ID: 2, Name: "Ana María"}, {ID: 3, Name: "Bob"}}
+
+	tests := []struct {
+		body string
+		want []int
+	}{
+		{"hola @Ana", []int{1}},
+		{"hola @Ana María", []int{2}},
+		{"@ana maría y @ANA", []int{1, 2}},
+		{"@Ana María y @Ana María otra vez", []int{2}},
+		{"@Ana, @Bob.", []int{1, 3}},
+		{"@Anabel no es nadie", []int{}},
+		{"@Ana Marían es Ana", []int{1}},
+		{"Ana sin arroba", []int{}},
+		{"", []int{}},
+	}
+	for _, tt := range tests {
+		if got := FindMentions(tt.body, users); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindMentions(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestCommentPagesAfterID(t *testing.T) {
+
+	user := newTestUser(t, "commenter")
+	picnicID, err := CreatePicnic(Picnic{Name: "Paged", Date: "2026-11-21 13:00", CreatedBy: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]int, 0, 5)
+	for i := 1; i <= 5; i++ {
+		comment, err := CreateComment(Comment{PicnicID: picnicID, UserID: user.ID, Body: fmt.Sprintf("comment %d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, comment.ID)
+	}
+
+	tests := []struct {
+		name    string
+		afterID int
+		limit   int
+		want    []int
+	}{
+		{"first page", 0, 2, ids[:2]},
+		{"after the first page", ids[1], 2, ids[2:4]},
+		{"last page is short", ids[3], 2, ids[4:]},
+		{"after the last", ids[4], 2, []int{}},
+		{"limit equals what is left", ids[2], 2, ids[3:]},
+		{"limit above the total", 0, 10, ids},
+	}
+	for _, tt := range tests {
+		comments, err := GetCommentsByPicnic(picnicID, 0, tt.afterID, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]int, 0, len(comments))
+		for _, comment := range comments {
+			got = append(got, comment.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"database/sql"
+)
+
+const CREATE_PICNIC_EVENTS_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS picnic_events (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  picnic_id   INTEGER NOT NULL,
+  event       VARCHAR NOT NULL,
+  data        TEXT NOT NULL,
+  created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS index_picnic_events_on_picnic_id ON picnic_events (picnic_id, id);
+
+`
+
+// Eventos del stream de un picnic, ademas de los de webhooks
+const (
+	EventPicnicUpdated       = "picnic.updated"
+	EventMembershipUpdated   = "membership.updated"
+	EventCommentCreated      = "comment.created"
+	EventCommentUpdated      = "comment.updated"
+	EventCommentDeleted      = "comment.deleted"
+	EventPollCreated         = "poll.created"
+	EventPollVoted           = "poll.voted"
+	EventPollClosed          = "poll.closed"
+	EventGearAssigned        = "gear.assigned"
+	EventGearUpdated         = "gear.updated"
+	EventGearRemoved         = "gear.removed"
+	EventInventoryReserved   = "inventory.reserved"
+	EventInventoryCheckedOut = "inventory.checked_out"
+	EventInventoryReturned   = "inventory.returned"
+	EventInventoryCancelled  = "inventory.cancelled"
+)
+
+// PicnicEvent is one entry of the log behind /picnics/:picnic_id/events, the
+// id is what clients send back as Last-Event-ID.
+type PicnicEvent struct {
+	ID        int    `json:"id"`
+	PicnicID  int    `json:"picnic_id"`
+	Event     string `json:"event"`
+	Data      string `json:"data"`
+	CreatedAt string `json:"created_at"`
+}
+
+func AddPicnicEvent(picnicID int, event string, data string) (PicnicEvent, error) {
+
+	result, err := DB.Exec("INSERT INTO picnic_events (picnic_id, event, data) VALUES (?, ?, ?)", picnicID, event, data)
+	if err != nil {
+		return PicnicEvent{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return PicnicEvent{}, err
+	}
+
+	return GetPicnicEventById(int(id))
+}
+
+const picnicEventColumns = "id, picnic_id, event, data, created_at"
+
+func GetPicnicEventById(id int) (PicnicEvent, error) {
+
+	event := PicnicEvent{}
+	err := DB.QueryRow("SELECT "+picnicEventColumns+" FROM picnic_events WHERE id = ?", id).Scan(&event.ID, &event.PicnicID, &event.Event, &event.Data, &event.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return PicnicEvent{}, nil
+		}
+		return PicnicEvent{}, err
+	}
+	return event, nil
+}
+
+// GetPicnicEventsAfter returns up to limit events newer than afterID, oldest first
+func GetPicnicEventsAfter(picnicID int, afterID int, limit int) ([]PicnicEvent, error) {
+
+	rows, err := DB.Query("SELECT "+picnicEventColumns+" FROM picnic_events WHERE picnic_id = ? AND id > ? ORDER BY id LIMIT ?", picnicID, afterID, limit)
+	events := make([]PicnicEvent, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		event := PicnicEvent{}
+		err := rows.Scan(&event.ID, &event.PicnicID, &event.Event, &event.Data, &event.CreatedAt)
+		if err != nil {
+			return make([]PicnicEvent, 0), err
+		}
+		events = append(events, event)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]PicnicEvent, 0), err
+	}
+
+	return events, err
+}
+
+// LastPicnicEventId is 0 when the picnic has no events yet
+func LastPicnicEventId(picnicID int) (int, error) {
+	var id int
+	err := DB.QueryRow("SELECT COALESCE(MAX(id), 0) FROM picnic_events WHERE picnic_id = ?", picnicID).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,302 @@
+package models
+
+import (
+	"database/sql"
+	"server/i18n"
+)
+
+const CREATE_GEAR_TABLES_SQL = `
+
+CREATE TABLE IF NOT EXISTS gear_items (
+  id           INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  name         VARCHAR NOT NULL,
+  description  VARCHAR NOT NULL DEFAULT '',
+  url          VARCHAR NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS gear_assignments (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  picnic_id     INTEGER NOT NULL,
+  gear_item_id  INTEGER NOT NULL,
+  user_id       INTEGER NOT NULL,
+  quantity      INTEGER NOT NULL DEFAULT 1,
+  FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE,
+  FOREIGN KEY (gear_item_id) REFERENCES gear_items(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_gear_assignments_on_picnic_id ON gear_assignments (picnic_id);
+
+`
+
+// GearItem es lo que no se come: mantas, parrillas, vasos. No lleva measure,
+// se cuenta por unidad.
+type GearItem struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Url         string `json:"url"`
+}
+
+// GearAssignment is who brings how many of a gear item to a picnic
+type GearAssignment struct {
+	ID         int `json:"id"`
+	PicnicID   int `json:"picnic_id"`
+	GearItemID int `json:"gear_item_id" binding:"required"`
+	UserID     int `json:"user_id"`
+	Quantity   int `json:"quantity"`
+}
+
+// ListItem is one line of a picnic's bring list, food and gear together
+type ListItem struct {
+	Kind     string `json:"kind"`
+	ID       int    `json:"id"`
+	UserID   int    `json:"user_id"`
+	ItemID   int    `json:"item_id"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Measure  string `json:"measure,omitempty"`
+}
+
+const (
+	ListItemFood = "food"
+	ListItemGear = "gear"
+)
+
+func CreateGearItem(newGearItem GearItem) (int, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO gear_items (name, description, url) VALUES (?, ?, ?)", newGearItem.Name, newGearItem.Description, newGearItem.Url)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	tx.Commit()
+
+	return int(id), nil
+}
+
+func GetGearItemById(id int) (GearItem, error) {
+
+	gearItem := GearItem{}
+	err := DB.QueryRow("SELECT id, name, description, url FROM gear_items WHERE id = ?", id).Scan(&gearItem.ID, &gearItem.Name, &gearItem.Description, &gearItem.Url)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return GearItem{}, nil
+		}
+		return GearItem{}, err
+	}
+	return gearItem, nil
+}
+
+func GetGearItems() ([]GearItem, error) {
+
+	rows, err := DB.Query("SELECT id, name, description, url FROM gear_items ORDER BY name")
+	gearItems := make([]GearItem, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		gearItem := GearItem{}
+		err = rows.Scan(&gearItem.ID, &gearItem.Name, &gearItem.Description, &gearItem.Url)
+		if err != nil {
+			rows.Close()
+			return make([]GearItem, 0), err
+		}
+		gearItems = append(gearItems, gearItem)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]GearItem, 0), err
+	}
+
+	return gearItems, err
+}
+
+func UpdateGearItem(updatedGearItem GearItem, idToUpdate int) (bool, error) {
+
+	result, err := DB.Exec("UPDATE gear_items SET name = ?, description = ?, url = ? WHERE id = ?", updatedGearItem.Name, updatedGearItem.Description, updatedGearItem.Url, idToUpdate)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("gear item %d not found", idToUpdate)
+	}
+	return true, nil
+}
+
+func validateGearAssignment(assignment GearAssignment) error {
+
+	if assignment.Quantity <= 0 {
+		return i18n.Errorf("quantity must be at least 1")
+	}
+
+	gearItem, err := GetGearItemById(assignment.GearItemID)
+	if err != nil {
+		return err
+	}
+	if gearItem.ID == 0 {
+		return i18n.Errorf("gear item %d not found", assignment.GearItemID)
+	}
+	return nil
+}
+
+func CreateGearAssignment(newAssignment GearAssignment) (int, error) {
+
+	if err := validateGearAssignment(newAssignment); err != nil {
+		return 0, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO gear_assignments (picnic_id, gear_item_id, user_id, quantity) VALUES (?, ?, ?, ?)", newAssignment.PicnicID, newAssignment.GearItemID, newAssignment.UserID, newAssignment.Quantity)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	tx.Commit()
+
+	return int(id), nil
+}
+
+func GetGearAssignmentById(id int) (GearAssignment, error) {
+
+	assignment := GearAssignment{}
+	err := DB.QueryRow("SELECT id, picnic_id, gear_item_id, user_id, quantity FROM gear_assignments WHERE id = ?", id).Scan(&assignment.ID, &assignment.PicnicID, &assignment.GearItemID, &assignment.UserID, &assignment.Quantity)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return GearAssignment{}, nil
+		}
+		return GearAssignment{}, err
+	}
+	return assignment, nil
+}
+
+func queryGearAssignments(query string, args ...interface{}) ([]GearAssignment, error) {
+
+	rows, err := DB.Query(query, args...)
+	assignments := make([]GearAssignment, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		assignment := GearAssignment{}
+		err = rows.Scan(&assignment.ID, &assignment.PicnicID, &assignment.GearItemID, &assignment.UserID, &assignment.Quantity)
+		if err != nil {
+			rows.Close()
+			return make([]GearAssignment, 0), err
+		}
+		assignments = append(assignments, assignment)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]GearAssignment, 0), err
+	}
+
+	return assignments, err
+}
+
+func GetGearAssignmentsByPicnic(picnicID int) ([]GearAssignment, error) {
+	return queryGearAssignments("SELECT id, picnic_id, gear_item_id, user_id, quantity FROM gear_assignments WHERE picnic_id = ? ORDER BY id", picnicID)
+}
+
+func GetGearAssignmentsByUserAndPicnic(userID int, picnicID int) ([]GearAssignment, error) {
+	return queryGearAssignments("SELECT id, picnic_id, gear_item_id, user_id, quantity FROM gear_assignments WHERE user_id = ? AND picnic_id = ? ORDER BY id", userID, picnicID)
+}
+
+// UpdateGearAssignment keeps the picnic, only the item, the person and the
+// quantity change.
+func UpdateGearAssignment(updatedAssignment GearAssignment, idToUpdate int) (bool, error) {
+
+	if err := validateGearAssignment(updatedAssignment); err != nil {
+		return false, err
+	}
+
+	_, err := DB.Exec("UPDATE gear_assignments SET gear_item_id = ?, user_id = ?, quantity = ? WHERE id = ?", updatedAssignment.GearItemID, updatedAssignment.UserID, updatedAssignment.Quantity, idToUpdate)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func DeleteGearAssignment(id int) (bool, error) {
+
+	_, err := DB.Exec("DELETE FROM gear_assignments WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetPicnicList is everything people bring to the picnic, food contributions
+// first and then gear.
+func GetPicnicList(picnicID int) ([]ListItem, error) {
+
+	list := make([]ListItem, 0)
+
+	rows, err := DB.Query("SELECT contributions.id, contributions.user_id, food_items.id, food_items.name, contributions.quantity, food_items.measure FROM contributions INNER JOIN food_items ON food_items.id = contributions.food_item_id WHERE contributions.picnic_id = ? ORDER BY contributions.id", picnicID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		item := ListItem{Kind: ListItemFood}
+		err = rows.Scan(&item.ID, &item.UserID, &item.ItemID, &item.Name, &item.Quantity, &item.Measure)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = DB.Query("SELECT gear_assignments.id, gear_assignments.user_id, gear_items.id, gear_items.name, gear_assignments.quantity FROM gear_assignments INNER JOIN gear_items ON gear_items.id = gear_assignments.gear_item_id WHERE gear_assignments.picnic_id = ? ORDER BY gear_assignments.id", picnicID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		item := ListItem{Kind: ListItemGear}
+		err = rows.Scan(&item.ID, &item.UserID, &item.ItemID, &item.Name, &item.Quantity)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
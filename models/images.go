@@ -0,0 +1,226 @@
+package models
+
+import (
+	"database/sql"
+	"server/i18n"
+)
+
+const CREATE_IMAGES_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS images (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  name          VARCHAR NOT NULL UNIQUE,
+  thumb_name    VARCHAR NOT NULL UNIQUE,
+  content_type  VARCHAR NOT NULL,
+  width         INTEGER NOT NULL,
+  height        INTEGER NOT NULL,
+  size          INTEGER NOT NULL,
+  user_id       INTEGER,
+  food_item_id  INTEGER,
+  picnic_id     INTEGER,
+  created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+  FOREIGN KEY (food_item_id) REFERENCES food_items(id) ON DELETE CASCADE,
+  FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_images_on_picnic_id ON images (picnic_id);
+CREATE INDEX IF NOT EXISTS index_images_on_food_item_id ON images (food_item_id);
+
+`
+
+// ImagePath is where the uploaded files are served, see ImageURL
+const ImagePath = "/uploads/"
+
+// Un image es de un food item o de un picnic, nunca de los dos. Name y
+// ThumbName son las keys en el blob store, las URLs salen de ahi.
+type Image struct {
+	ID           int    `json:"id"`
+	Name         string `json:"-"`
+	ThumbName    string `json:"-"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Size         int    `json:"size"`
+	UserID       int    `json:"user_id"`
+	FoodItemID   int    `json:"food_item_id,omitempty"`
+	PicnicID     int    `json:"picnic_id,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+func ImageURL(name string) string {
+	return ImagePath + name
+}
+
+// CreateImage saves a picnic photo, food item images go through
+// SetFoodItemImage.
+func CreateImage(newImage Image) (Image, error) {
+
+	if newImage.PicnicID == 0 {
+		return Image{}, i18n.Errorf("image needs a picnic")
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Image{}, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertImage(tx, newImage)
+	if err != nil {
+		return Image{}, err
+	}
+
+	tx.Commit()
+
+	return GetImageById(id)
+}
+
+// SetFoodItemImage stores the new image of a food item and points its url
+// to it. The images it replaces are deleted from the table and returned so
+// the caller can remove their files.
+func SetFoodItemImage(newImage Image) (Image, []Image, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Image{}, nil, err
+	}
+	defer tx.Rollback()
+
+	replaced, err := queryImages(tx, "SELECT "+imageColumns+" FROM images WHERE food_item_id = ? ORDER BY id", newImage.FoodItemID)
+	if err != nil {
+		return Image{}, nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM images WHERE food_item_id = ?", newImage.FoodItemID)
+	if err != nil {
+		return Image{}, nil, err
+	}
+
+	id, err := insertImage(tx, newImage)
+	if err != nil {
+		return Image{}, nil, err
+	}
+
+	result, err := tx.Exec("UPDATE food_items SET url = ? WHERE id = ?", ImageURL(newImage.Name), newImage.FoodItemID)
+	if err != nil {
+		return Image{}, nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return Image{}, nil, err
+	}
+	if affected == 0 {
+		return Image{}, nil, i18n.Errorf("food item %d not found", newImage.FoodItemID)
+	}
+
+	tx.Commit()
+
+	image, err := GetImageById(id)
+	return image, replaced, err
+}
+
+func insertImage(tx *sql.Tx, newImage Image) (int, error) {
+
+	result, err := tx.Exec("INSERT INTO images (name, thumb_name, content_type, width, height, size, user_id, food_item_id, picnic_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		newImage.Name, newImage.ThumbName, newImage.ContentType, newImage.Width, newImage.Height, newImage.Size,
+		nullableID(newImage.UserID), nullableID(newImage.FoodItemID), nullableID(newImage.PicnicID))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+const imageColumns = "id, name, thumb_name, content_type, width, height, size, COALESCE(user_id, 0), COALESCE(food_item_id, 0), COALESCE(picnic_id, 0), created_at"
+
+func scanImage(row scanner) (Image, error) {
+	image := Image{}
+	err := row.Scan(&image.ID, &image.Name, &image.ThumbName, &image.ContentType, &image.Width, &image.Height, &image.Size, &image.UserID, &image.FoodItemID, &image.PicnicID, &image.CreatedAt)
+	image.URL = ImageURL(image.Name)
+	image.ThumbnailURL = ImageURL(image.ThumbName)
+	return image, err
+}
+
+// querier is DB or a transaction
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func queryImages(db querier, query string, args ...interface{}) ([]Image, error) {
+
+	rows, err := db.Query(query, args...)
+	images := make([]Image, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		image, err := scanImage(rows)
+		if err != nil {
+			rows.Close()
+			return make([]Image, 0), err
+		}
+		images = append(images, image)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Image, 0), err
+	}
+
+	return images, nil
+}
+
+func GetImageById(id int) (Image, error) {
+
+	image, err := scanImage(DB.QueryRow("SELECT "+imageColumns+" FROM images WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Image{}, nil
+		}
+		return Image{}, err
+	}
+	return image, nil
+}
+
+// GetImageByName finds the image a served file belongs to, name can be the
+// image or its thumbnail.
+func GetImageByName(name string) (Image, error) {
+
+	image, err := scanImage(DB.QueryRow("SELECT "+imageColumns+" FROM images WHERE name = ? OR thumb_name = ?", name, name))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Image{}, nil
+		}
+		return Image{}, err
+	}
+	return image, nil
+}
+
+func GetImagesByPicnic(picnicID int) ([]Image, error) {
+	return queryImages(DB, "SELECT "+imageColumns+" FROM images WHERE picnic_id = ? ORDER BY id", picnicID)
+}
+
+func DeleteImage(id int) (bool, error) {
+
+	result, err := DB.Exec("DELETE FROM images WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("image %d not found", id)
+	}
+	return true, nil
+}
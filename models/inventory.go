@@ -0,0 +1,458 @@
+package models
+
+import (
+	"database/sql"
+	"server/i18n"
+)
+
+const CREATE_INVENTORY_TABLES_SQL = `
+
+CREATE TABLE IF NOT EXISTS inventory_items (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  name          VARCHAR NOT NULL,
+  gear_item_id  INTEGER,
+  notes         VARCHAR NOT NULL DEFAULT '',
+  holder_id     INTEGER,
+  created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (gear_item_id) REFERENCES gear_items(id) ON DELETE SET NULL,
+  FOREIGN KEY (holder_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS inventory_reservations (
+  id              INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  item_id         INTEGER NOT NULL,
+  picnic_id       INTEGER NOT NULL,
+  user_id         INTEGER NOT NULL,
+  status          VARCHAR NOT NULL DEFAULT 'reserved',
+  created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  checked_out_at  DATETIME,
+  returned_at     DATETIME,
+  FOREIGN KEY (item_id) REFERENCES inventory_items(id) ON DELETE CASCADE,
+  FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_inventory_reservations_on_item_id ON inventory_reservations (item_id, status);
+CREATE INDEX IF NOT EXISTS index_inventory_reservations_on_picnic_id ON inventory_reservations (picnic_id);
+
+`
+
+// Estados de una reserva. reserved y checked_out bloquean el item para
+// otros picnics en las mismas fechas.
+const (
+	ReservationReserved   = "reserved"
+	ReservationCheckedOut = "checked_out"
+	ReservationReturned   = "returned"
+	ReservationCancelled  = "cancelled"
+)
+
+// InventoryItem is a piece of equipment the team owns, HolderID is who has
+// it right now (0 when it is in storage).
+type InventoryItem struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name" binding:"required"`
+	GearItemID int    `json:"gear_item_id,omitempty"`
+	Notes      string `json:"notes"`
+	HolderID   int    `json:"holder_id"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// Reservation ties an item to a picnic, the rows of an item are its history
+type Reservation struct {
+	ID           int    `json:"id"`
+	ItemID       int    `json:"item_id" binding:"required"`
+	PicnicID     int    `json:"picnic_id"`
+	UserID       int    `json:"user_id"`
+	Status       string `json:"status"`
+	CreatedAt    string `json:"created_at"`
+	CheckedOutAt string `json:"checked_out_at,omitempty"`
+	ReturnedAt   string `json:"returned_at,omitempty"`
+}
+
+// DoubleBookingError says which reservation is already using the item
+type DoubleBookingError struct {
+	ItemID   int
+	PicnicID int
+	Conflict Reservation
+}
+
+func (e *DoubleBookingError) Error() string {
+	return e.Unwrap().Error()
+}
+
+// Unwrap is the translatable message, the conflict can be with the same
+// picnic or with another one
+func (e *DoubleBookingError) Unwrap() error {
+	if e.Conflict.PicnicID == e.PicnicID {
+		return i18n.Errorf("item %d is already reserved for this picnic", e.ItemID)
+	}
+	return i18n.Errorf("item %d is already reserved for picnic %d on overlapping dates", e.ItemID, e.Conflict.PicnicID)
+}
+
+func CreateInventoryItem(newItem InventoryItem) (InventoryItem, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return InventoryItem{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO inventory_items (name, gear_item_id, notes) VALUES (?, ?, ?)", newItem.Name, nullableID(newItem.GearItemID), newItem.Notes)
+	if err != nil {
+		return InventoryItem{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return InventoryItem{}, err
+	}
+
+	tx.Commit()
+
+	return GetInventoryItemById(int(id))
+}
+
+const inventoryItemColumns = "id, name, COALESCE(gear_item_id, 0), notes, COALESCE(holder_id, 0), created_at"
+
+func scanInventoryItem(row scanner) (InventoryItem, error) {
+	item := InventoryItem{}
+	err := row.Scan(&item.ID, &item.Name, &item.GearItemID, &item.Notes, &item.HolderID, &item.CreatedAt)
+	return item, err
+}
+
+func GetInventoryItemById(id int) (InventoryItem, error) {
+
+	item, err := scanInventoryItem(DB.QueryRow("SELECT "+inventoryItemColumns+" FROM inventory_items WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return InventoryItem{}, nil
+		}
+		return InventoryItem{}, err
+	}
+	return item, nil
+}
+
+func GetInventoryItems() ([]InventoryItem, error) {
+
+	rows, err := DB.Query("SELECT " + inventoryItemColumns + " FROM inventory_items ORDER BY name")
+	items := make([]InventoryItem, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		item, err := scanInventoryItem(rows)
+		if err != nil {
+			rows.Close()
+			return make([]InventoryItem, 0), err
+		}
+		items = append(items, item)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]InventoryItem, 0), err
+	}
+
+	return items, err
+}
+
+// UpdateInventoryItem doesn't touch the holder, that only changes through
+// checkout and return.
+func UpdateInventoryItem(updatedItem InventoryItem, idToUpdate int) (bool, error) {
+
+	result, err := DB.Exec("UPDATE inventory_items SET name = ?, gear_item_id = ?, notes = ? WHERE id = ?", updatedItem.Name, nullableID(updatedItem.GearItemID), updatedItem.Notes, idToUpdate)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("inventory item %d not found", idToUpdate)
+	}
+	return true, nil
+}
+
+const reservationColumns = "id, item_id, picnic_id, user_id, status, created_at, checked_out_at, returned_at"
+
+func scanReservation(row scanner) (Reservation, error) {
+	reservation := Reservation{}
+	var checkedOutAt, returnedAt sql.NullString
+	err := row.Scan(&reservation.ID, &reservation.ItemID, &reservation.PicnicID, &reservation.UserID, &reservation.Status, &reservation.CreatedAt, &checkedOutAt, &returnedAt)
+	reservation.CheckedOutAt = checkedOutAt.String
+	reservation.ReturnedAt = returnedAt.String
+	return reservation, err
+}
+
+func queryReservations(db querier, query string, args ...interface{}) ([]Reservation, error) {
+
+	rows, err := db.Query(query, args...)
+	reservations := make([]Reservation, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			rows.Close()
+			return make([]Reservation, 0), err
+		}
+		reservations = append(reservations, reservation)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Reservation, 0), err
+	}
+
+	return reservations, err
+}
+
+func GetReservationById(id int) (Reservation, error) {
+
+	reservation, err := scanReservation(DB.QueryRow("SELECT "+reservationColumns+" FROM inventory_reservations WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Reservation{}, nil
+		}
+		return Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func GetReservationsByPicnic(picnicID int) ([]Reservation, error) {
+	return queryReservations(DB, "SELECT "+reservationColumns+" FROM inventory_reservations WHERE picnic_id = ? ORDER BY id", picnicID)
+}
+
+// GetItemHistory is newest first, the first checked out or returned row is
+// who last had the item.
+func GetItemHistory(itemID int) ([]Reservation, error) {
+	return queryReservations(DB, "SELECT "+reservationColumns+" FROM inventory_reservations WHERE item_id = ? ORDER BY id DESC", itemID)
+}
+
+func activeReservations(db querier, itemID int) ([]Reservation, error) {
+	return queryReservations(db, "SELECT "+reservationColumns+" FROM inventory_reservations WHERE item_id = ? AND status IN (?, ?) ORDER BY id", itemID, ReservationReserved, ReservationCheckedOut)
+}
+
+// findDoubleBooking returns the active reservation of itemID for picnicID,
+// or for another picnic whose dates overlap it, or an empty Reservation.
+// exceptID is left out, the reservation being checked.
+func findDoubleBooking(db querier, itemID int, picnicID int, exceptID int) (Reservation, error) {
+
+	picnic, err := getPicnic(db, picnicID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if picnic.ID == 0 {
+		return Reservation{}, i18n.Errorf("picnic %d not found", picnicID)
+	}
+	window, err := picnicInterval(picnic)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	reservations, err := activeReservations(db, itemID)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	for _, reservation := range reservations {
+		if reservation.ID == exceptID {
+			continue
+		}
+		if reservation.PicnicID == picnicID {
+			return reservation, nil
+		}
+		other, err := getPicnic(db, reservation.PicnicID)
+		if err != nil {
+			return Reservation{}, err
+		}
+		otherWindow, err := picnicInterval(other)
+		if err != nil {
+			continue
+		}
+		if overlaps([]interval{otherWindow}, window.start, window.end) {
+			return reservation, nil
+		}
+	}
+	return Reservation{}, nil
+}
+
+// ReserveItem books the item for the picnic, a *DoubleBookingError comes
+// back when another picnic on overlapping dates already has it.
+func ReserveItem(newReservation Reservation) (Reservation, error) {
+
+	item, err := GetInventoryItemById(newReservation.ItemID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if item.ID == 0 {
+		return Reservation{}, i18n.Errorf("inventory item %d not found", newReservation.ItemID)
+	}
+
+	// primero el insert, que toma el lock de escritura: otra reserva a la vez
+	// espera al commit y despues ve esta. Si hay conflicto se deshace.
+	tx, err := DB.Begin()
+	if err != nil {
+		return Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO inventory_reservations (item_id, picnic_id, user_id) VALUES (?, ?, ?)", newReservation.ItemID, newReservation.PicnicID, newReservation.UserID)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	conflict, err := findDoubleBooking(tx, newReservation.ItemID, newReservation.PicnicID, int(id))
+	if err != nil {
+		return Reservation{}, err
+	}
+	if conflict.ID != 0 {
+		return Reservation{}, &DoubleBookingError{ItemID: newReservation.ItemID, PicnicID: newReservation.PicnicID, Conflict: conflict}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Reservation{}, err
+	}
+	return GetReservationById(int(id))
+}
+
+// CheckoutItem hands the item to userID. It has to be in storage, whoever
+// had it last must return it first.
+func CheckoutItem(reservationID int, userID int) (Reservation, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	reservation, err := scanReservation(tx.QueryRow("SELECT "+reservationColumns+" FROM inventory_reservations WHERE id = ?", reservationID))
+	if err != nil {
+		return Reservation{}, err
+	}
+	if reservation.Status != ReservationReserved {
+		return Reservation{}, i18n.Errorf("reservation %d is %s, only reserved items can be checked out", reservationID, reservation.Status)
+	}
+
+	result, err := tx.Exec("UPDATE inventory_items SET holder_id = ? WHERE id = ? AND holder_id IS NULL", userID, reservation.ItemID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return Reservation{}, err
+	}
+	if affected == 0 {
+		return Reservation{}, i18n.Errorf("item %d hasn't been returned yet", reservation.ItemID)
+	}
+
+	_, err = tx.Exec("UPDATE inventory_reservations SET status = ?, user_id = ?, checked_out_at = CURRENT_TIMESTAMP WHERE id = ?", ReservationCheckedOut, userID, reservationID)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	tx.Commit()
+
+	return GetReservationById(reservationID)
+}
+
+func ReturnItem(reservationID int) (Reservation, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	reservation, err := scanReservation(tx.QueryRow("SELECT "+reservationColumns+" FROM inventory_reservations WHERE id = ?", reservationID))
+	if err != nil {
+		return Reservation{}, err
+	}
+	if reservation.Status != ReservationCheckedOut {
+		return Reservation{}, i18n.Errorf("reservation %d is %s, only checked out items can be returned", reservationID, reservation.Status)
+	}
+
+	_, err = tx.Exec("UPDATE inventory_items SET holder_id = NULL WHERE id = ?", reservation.ItemID)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	_, err = tx.Exec("UPDATE inventory_reservations SET status = ?, returned_at = CURRENT_TIMESTAMP WHERE id = ?", ReservationReturned, reservationID)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	tx.Commit()
+
+	return GetReservationById(reservationID)
+}
+
+func CancelReservation(reservationID int) (bool, error) {
+
+	result, err := DB.Exec("UPDATE inventory_reservations SET status = ? WHERE id = ? AND status = ?", ReservationCancelled, reservationID, ReservationReserved)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("reservation %d can't be cancelled, only reserved items can", reservationID)
+	}
+	return true, nil
+}
+
+// GetDoubleBookings finds every pair of active reservations that overlap,
+// which can happen after a picnic changes its date.
+func GetDoubleBookings() ([][2]Reservation, error) {
+
+	items, err := GetInventoryItems()
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := make([][2]Reservation, 0)
+	for _, item := range items {
+		reservations, err := activeReservations(DB, item.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		windows := make([]interval, len(reservations))
+		for i, reservation := range reservations {
+			picnic, err := GetPicnicById(reservation.PicnicID)
+			if err != nil {
+				return nil, err
+			}
+			// sin fecha valida no se puede comparar
+			windows[i], _ = picnicInterval(picnic)
+		}
+
+		for i := range reservations {
+			for j := i + 1; j < len(reservations); j++ {
+				if windows[i].start.IsZero() || windows[j].start.IsZero() {
+					continue
+				}
+				if overlaps(windows[i:i+1], windows[j].start, windows[j].end) {
+					conflicts = append(conflicts, [2]Reservation{reservations[i], reservations[j]})
+				}
+			}
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,308 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"os"
+	"server/i18n"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const CREATE_INVITATIONS_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS invitations (
+  id           INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  picnic_id    INTEGER NOT NULL,
+  user_id      INTEGER,
+  created_by   INTEGER,
+  status       VARCHAR NOT NULL DEFAULT 'pending',
+  expires_at   DATETIME NOT NULL,
+  revoked_at   DATETIME,
+  created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS index_invitations_on_picnic_id ON invitations (picnic_id);
+
+`
+
+// Estados de una invitacion. Los links abiertos (sin UserID) quedan en pending
+// y se pueden usar varias veces hasta que vencen o se revocan.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+const DefaultInvitationDuration = 7 * 24 * time.Hour
+
+var ErrInvalidInvitation = i18n.Errorf("invitation link is invalid or has expired")
+
+// UserID 0 es un link abierto para cualquiera que lo tenga
+type Invitation struct {
+	ID        int    `json:"id"`
+	PicnicID  int    `json:"picnic_id"`
+	UserID    int    `json:"user_id"`
+	CreatedBy int    `json:"created_by"`
+	Status    string `json:"status"`
+	ExpiresAt string `json:"expires_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+func invitationSecret() ([]byte, error) {
+	if secret := os.Getenv("INVITATION_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	return GetSecret("invitations")
+}
+
+func signInvitation(payload string) (string, error) {
+	secret, err := invitationSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// InvitationToken is "<id>.<expires unix>.<signature>", so a link can be
+// checked before touching the database.
+func InvitationToken(invitation Invitation, expiresAt time.Time) (string, error) {
+	payload := strconv.Itoa(invitation.ID) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	signature, err := signInvitation(payload)
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + signature, nil
+}
+
+func parseInvitationToken(token string) (int, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidInvitation
+	}
+
+	expected, err := signInvitation(parts[0] + "." + parts[1])
+	if err != nil {
+		return 0, err
+	}
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return 0, ErrInvalidInvitation
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return 0, ErrInvalidInvitation
+	}
+
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, ErrInvalidInvitation
+	}
+	return id, nil
+}
+
+// CreateInvitation returns the stored invitation together with its signed token.
+func CreateInvitation(newInvitation Invitation, expiresIn time.Duration) (Invitation, string, error) {
+
+	if expiresIn <= 0 {
+		expiresIn = DefaultInvitationDuration
+	}
+	expiresAt := time.Now().UTC().Add(expiresIn).Truncate(time.Second)
+
+	var userID, createdBy sql.NullInt64
+	if newInvitation.UserID != 0 {
+		userID = sql.NullInt64{Int64: int64(newInvitation.UserID), Valid: true}
+	}
+	if newInvitation.CreatedBy != 0 {
+		createdBy = sql.NullInt64{Int64: int64(newInvitation.CreatedBy), Valid: true}
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Invitation{}, "", err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO invitations (picnic_id, user_id, created_by, expires_at) VALUES (?, ?, ?, ?)", newInvitation.PicnicID, userID, createdBy, expiresAt.Format(sqliteTimeFormat))
+	if err != nil {
+		return Invitation{}, "", err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Invitation{}, "", err
+	}
+
+	tx.Commit()
+
+	invitation, err := GetInvitationById(int(id))
+	if err != nil {
+		return Invitation{}, "", err
+	}
+
+	token, err := InvitationToken(invitation, expiresAt)
+	if err != nil {
+		return Invitation{}, "", err
+	}
+	return invitation, token, nil
+}
+
+const invitationColumns = "id, picnic_id, COALESCE(user_id, 0), COALESCE(created_by, 0), CASE WHEN revoked_at IS NOT NULL THEN 'revoked' WHEN status = 'pending' AND expires_at <= datetime('now') THEN 'expired' ELSE status END, expires_at, created_at"
+
+func scanInvitation(row scanner) (Invitation, error) {
+	invitation := Invitation{}
+	err := row.Scan(&invitation.ID, &invitation.PicnicID, &invitation.UserID, &invitation.CreatedBy, &invitation.Status, &invitation.ExpiresAt, &invitation.CreatedAt)
+	return invitation, err
+}
+
+func GetInvitationById(id int) (Invitation, error) {
+
+	invitation, err := scanInvitation(DB.QueryRow("SELECT "+invitationColumns+" FROM invitations WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Invitation{}, nil
+		}
+		return Invitation{}, err
+	}
+	return invitation, nil
+}
+
+// GetInvitationByToken checks the signature and that the invitation can still
+// be answered.
+func GetInvitationByToken(token string) (Invitation, error) {
+
+	id, err := parseInvitationToken(token)
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	invitation, err := GetInvitationById(id)
+	if err != nil {
+		return Invitation{}, err
+	}
+	if invitation.ID == 0 || invitation.Status == InvitationRevoked || invitation.Status == InvitationExpired {
+		return Invitation{}, ErrInvalidInvitation
+	}
+	return invitation, nil
+}
+
+func GetInvitationsByPicnic(picnicID int) ([]Invitation, error) {
+
+	rows, err := DB.Query("SELECT "+invitationColumns+" FROM invitations WHERE picnic_id = ? ORDER BY id", picnicID)
+	invitations := make([]Invitation, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return make([]Invitation, 0), err
+		}
+		invitations = append(invitations, invitation)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Invitation, 0), err
+	}
+
+	return invitations, err
+}
+
+func RevokeInvitation(invitationID int, picnicID int) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE invitations SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND picnic_id = ? AND revoked_at IS NULL", invitationID, picnicID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("invitation %d not found", invitationID)
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
+
+func checkInvitee(invitation Invitation, userID int) error {
+	if invitation.UserID != 0 && invitation.UserID != userID {
+		return i18n.Errorf("this invitation is for another user")
+	}
+	if invitation.UserID != 0 && invitation.Status != InvitationPending {
+		return i18n.Errorf("this invitation was already %s", invitation.Status)
+	}
+	return nil
+}
+
+// AcceptInvitation adds the user through AddUserToPicnic, so a full picnic
+// puts them on the waitlist like any other addition.
+func AcceptInvitation(token string, userID int) (Invitation, error) {
+
+	invitation, err := GetInvitationByToken(token)
+	if err != nil {
+		return Invitation{}, err
+	}
+	if err = checkInvitee(invitation, userID); err != nil {
+		return Invitation{}, err
+	}
+
+	_, err = AddUserToPicnic(userID, invitation.PicnicID)
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	if invitation.UserID != 0 {
+		_, err = DB.Exec("UPDATE invitations SET status = ? WHERE id = ?", InvitationAccepted, invitation.ID)
+		if err != nil {
+			return Invitation{}, err
+		}
+		invitation.Status = InvitationAccepted
+	}
+	return invitation, nil
+}
+
+// DeclineInvitation only records anything for invitations sent to a user, an
+// open link has nobody to decline on behalf of.
+func DeclineInvitation(token string, userID int) (Invitation, error) {
+
+	invitation, err := GetInvitationByToken(token)
+	if err != nil {
+		return Invitation{}, err
+	}
+	if err = checkInvitee(invitation, userID); err != nil {
+		return Invitation{}, err
+	}
+
+	if invitation.UserID != 0 {
+		_, err = DB.Exec("UPDATE invitations SET status = ? WHERE id = ?", InvitationDeclined, invitation.ID)
+		if err != nil {
+			return Invitation{}, err
+		}
+		invitation.Status = InvitationDeclined
+	}
+	return invitation, nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Los tests que tocan la base comparten una temporal, cada uno crea sus
+// propios usuarios y picnics
+func TestMain(m *testing.M) {
+
+	PasswordCost = bcrypt.MinCost
+
+	dir, err := os.MkdirTemp("", "picnic-models-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := OpenDatabase(filepath.Join(dir, "test.db")); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	DB.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+var testUsers int64
+
+func newTestUser(t *testing.T, name string) User {
+	t.Helper()
+
+	name = fmt.Sprintf("%s-%d", name, atomic.AddInt64(&testUsers, 1))
+	user, err := RegisterUser(User{Name: name, Email: name + "@example.com"}, "secretpw1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const CREATE_OUTBOX_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS outbox (
+  id               INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  to_address       VARCHAR NOT NULL,
+  subject          VARCHAR NOT NULL,
+  text_body        TEXT NOT NULL,
+  html_body        TEXT NOT NULL,
+  attempts         INTEGER NOT NULL DEFAULT 0,
+  last_error       VARCHAR NOT NULL DEFAULT '',
+  next_attempt_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  sent_at          DATETIME,
+  created_at       DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS index_outbox_on_next_attempt_at ON outbox (sent_at, next_attempt_at);
+
+`
+
+// Despues de estos intentos el mail queda en el outbox con su ultimo error
+const MaxOutboxAttempts = 8
+
+type OutboxMessage struct {
+	ID        int    `json:"id"`
+	To        string `json:"to"`
+	Subject   string `json:"subject"`
+	TextBody  string `json:"text_body"`
+	HTMLBody  string `json:"html_body"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error"`
+}
+
+func EnqueueEmail(message OutboxMessage) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO outbox (to_address, subject, text_body, html_body) VALUES (?, ?, ?, ?)")
+
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.Exec(message.To, message.Subject, message.TextBody, message.HTMLBody)
+
+	if err != nil {
+		return false, err
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
+
+// GetDueEmails returns unsent messages whose retry time has come.
+func GetDueEmails(limit int) ([]OutboxMessage, error) {
+
+	rows, err := DB.Query("SELECT id, to_address, subject, text_body, html_body, attempts, last_error FROM outbox WHERE sent_at IS NULL AND attempts < ? AND next_attempt_at <= datetime('now') ORDER BY id LIMIT ?", MaxOutboxAttempts, limit)
+	messages := make([]OutboxMessage, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		message := OutboxMessage{}
+		err := rows.Scan(&message.ID, &message.To, &message.Subject, &message.TextBody, &message.HTMLBody, &message.Attempts, &message.LastError)
+		if err != nil {
+			return make([]OutboxMessage, 0), err
+		}
+		messages = append(messages, message)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]OutboxMessage, 0), err
+	}
+
+	return messages, err
+}
+
+func MarkEmailSent(id int) error {
+	_, err := DB.Exec("UPDATE outbox SET sent_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = '' WHERE id = ?", id)
+	return err
+}
+
+// MarkEmailFailed schedules the next try with exponential backoff: 1, 2, 4...
+// minutes after each failure.
+func MarkEmailFailed(message OutboxMessage, sendErr error) error {
+	backoff := time.Minute << uint(message.Attempts)
+	next := time.Now().UTC().Add(backoff).Format(sqliteTimeFormat)
+	_, err := DB.Exec("UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?", sendErr.Error(), next, message.ID)
+	return err
+}
+
+func GetOutboxMessageById(id int) (OutboxMessage, error) {
+
+	message := OutboxMessage{}
+	err := DB.QueryRow("SELECT id, to_address, subject, text_body, html_body, attempts, last_error FROM outbox WHERE id = ?", id).Scan(&message.ID, &message.To, &message.Subject, &message.TextBody, &message.HTMLBody, &message.Attempts, &message.LastError)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return OutboxMessage{}, nil
+		}
+		return OutboxMessage{}, err
+	}
+	return message, nil
+}
@@ -4,6 +4,10 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"server/i18n"
+	"strconv"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -13,6 +17,9 @@ const CREATE_TABLE_STATEMENTS_SQL = `
 CREATE TABLE IF NOT EXISTS users (
   id          INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
   name        VARCHAR UNIQUE NOT NULL,
+  password_hash VARCHAR,
+  email       VARCHAR NOT NULL DEFAULT '',
+  locale      VARCHAR NOT NULL DEFAULT '',
   created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
@@ -24,14 +31,19 @@ CREATE TABLE IF NOT EXISTS picnics (
   name         VARCHAR NOT NULL,
   location     VARCHAR NOT NULL,
   date         DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  capacity     INTEGER NOT NULL DEFAULT 0,
+  created_by   INTEGER,
   created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+  updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
 );
 
 CREATE TABLE IF NOT EXISTS users_picnics (
   id       INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
   user_id  INTEGER,
   picnic_id INTEGER,
+  status   VARCHAR NOT NULL DEFAULT 'attending',
+  role     VARCHAR NOT NULL DEFAULT 'attendee',
   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
   FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE
 );
@@ -51,30 +63,64 @@ CREATE TABLE IF NOT EXISTS contributions (
 	picnic_id    INTEGER,
 	food_item_id INTEGER,
 	quantity   INTEGER NOT NULL,
+	paid_by      INTEGER,
+	amount_paid  INTEGER NOT NULL DEFAULT 0,
+	version      INTEGER NOT NULL DEFAULT 1,
 	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
   	FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE,
-	FOREIGN KEY (food_item_id) REFERENCES food_items(id) ON DELETE CASCADE
+	FOREIGN KEY (food_item_id) REFERENCES food_items(id) ON DELETE CASCADE,
+	FOREIGN KEY (paid_by) REFERENCES users(id) ON DELETE SET NULL
 );
 
 `
 
 // A contribution le paso el id de la persona y del picnic
+// Capacity 0 significa sin limite
 type Picnic struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	Location string `json:"location"`
-	Date     string `json:"date"`
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Location  string `json:"location"`
+	Date      string `json:"date"`
+	Capacity  int    `json:"capacity"`
+	CreatedBy int    `json:"created_by"`
 }
 
+// Email es opcional, sin email no se mandan notificaciones. No sale en el
+// JSON de un User, solo el propio usuario lo ve a traves de Account. Locale
+// es el idioma elegido ("en", "es"), vacio sigue al Accept-Language.
 type User struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"-"`
+	Locale string `json:"locale,omitempty"`
+}
+
+// Account is a user as seen by themselves, with the email. The API reads
+// and answers accounts where the user is the caller.
+type Account struct {
+	User
+	Email string `json:"email,omitempty"`
+}
+
+func AccountOf(user User) Account {
+	return Account{user, user.Email}
 }
 
+// ToUser moves the email back into the User
+func (a Account) ToUser() User {
+	user := a.User
+	user.Email = a.Email
+	return user
+}
+
+// WaitlistPosition is 1-based and only set while Status is waitlisted
 type UserPicnic struct {
-	ID       int `json:"id"`
-	UserID   int `json:"user_id"`
-	PicnicID int `json:"picnic_id"`
+	ID               int    `json:"id"`
+	UserID           int    `json:"user_id"`
+	PicnicID         int    `json:"picnic_id"`
+	Status           string `json:"status"`
+	Role             string `json:"role"`
+	WaitlistPosition int    `json:"waitlist_position,omitempty"`
 }
 
 type FoodItem struct {
@@ -84,18 +130,31 @@ type FoodItem struct {
 	Measure string `json:"measure"`
 }
 
+// AmountPaid va en centavos; PaidBy es quien pago (si es 0 se toma UserID).
+// Version sube con cada update, ver UpdateContributionAtVersion.
 type Contribution struct {
 	ID         int `json:"id"`
 	UserID     int `json:"user_id"`
 	PicnicID   int `json:"picnic_id"`
 	FoodItemID int `json:"food_item_id"`
 	Quantity   int `json:"quantity"`
+	PaidBy     int `json:"paid_by"`
+	AmountPaid int `json:"amount_paid"`
+	Version    int `json:"version"`
 }
 
 var DB *sql.DB
 
 func ConnectDatabase() error {
-	db, err := sql.Open("sqlite3", "./models/data.db")
+	return OpenDatabase("./models/data.db")
+}
+
+// OpenDatabase is ConnectDatabase with another file, the tests use a
+// temporary one
+func OpenDatabase(path string) error {
+	// con varios requests a la vez sqlite devuelve SQLITE_BUSY, se espera
+	// hasta 5s por el lock en vez de fallar
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
 	if err != nil {
 		return err
 	}
@@ -111,19 +170,94 @@ func createTables() {
 		log.Fatal("Error during table creation SQL statements")
 		return
 	}
+
+	// columns added after the first release, older data.db files don't have them
+	addColumnIfMissing("contributions", "paid_by", "INTEGER REFERENCES users(id) ON DELETE SET NULL")
+	addColumnIfMissing("contributions", "amount_paid", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfMissing("contributions", "version", "INTEGER NOT NULL DEFAULT 1")
+	addColumnIfMissing("picnics", "capacity", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfMissing("users_picnics", "status", "VARCHAR NOT NULL DEFAULT 'attending'")
+	addColumnIfMissing("picnics", "created_by", "INTEGER REFERENCES users(id) ON DELETE SET NULL")
+	addColumnIfMissing("users_picnics", "role", "VARCHAR NOT NULL DEFAULT 'attendee'")
+	addColumnIfMissing("users", "password_hash", "VARCHAR")
+	addColumnIfMissing("users", "email", "VARCHAR NOT NULL DEFAULT ''")
+	addColumnIfMissing("users", "locale", "VARCHAR NOT NULL DEFAULT ''")
+
+	// tablas de cada feature, cada archivo del paquete trae las suyas
+	for _, statements := range []string{
+		CREATE_SETTLEMENTS_TABLE_SQL,
+		CREATE_SESSIONS_TABLE_SQL,
+		CREATE_API_TOKENS_TABLE_SQL,
+		CREATE_SECRETS_TABLE_SQL,
+		CREATE_INVITATIONS_TABLE_SQL,
+		CREATE_OUTBOX_TABLE_SQL,
+		CREATE_REMINDERS_TABLE_SQL,
+		CREATE_WEBHOOKS_TABLES_SQL,
+		CREATE_PICNIC_EVENTS_TABLE_SQL,
+		CREATE_COMMENTS_TABLES_SQL,
+		CREATE_POLLS_TABLES_SQL,
+		CREATE_AVAILABILITY_TABLE_SQL,
+		CREATE_GEAR_TABLES_SQL,
+		CREATE_INVENTORY_TABLES_SQL,
+		CREATE_IMAGES_TABLE_SQL,
+	} {
+		_, err = DB.Exec(statements)
+		if err != nil {
+			log.Fatalf("Error during table creation SQL statements: %v", err)
+			return
+		}
+	}
+
+	adminID, _ := strconv.Atoi(os.Getenv("PICNIC_ADMIN_ID"))
+	backfilled, err := backfillOwners(adminID)
+	if err != nil {
+		log.Fatalf("Error giving owners to picnics: %v", err)
+	}
+	if backfilled > 0 {
+		log.Printf("gave an owner to %d picnics without one", backfilled)
+	}
 }
 
-func GetPicnicById(id int) (Picnic, error) {
+func addColumnIfMissing(table string, column string, definition string) {
+	rows, err := DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	found := false
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		err = rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if name == column {
+			found = true
+		}
+	}
+	rows.Close()
 
-	stmt, err := DB.Prepare("SELECT id, name, location, date from picnics WHERE id = ?")
+	if found {
+		return
+	}
 
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	if err != nil {
-		return Picnic{}, err
+		log.Fatal(err)
 	}
+}
+
+func GetPicnicById(id int) (Picnic, error) {
+	return getPicnic(DB, id)
+}
+
+func getPicnic(db querier, id int) (Picnic, error) {
 
 	picnic := Picnic{}
 
-	sqlErr := stmt.QueryRow(id).Scan(&picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date)
+	sqlErr := db.QueryRow("SELECT id, name, location, date, capacity, COALESCE(created_by, 0) from picnics WHERE id = ?", id).Scan(&picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date, &picnic.Capacity, &picnic.CreatedBy)
 
 	if sqlErr != nil {
 		if sqlErr == sql.ErrNoRows {
@@ -136,7 +270,7 @@ func GetPicnicById(id int) (Picnic, error) {
 
 func GetPicnics() ([]Picnic, error) {
 
-	rows, err := DB.Query("SELECT id, name, location, date from picnics")
+	rows, err := DB.Query("SELECT id, name, location, date, capacity, COALESCE(created_by, 0) from picnics")
 	picnics := make([]Picnic, 0)
 	if err != nil {
 		return picnics, err
@@ -144,7 +278,7 @@ func GetPicnics() ([]Picnic, error) {
 
 	for rows.Next() {
 		picnic := Picnic{}
-		err = rows.Scan(&picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date)
+		err = rows.Scan(&picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date, &picnic.Capacity, &picnic.CreatedBy)
 
 		if err != nil {
 			return make([]Picnic, 0), err
@@ -162,29 +296,49 @@ func GetPicnics() ([]Picnic, error) {
 	return picnics, err
 }
 
-func CreatePicnic(newPicnic Picnic) (bool, error) {
+// CreatePicnic makes CreatedBy the owner of the new picnic and returns its id.
+func CreatePicnic(newPicnic Picnic) (int, error) {
 
 	tx, err := DB.Begin()
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	stmt, err := tx.Prepare("INSERT INTO picnics (name, location, date) VALUES (?, ?, ?)")
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO picnics (name, location, date, capacity, created_by) VALUES (?, ?, ?, ?, ?)")
 
 	if err != nil {
-		return false, err
+		return 0, err
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(newPicnic.Name, newPicnic.Location, newPicnic.Date)
+	var createdBy sql.NullInt64
+	if newPicnic.CreatedBy != 0 {
+		createdBy = sql.NullInt64{Int64: int64(newPicnic.CreatedBy), Valid: true}
+	}
+
+	result, err := stmt.Exec(newPicnic.Name, newPicnic.Location, newPicnic.Date, newPicnic.Capacity, createdBy)
 
 	if err != nil {
-		return false, err
+		return 0, err
+	}
+
+	picnicID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if createdBy.Valid {
+		_, err = tx.Exec("INSERT INTO users_picnics (user_id, picnic_id, status, role) VALUES (?, ?, ?, ?)", newPicnic.CreatedBy, picnicID, MembershipAttending, RoleOwner)
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	tx.Commit()
 
-	return true, nil
+	return int(picnicID), nil
 }
 
 func UpdatePicnic(updatedPicnic Picnic, idToUpdate int) (bool, error) {
@@ -193,26 +347,52 @@ func UpdatePicnic(updatedPicnic Picnic, idToUpdate int) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	defer tx.Rollback()
 
-	stmt, err := tx.Prepare("UPDATE picnics SET name = ?, location = ?, date = ? WHERE id = ?")
+	err = updatePicnic(tx, updatedPicnic, idToUpdate)
 
 	if err != nil {
 		return false, err
 	}
 
-	defer stmt.Close()
+	tx.Commit()
+
+	return true, nil
+}
+
+func updatePicnic(tx *sql.Tx, updatedPicnic Picnic, idToUpdate int) error {
 
-	_, err = stmt.Exec(updatedPicnic.Name, updatedPicnic.Location, updatedPicnic.Date, idToUpdate)
+	_, err := tx.Exec("UPDATE picnics SET name = ?, location = ?, date = ?, capacity = ? WHERE id = ?", updatedPicnic.Name, updatedPicnic.Location, updatedPicnic.Date, updatedPicnic.Capacity, idToUpdate)
 
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	tx.Commit()
+	// si subio la capacidad entran los primeros de la lista de espera
+	return promoteWaitlisted(tx, idToUpdate)
+}
 
-	return true, nil
+// picnicDependents are deleted with the picnic, children before parents.
+// Foreign keys are off in sqlite unless asked for and older data.db files
+// may not satisfy them, so the ON DELETE CASCADEs in the schema never run.
+var picnicDependents = []string{
+	"DELETE FROM comment_mentions WHERE comment_id IN (SELECT id FROM comments WHERE picnic_id = ?)",
+	"DELETE FROM comments WHERE picnic_id = ?",
+	"DELETE FROM poll_votes WHERE option_id IN (SELECT poll_options.id FROM poll_options INNER JOIN polls ON polls.id = poll_options.poll_id WHERE polls.picnic_id = ?)",
+	"DELETE FROM poll_options WHERE poll_id IN (SELECT id FROM polls WHERE picnic_id = ?)",
+	"DELETE FROM polls WHERE picnic_id = ?",
+	"DELETE FROM gear_assignments WHERE picnic_id = ?",
+	"DELETE FROM inventory_reservations WHERE picnic_id = ?",
+	"DELETE FROM invitations WHERE picnic_id = ?",
+	"DELETE FROM reminders_sent WHERE picnic_id = ?",
+	"DELETE FROM settlements WHERE picnic_id = ?",
+	"DELETE FROM images WHERE picnic_id = ?",
+	"DELETE FROM contributions WHERE picnic_id = ?",
+	"DELETE FROM users_picnics WHERE picnic_id = ?",
 }
 
+// DeletePicnic deletes the picnic and every row that belongs to it. The
+// files of its images are left to the caller.
 func DeletePicnic(picnicId int) (bool, error) {
 
 	tx, err := DB.Begin()
@@ -221,15 +401,15 @@ func DeletePicnic(picnicId int) (bool, error) {
 		return false, err
 	}
 
-	stmt, err := DB.Prepare("DELETE from picnics where id = ?")
+	defer tx.Rollback()
 
-	if err != nil {
-		return false, err
+	for _, statement := range picnicDependents {
+		if _, err = tx.Exec(statement, picnicId); err != nil {
+			return false, err
+		}
 	}
 
-	defer stmt.Close()
-
-	_, err = stmt.Exec(picnicId)
+	_, err = tx.Exec("DELETE from picnics where id = ?", picnicId)
 
 	if err != nil {
 		return false, err
@@ -246,7 +426,7 @@ func CreateUser(newUser User) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	stmt, err := tx.Prepare("INSERT INTO users (name) VALUES (?)")
+	stmt, err := tx.Prepare("INSERT INTO users (name, email) VALUES (?, ?)")
 
 	if err != nil {
 		return false, err
@@ -254,7 +434,7 @@ func CreateUser(newUser User) (bool, error) {
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(newUser.Name)
+	_, err = stmt.Exec(newUser.Name, newUser.Email)
 
 	if err != nil {
 		return false, err
@@ -267,7 +447,7 @@ func CreateUser(newUser User) (bool, error) {
 
 func GetUserById(id int) (User, error) {
 
-	stmt, err := DB.Prepare("SELECT id, name FROM users WHERE id = ?")
+	stmt, err := DB.Prepare("SELECT id, name, email, locale FROM users WHERE id = ?")
 
 	if err != nil {
 		return User{}, err
@@ -275,7 +455,7 @@ func GetUserById(id int) (User, error) {
 
 	user := User{}
 
-	sqlErr := stmt.QueryRow(id).Scan(&user.ID, &user.Name)
+	sqlErr := stmt.QueryRow(id).Scan(&user.ID, &user.Name, &user.Email, &user.Locale)
 
 	if sqlErr != nil {
 		if sqlErr == sql.ErrNoRows {
@@ -288,7 +468,7 @@ func GetUserById(id int) (User, error) {
 
 func GetUsers() ([]User, error) {
 
-	rows, err := DB.Query("SELECT id, name FROM users")
+	rows, err := DB.Query("SELECT id, name, email, locale FROM users")
 	users := make([]User, 0)
 	if err != nil {
 		return users, err
@@ -296,7 +476,7 @@ func GetUsers() ([]User, error) {
 
 	for rows.Next() {
 		user := User{}
-		err = rows.Scan(&user.ID, &user.Name)
+		err = rows.Scan(&user.ID, &user.Name, &user.Email, &user.Locale)
 
 		if err != nil {
 			return make([]User, 0), err
@@ -316,12 +496,16 @@ func GetUsers() ([]User, error) {
 
 func UpdateUser(updatedUser User, idToUpdate int) (bool, error) {
 
+	if updatedUser.Locale != "" && !i18n.IsSupported(updatedUser.Locale) {
+		return false, i18n.Errorf("locale must be one of %s", strings.Join(i18n.Supported, ", "))
+	}
+
 	tx, err := DB.Begin()
 	if err != nil {
 		return false, err
 	}
 
-	stmt, err := tx.Prepare("UPDATE users SET name = ? WHERE id = ?")
+	stmt, err := tx.Prepare("UPDATE users SET name = ?, email = ?, locale = ? WHERE id = ?")
 
 	if err != nil {
 		return false, err
@@ -329,7 +513,7 @@ func UpdateUser(updatedUser User, idToUpdate int) (bool, error) {
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(updatedUser.Name, idToUpdate)
+	_, err = stmt.Exec(updatedUser.Name, updatedUser.Email, updatedUser.Locale, idToUpdate)
 
 	if err != nil {
 		return false, err
@@ -340,13 +524,56 @@ func UpdateUser(updatedUser User, idToUpdate int) (bool, error) {
 	return true, nil
 }
 
+// AddUserToPicnic puts the user on the waitlist when the picnic is full. A user
+// who had declined can join again, they go through the same capacity check.
 func AddUserToPicnic(userID int, picnicID int) (bool, error) {
 
 	tx, err := DB.Begin()
 	if err != nil {
 		return false, err
 	}
-	stmt, err := tx.Prepare("INSERT INTO users_picnics (user_id, picnic_id) VALUES (?, ?)")
+	defer tx.Rollback()
+
+	var capacity int
+	err = tx.QueryRow("SELECT capacity FROM picnics WHERE id = ?", picnicID).Scan(&capacity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, i18n.Errorf("picnic %d not found", picnicID)
+		}
+		return false, err
+	}
+
+	var existingID int
+	var existingStatus string
+	err = tx.QueryRow("SELECT id, status FROM users_picnics WHERE user_id = ? AND picnic_id = ?", userID, picnicID).Scan(&existingID, &existingStatus)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if err == nil && existingStatus != MembershipDeclined {
+		return false, i18n.Errorf("user %d is already %s for picnic %d", userID, existingStatus, picnicID)
+	}
+
+	status := MembershipAttending
+	if capacity > 0 {
+		var attending int
+		err = tx.QueryRow("SELECT COUNT(*) FROM users_picnics WHERE picnic_id = ? AND status = ?", picnicID, MembershipAttending).Scan(&attending)
+		if err != nil {
+			return false, err
+		}
+		if attending >= capacity {
+			status = MembershipWaitlisted
+		}
+	}
+
+	// al volver se borra la fila vieja para que quede al final de la lista de espera
+	if existingID != 0 {
+		_, err = tx.Exec("DELETE FROM users_picnics WHERE id = ?", existingID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO users_picnics (user_id, picnic_id, status) VALUES (?, ?, ?)")
 
 	if err != nil {
 		return false, err
@@ -354,7 +581,7 @@ func AddUserToPicnic(userID int, picnicID int) (bool, error) {
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(userID, picnicID)
+	_, err = stmt.Exec(userID, picnicID, status)
 
 	if err != nil {
 		return false, err
@@ -367,7 +594,7 @@ func AddUserToPicnic(userID int, picnicID int) (bool, error) {
 
 func GetUsersByPicnic(picnicId int) ([]User, error) {
 	// Select the necessary data to create a user obj by picnic id from tables users and picnics
-	rows, err := DB.Query("SELECT users.id, users.name FROM users INNER JOIN users_picnics ON users.id = users_picnics.user_id WHERE users_picnics.picnic_id = ?", picnicId)
+	rows, err := DB.Query("SELECT users.id, users.name, users.email, users.locale FROM users INNER JOIN users_picnics ON users.id = users_picnics.user_id WHERE users_picnics.picnic_id = ? AND users_picnics.status = ?", picnicId, MembershipAttending)
 	users := make([]User, 0)
 	if err != nil {
 		return nil, err
@@ -375,7 +602,7 @@ func GetUsersByPicnic(picnicId int) ([]User, error) {
 
 	for rows.Next() {
 		user := User{}
-		err := rows.Scan(&user.ID, &user.Name)
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Locale)
 		if err != nil {
 			return make([]User, 0), err
 		}
@@ -392,7 +619,7 @@ func GetUsersByPicnic(picnicId int) ([]User, error) {
 }
 
 func GetPicnicsByUser(userId int) ([]Picnic, error) {
-	rows, err := DB.Query("SELECT picnics.id,  picnics.name, picnics.location, picnics.date FROM picnics INNER JOIN users_picnics ON picnics.id = picnic_id WHERE user_id = ?", userId)
+	rows, err := DB.Query("SELECT picnics.id,  picnics.name, picnics.location, picnics.date, picnics.capacity, COALESCE(picnics.created_by, 0) FROM picnics INNER JOIN users_picnics ON picnics.id = picnic_id WHERE user_id = ? AND status != ?", userId, MembershipDeclined)
 	picnics := make([]Picnic, 0)
 	if err != nil {
 		return nil, err
@@ -400,7 +627,7 @@ func GetPicnicsByUser(userId int) ([]Picnic, error) {
 
 	for rows.Next() {
 		picnic := Picnic{}
-		err := rows.Scan(&picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date)
+		err := rows.Scan(&picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date, &picnic.Capacity, &picnic.CreatedBy)
 
 		if err != nil {
 			return make([]Picnic, 0), err
@@ -415,6 +642,30 @@ func GetPicnicsByUser(userId int) ([]Picnic, error) {
 	return picnics, err
 }
 
+func GetSettlementsByUser(userId int) ([]Settlement, error) {
+	rows, err := DB.Query("SELECT id, COALESCE(picnic_id, 0), from_user_id, to_user_id, amount, created_at FROM settlements WHERE from_user_id = ? OR to_user_id = ? ORDER BY created_at DESC, id DESC", userId, userId)
+	settlements := make([]Settlement, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		settlement := Settlement{}
+		err := rows.Scan(&settlement.ID, &settlement.PicnicID, &settlement.FromUserID, &settlement.ToUserID, &settlement.Amount, &settlement.CreatedAt)
+
+		if err != nil {
+			return make([]Settlement, 0), err
+		}
+		settlements = append(settlements, settlement)
+	}
+	err = rows.Err()
+	if err != nil {
+		return make([]Settlement, 0), err
+	}
+
+	return settlements, err
+}
+
 func CreateFoodItem(newFoodItem FoodItem) (bool, error) {
 	tx, err := DB.Begin()
 	if err != nil {
@@ -519,33 +770,60 @@ func UpdateFoodItem(updatedFoodItem FoodItem, idToUpdate int) (bool, error) {
 	return true, nil
 }
 
-func CreateContribution(newContribution Contribution) (bool, error) {
+func CreateContribution(newContribution Contribution) (int, error) {
 	tx, err := DB.Begin()
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	stmt, err := tx.Prepare("INSERT INTO contributions (user_id, picnic_id, food_item_id, quantity) VALUES (?, ?, ?, ?)")
+	stmt, err := tx.Prepare("INSERT INTO contributions (user_id, picnic_id, food_item_id, quantity, paid_by, amount_paid) VALUES (?, ?, ?, ?, ?, ?)")
 
 	if err != nil {
-		return false, err
+		return 0, err
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(newContribution.UserID, newContribution.PicnicID, newContribution.FoodItemID, newContribution.Quantity)
+	result, err := stmt.Exec(newContribution.UserID, newContribution.PicnicID, newContribution.FoodItemID, newContribution.Quantity, payerOf(newContribution), newContribution.AmountPaid)
 
 	if err != nil {
-		return false, err
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
 	}
 
 	tx.Commit()
 
-	return true, nil
+	return int(id), nil
+}
+
+func GetContributionById(id int) (Contribution, error) {
+
+	stmt, err := DB.Prepare("SELECT id, user_id, picnic_id, food_item_id, quantity, COALESCE(paid_by, 0), amount_paid, version from contributions WHERE id = ?")
+
+	if err != nil {
+		return Contribution{}, err
+	}
+
+	contribution := Contribution{}
+
+	sqlErr := stmt.QueryRow(id).Scan(&contribution.ID, &contribution.UserID, &contribution.PicnicID, &contribution.FoodItemID, &contribution.Quantity, &contribution.PaidBy, &contribution.AmountPaid, &contribution.Version)
+
+	if sqlErr != nil {
+		if sqlErr == sql.ErrNoRows {
+			return Contribution{}, nil
+		}
+		return Contribution{}, sqlErr
+	}
+	return contribution, nil
 }
 
 func GetContributionsOfUserToPicnic(idUser int, idPicnic int) (Contribution, error) {
 
-	stmt, err := DB.Prepare("SELECT id, user_id, picnic_id, food_item_id, quantity from contributions WHERE user_id = ? AND picnic_id = ?")
+	stmt, err := DB.Prepare("SELECT id, user_id, picnic_id, food_item_id, quantity, COALESCE(paid_by, 0), amount_paid, version from contributions WHERE user_id = ? AND picnic_id = ?")
 
 	if err != nil {
 		return Contribution{}, err
@@ -553,7 +831,7 @@ func GetContributionsOfUserToPicnic(idUser int, idPicnic int) (Contribution, err
 
 	contribution := Contribution{}
 
-	sqlErr := stmt.QueryRow(idUser, idPicnic).Scan(&contribution.ID, &contribution.UserID, &contribution.PicnicID, &contribution.FoodItemID, &contribution.Quantity)
+	sqlErr := stmt.QueryRow(idUser, idPicnic).Scan(&contribution.ID, &contribution.UserID, &contribution.PicnicID, &contribution.FoodItemID, &contribution.Quantity, &contribution.PaidBy, &contribution.AmountPaid, &contribution.Version)
 
 	if sqlErr != nil {
 		if sqlErr == sql.ErrNoRows {
@@ -565,9 +843,36 @@ func GetContributionsOfUserToPicnic(idUser int, idPicnic int) (Contribution, err
 
 }
 
+func GetContributionsByUserAndPicnic(idUser int, idPicnic int) ([]Contribution, error) {
+
+	rows, err := DB.Query("SELECT id, user_id, picnic_id, food_item_id, quantity, COALESCE(paid_by, 0), amount_paid, version FROM contributions WHERE user_id = ? AND picnic_id = ?", idUser, idPicnic)
+	contributions := make([]Contribution, 0)
+	if err != nil {
+		return contributions, err
+	}
+
+	for rows.Next() {
+		contribution := Contribution{}
+		err = rows.Scan(&contribution.ID, &contribution.UserID, &contribution.PicnicID, &contribution.FoodItemID, &contribution.Quantity, &contribution.PaidBy, &contribution.AmountPaid, &contribution.Version)
+
+		if err != nil {
+			return make([]Contribution, 0), err
+		}
+
+		contributions = append(contributions, contribution)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Contribution, 0), err
+	}
+	return contributions, err
+}
+
 func GetContributions() ([]Contribution, error) {
 
-	rows, err := DB.Query("SELECT id, user_id, picnic_id, food_item_id, quantity FROM contributions")
+	rows, err := DB.Query("SELECT id, user_id, picnic_id, food_item_id, quantity, COALESCE(paid_by, 0), amount_paid, version FROM contributions")
 	contributions := make([]Contribution, 0)
 	if err != nil {
 		return contributions, err
@@ -575,7 +880,7 @@ func GetContributions() ([]Contribution, error) {
 
 	for rows.Next() {
 		contribution := Contribution{}
-		err = rows.Scan(&contribution.ID, &contribution.UserID, &contribution.PicnicID, &contribution.FoodItemID, &contribution.Quantity)
+		err = rows.Scan(&contribution.ID, &contribution.UserID, &contribution.PicnicID, &contribution.FoodItemID, &contribution.Quantity, &contribution.PaidBy, &contribution.AmountPaid, &contribution.Version)
 
 		if err != nil {
 			return make([]Contribution, 0), err
@@ -599,7 +904,7 @@ func UpdateContribution(updatedContribution Contribution, idToUpdate int) (bool,
 		return false, err
 	}
 
-	stmt, err := tx.Prepare("UPDATE contributions SET user_id = ?, picnic_id = ?, food_item_id = ?, quantity = ? WHERE id = ?")
+	stmt, err := tx.Prepare("UPDATE contributions SET user_id = ?, picnic_id = ?, food_item_id = ?, quantity = ?, paid_by = ?, amount_paid = ?, version = version + 1 WHERE id = ?")
 
 	if err != nil {
 		return false, err
@@ -607,7 +912,7 @@ func UpdateContribution(updatedContribution Contribution, idToUpdate int) (bool,
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(updatedContribution.UserID, updatedContribution.PicnicID, updatedContribution.FoodItemID, updatedContribution.Quantity, idToUpdate)
+	_, err = stmt.Exec(updatedContribution.UserID, updatedContribution.PicnicID, updatedContribution.FoodItemID, updatedContribution.Quantity, payerOf(updatedContribution), updatedContribution.AmountPaid, idToUpdate)
 
 	if err != nil {
 		return false, err
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDeletePicnicTakesItsRows(t *testing.T) {
+
+	owner, friend := newTestUser(t, "owner"), newTestUser(t, "friend")
+	picnicID, err := CreatePicnic(Picnic{Name: "Gone", Date: "2026-11-21 13:00", CreatedBy: owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddUserToPicnic(friend.ID, picnicID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CreateFoodItem(FoodItem{Name: fmt.Sprintf("Empanadas %d", picnicID), Measure: "units"}); err != nil {
+		t.Fatal(err)
+	}
+	foodItems, err := GetFoodItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateContribution(Contribution{UserID: friend.ID, PicnicID: picnicID, FoodItemID: foodItems[len(foodItems)-1].ID, Quantity: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateComment(Comment{PicnicID: picnicID, UserID: owner.ID, Body: "see you @" + friend.Name}); err != nil {
+		t.Fatal(err)
+	}
+	poll, err := CreatePoll(Poll{PicnicID: picnicID, Title: "When", CreatedBy: owner.ID, Options: []PollOption{{Date: "2026-11-21 13:00"}, {Date: "2026-11-28 13:00"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Vote(poll.ID, friend.ID, []PollVote{{OptionID: poll.Options[0].ID, Vote: "yes"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := map[string]string{
+		"memberships":   "SELECT COUNT(*) FROM users_picnics WHERE picnic_id = ?",
+		"contributions": "SELECT COUNT(*) FROM contributions WHERE picnic_id = ?",
+		"comments":      "SELECT COUNT(*) FROM comments WHERE picnic_id = ?",
+		"polls":         "SELECT COUNT(*) FROM polls WHERE picnic_id = ?",
+		"poll options":  "SELECT COUNT(*) FROM poll_options WHERE poll_id = ?",
+		"mentions":      "SELECT COUNT(*) FROM comment_mentions WHERE user_id = ?",
+		"votes":         "SELECT COUNT(*) FROM poll_votes WHERE user_id = ?",
+	}
+	args := map[string]int{"poll options": poll.ID, "mentions": friend.ID, "votes": friend.ID}
+	count := func(name string) int {
+		arg, ok := args[name]
+		if !ok {
+			arg = picnicID
+		}
+		var n int
+		if err := DB.QueryRow(rows[name], arg).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	for name := range rows {
+		if count(name) == 0 {
+			t.Fatalf("no %s to delete", name)
+		}
+	}
+	if _, err := DeletePicnic(picnicID); err != nil {
+		t.Fatal(err)
+	}
+	for name := range rows {
+		if n := count(name); n != 0 {
+			t.Errorf("%d %s left", n, name)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package models
+
+import (
+	"database/sql"
+	"server/i18n"
+	"sort"
+	"strings"
+)
+
+const CREATE_POLLS_TABLES_SQL = `
+
+CREATE TABLE IF NOT EXISTS polls (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  picnic_id   INTEGER NOT NULL,
+  title       VARCHAR NOT NULL,
+  status      VARCHAR NOT NULL DEFAULT 'open',
+  created_by  INTEGER,
+  created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  closed_at   DATETIME,
+  FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE,
+  FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS index_polls_on_picnic_id ON polls (picnic_id);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+  id        INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  poll_id   INTEGER NOT NULL,
+  date      VARCHAR NOT NULL DEFAULT '',
+  location  VARCHAR NOT NULL DEFAULT '',
+  FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+  option_id  INTEGER NOT NULL,
+  user_id    INTEGER NOT NULL,
+  vote       VARCHAR NOT NULL,
+  PRIMARY KEY (option_id, user_id),
+  FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+`
+
+const (
+	PollOpen   = "open"
+	PollClosed = "closed"
+
+	VoteYes   = "yes"
+	VoteMaybe = "maybe"
+	VoteNo    = "no"
+)
+
+// Un option puede traer fecha, lugar o los dos. Al cerrar se elige la mejor
+// fecha y el mejor lugar por separado.
+type PollOption struct {
+	ID       int    `json:"id"`
+	PollID   int    `json:"poll_id"`
+	Date     string `json:"date"`
+	Location string `json:"location"`
+	Yes      int    `json:"yes"`
+	Maybe    int    `json:"maybe"`
+	No       int    `json:"no"`
+	Score    int    `json:"score"`
+}
+
+type Poll struct {
+	ID        int          `json:"id"`
+	PicnicID  int          `json:"picnic_id"`
+	Title     string       `json:"title" binding:"required"`
+	Status    string       `json:"status"`
+	CreatedBy int          `json:"created_by"`
+	Options   []PollOption `json:"options" binding:"required"`
+	CreatedAt string       `json:"created_at"`
+	ClosedAt  string       `json:"closed_at,omitempty"`
+	// solo en polls cerrados
+	WinningDate     int `json:"winning_date_option_id,omitempty"`
+	WinningLocation int `json:"winning_location_option_id,omitempty"`
+}
+
+type PollVote struct {
+	OptionID int    `json:"option_id" binding:"required"`
+	Vote     string `json:"vote" binding:"required"`
+}
+
+// Ranking: yes vale 2 y maybe 1. Empates los gana el que tiene menos no,
+// despues el que tiene mas yes y al final el option mas viejo.
+func rankOptions(options []PollOption) []PollOption {
+
+	ranked := append([]PollOption(nil), options...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.No != b.No {
+			return a.No < b.No
+		}
+		if a.Yes != b.Yes {
+			return a.Yes > b.Yes
+		}
+		return a.ID < b.ID
+	})
+	return ranked
+}
+
+// Winners returns the best option that has a date and the best option that
+// has a location, either can be empty.
+func (p Poll) Winners() (PollOption, PollOption) {
+
+	var date, location PollOption
+	for _, option := range rankOptions(p.Options) {
+		if date.ID == 0 && option.Date != "" {
+			date = option
+		}
+		if location.ID == 0 && option.Location != "" {
+			location = option
+		}
+	}
+	return date, location
+}
+
+func CreatePoll(newPoll Poll) (Poll, error) {
+
+	if strings.TrimSpace(newPoll.Title) == "" {
+		return Poll{}, i18n.Errorf("poll title can't be empty")
+	}
+	if len(newPoll.Options) < 2 {
+		return Poll{}, i18n.Errorf("a poll needs at least two options")
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Poll{}, err
+	}
+	defer tx.Rollback()
+
+	for _, option := range newPoll.Options {
+		if option.Date == "" && option.Location == "" {
+			return Poll{}, i18n.Errorf("every option needs a date or a location")
+		}
+		if option.Date != "" {
+			var valid bool
+			err = tx.QueryRow("SELECT datetime(?) IS NOT NULL", option.Date).Scan(&valid)
+			if err != nil {
+				return Poll{}, err
+			}
+			if !valid {
+				return Poll{}, i18n.Errorf("%q is not a valid date", option.Date)
+			}
+		}
+	}
+
+	result, err := tx.Exec("INSERT INTO polls (picnic_id, title, created_by) VALUES (?, ?, ?)", newPoll.PicnicID, newPoll.Title, nullableID(newPoll.CreatedBy))
+	if err != nil {
+		return Poll{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Poll{}, err
+	}
+
+	for _, option := range newPoll.Options {
+		_, err = tx.Exec("INSERT INTO poll_options (poll_id, date, location) VALUES (?, ?, ?)", id, option.Date, option.Location)
+		if err != nil {
+			return Poll{}, err
+		}
+	}
+
+	tx.Commit()
+
+	return GetPollById(int(id))
+}
+
+const pollColumns = "id, picnic_id, title, status, COALESCE(created_by, 0), created_at, closed_at"
+
+func scanPoll(row scanner) (Poll, error) {
+	poll := Poll{}
+	var closedAt sql.NullString
+	err := row.Scan(&poll.ID, &poll.PicnicID, &poll.Title, &poll.Status, &poll.CreatedBy, &poll.CreatedAt, &closedAt)
+	poll.ClosedAt = closedAt.String
+	return poll, err
+}
+
+// GetPollById returns the poll with its options already tallied
+func GetPollById(id int) (Poll, error) {
+	return getPoll(DB, id)
+}
+
+func getPoll(db querier, id int) (Poll, error) {
+
+	poll, err := scanPoll(db.QueryRow("SELECT "+pollColumns+" FROM polls WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Poll{}, nil
+		}
+		return Poll{}, err
+	}
+
+	return tallyPoll(db, poll)
+}
+
+func GetPollsByPicnic(picnicID int) ([]Poll, error) {
+
+	rows, err := DB.Query("SELECT "+pollColumns+" FROM polls WHERE picnic_id = ? ORDER BY id", picnicID)
+	polls := make([]Poll, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		poll, err := scanPoll(rows)
+		if err != nil {
+			rows.Close()
+			return make([]Poll, 0), err
+		}
+		polls = append(polls, poll)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Poll, 0), err
+	}
+
+	for i := range polls {
+		polls[i], err = tallyPoll(DB, polls[i])
+		if err != nil {
+			return make([]Poll, 0), err
+		}
+	}
+	return polls, nil
+}
+
+func tallyPoll(db querier, poll Poll) (Poll, error) {
+
+	rows, err := db.Query(`SELECT poll_options.id, poll_options.poll_id, poll_options.date, poll_options.location,
+		COALESCE(SUM(poll_votes.vote = 'yes'), 0), COALESCE(SUM(poll_votes.vote = 'maybe'), 0), COALESCE(SUM(poll_votes.vote = 'no'), 0)
+		FROM poll_options LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+		WHERE poll_options.poll_id = ? GROUP BY poll_options.id ORDER BY poll_options.id`, poll.ID)
+	if err != nil {
+		return Poll{}, err
+	}
+	defer rows.Close()
+
+	poll.Options = make([]PollOption, 0)
+	for rows.Next() {
+		option := PollOption{}
+		err := rows.Scan(&option.ID, &option.PollID, &option.Date, &option.Location, &option.Yes, &option.Maybe, &option.No)
+		if err != nil {
+			return Poll{}, err
+		}
+		option.Score = 2*option.Yes + option.Maybe
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return Poll{}, err
+	}
+
+	if poll.Status == PollClosed {
+		date, location := poll.Winners()
+		poll.WinningDate = date.ID
+		poll.WinningLocation = location.ID
+	}
+	return poll, nil
+}
+
+// GetPollVotesOfUser returns option id -> vote for one user
+func GetPollVotesOfUser(pollID int, userID int) (map[int]string, error) {
+
+	rows, err := DB.Query("SELECT poll_votes.option_id, poll_votes.vote FROM poll_votes INNER JOIN poll_options ON poll_options.id = poll_votes.option_id WHERE poll_options.poll_id = ? AND poll_votes.user_id = ?", pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make(map[int]string)
+	for rows.Next() {
+		var optionID int
+		var vote string
+		if err := rows.Scan(&optionID, &vote); err != nil {
+			return nil, err
+		}
+		votes[optionID] = vote
+	}
+	return votes, rows.Err()
+}
+
+// Vote replaces the user's votes on the given options, the other options
+// keep whatever the user voted before.
+func Vote(pollID int, userID int, votes []PollVote) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM polls WHERE id = ?", pollID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, i18n.Errorf("poll %d not found", pollID)
+		}
+		return false, err
+	}
+	if status != PollOpen {
+		return false, i18n.Errorf("poll %d is closed", pollID)
+	}
+
+	for _, vote := range votes {
+		if vote.Vote != VoteYes && vote.Vote != VoteMaybe && vote.Vote != VoteNo {
+			return false, i18n.Errorf("vote must be %s, %s or %s", VoteYes, VoteMaybe, VoteNo)
+		}
+
+		var count int
+		err = tx.QueryRow("SELECT COUNT(*) FROM poll_options WHERE id = ? AND poll_id = ?", vote.OptionID, pollID).Scan(&count)
+		if err != nil {
+			return false, err
+		}
+		if count == 0 {
+			return false, i18n.Errorf("option %d is not part of poll %d", vote.OptionID, pollID)
+		}
+
+		_, err = tx.Exec("INSERT INTO poll_votes (option_id, user_id, vote) VALUES (?, ?, ?) ON CONFLICT (option_id, user_id) DO UPDATE SET vote = excluded.vote", vote.OptionID, userID, vote.Vote)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
+
+// ClosePoll stops the voting and writes the winning date and location into
+// the picnic, both or neither. It returns the closed poll and the picnic as
+// it was before.
+func ClosePoll(pollID int) (Poll, Picnic, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Poll{}, Picnic{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE polls SET status = ?, closed_at = CURRENT_TIMESTAMP WHERE id = ? AND status = ?", PollClosed, pollID, PollOpen)
+	if err != nil {
+		return Poll{}, Picnic{}, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return Poll{}, Picnic{}, err
+	}
+	if affected == 0 {
+		return Poll{}, Picnic{}, i18n.Errorf("poll %d is not open", pollID)
+	}
+
+	poll, err := getPoll(tx, pollID)
+	if err != nil {
+		return Poll{}, Picnic{}, err
+	}
+
+	before, err := getPicnic(tx, poll.PicnicID)
+	if err != nil {
+		return Poll{}, Picnic{}, err
+	}
+
+	picnic := before
+	date, location := poll.Winners()
+	if date.ID != 0 {
+		picnic.Date = date.Date
+	}
+	if location.ID != 0 {
+		picnic.Location = location.Location
+	}
+
+	err = updatePicnic(tx, picnic, poll.PicnicID)
+	if err != nil {
+		return Poll{}, Picnic{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Poll{}, Picnic{}, err
+	}
+
+	return poll, before, nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const CREATE_REMINDERS_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS reminders_sent (
+  id              INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  picnic_id       INTEGER NOT NULL,
+  user_id         INTEGER NOT NULL,
+  offset_minutes  INTEGER NOT NULL,
+  channel         VARCHAR NOT NULL,
+  sent_at         DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (picnic_id, user_id, offset_minutes, channel),
+  FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+`
+
+// GetUpcomingPicnics returns the picnics starting between now and now+within.
+// Dates are compared as UTC.
+func GetUpcomingPicnics(within time.Duration) ([]Picnic, error) {
+
+	rows, err := DB.Query("SELECT id, name, location, date, capacity, COALESCE(created_by, 0) FROM picnics WHERE datetime(date) > datetime('now') AND datetime(date) <= datetime('now', ?) ORDER BY datetime(date)", fmt.Sprintf("+%d minutes", int(within.Minutes())))
+	picnics := make([]Picnic, 0)
+	if err != nil {
+		return picnics, err
+	}
+
+	for rows.Next() {
+		picnic := Picnic{}
+		err = rows.Scan(&picnic.ID, &picnic.Name, &picnic.Location, &picnic.Date, &picnic.Capacity, &picnic.CreatedBy)
+
+		if err != nil {
+			return make([]Picnic, 0), err
+		}
+
+		picnics = append(picnics, picnic)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Picnic, 0), err
+	}
+
+	return picnics, err
+}
+
+// ClaimReminder records the reminder before it goes out and returns false if
+// it was already recorded, so a restart never sends it twice.
+func ClaimReminder(picnicID int, userID int, offset time.Duration, channel string) (bool, error) {
+
+	result, err := DB.Exec("INSERT OR IGNORE INTO reminders_sent (picnic_id, user_id, offset_minutes, channel) VALUES (?, ?, ?, ?)", picnicID, userID, int(offset.Minutes()), channel)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ReleaseReminder undoes ClaimReminder when delivery failed, the next run
+// tries again.
+func ReleaseReminder(picnicID int, userID int, offset time.Duration, channel string) error {
+	_, err := DB.Exec("DELETE FROM reminders_sent WHERE picnic_id = ? AND user_id = ? AND offset_minutes = ? AND channel = ?", picnicID, userID, int(offset.Minutes()), channel)
+	return err
+}
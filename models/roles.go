@@ -0,0 +1,198 @@
+package models
+
+import (
+	"database/sql"
+	"server/i18n"
+)
+
+// Roles de users_picnics
+const (
+	RoleOwner    = "owner"
+	RoleCoHost   = "co-host"
+	RoleAttendee = "attendee"
+)
+
+// GetRole returns "" when the user is not part of the picnic. Declined and
+// waitlisted users keep their row but have no permission until they attend.
+func GetRole(userID int, picnicID int) (string, error) {
+
+	var role, status string
+	err := DB.QueryRow("SELECT role, status FROM users_picnics WHERE user_id = ? AND picnic_id = ?", userID, picnicID).Scan(&role, &status)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	if status != MembershipAttending {
+		return "", nil
+	}
+	return role, nil
+}
+
+// GetRolesOfUser is GetRole for every picnic the user attends, by picnic id
+func GetRolesOfUser(userID int) (map[int]string, error) {
+
+	rows, err := DB.Query("SELECT picnic_id, role FROM users_picnics WHERE user_id = ? AND status = ?", userID, MembershipAttending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make(map[int]string)
+	for rows.Next() {
+		var picnicID int
+		var role string
+		if err := rows.Scan(&picnicID, &role); err != nil {
+			return nil, err
+		}
+		roles[picnicID] = role
+	}
+	return roles, rows.Err()
+}
+
+// backfillOwners gives an owner to picnics from before roles existed, which
+// nobody could edit. The earliest attendee is promoted; a picnic without
+// attendees goes to adminID (PICNIC_ADMIN_ID), when there is one. Returns
+// how many picnics got an owner.
+func backfillOwners(adminID int) (int, error) {
+
+	rows, err := DB.Query("SELECT id FROM picnics WHERE NOT EXISTS (SELECT 1 FROM users_picnics WHERE picnic_id = picnics.id AND role = ?)", RoleOwner)
+	if err != nil {
+		return 0, err
+	}
+	picnicIds := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		picnicIds = append(picnicIds, id)
+	}
+	rows.Close()
+
+	backfilled := 0
+	for _, picnicID := range picnicIds {
+		ok, err := backfillOwner(picnicID, adminID)
+		if err != nil {
+			return backfilled, err
+		}
+		if ok {
+			backfilled++
+		}
+	}
+	return backfilled, nil
+}
+
+func backfillOwner(picnicID int, adminID int) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var membershipID int
+	err = tx.QueryRow("SELECT id FROM users_picnics WHERE picnic_id = ? AND status = ? ORDER BY id LIMIT 1", picnicID, MembershipAttending).Scan(&membershipID)
+
+	switch {
+	case err == nil:
+		_, err = tx.Exec("UPDATE users_picnics SET role = ? WHERE id = ?", RoleOwner, membershipID)
+	case err == sql.ErrNoRows && adminID != 0:
+		// el admin puede estar en lista de espera o haber declinado
+		_, err = tx.Exec("DELETE FROM users_picnics WHERE user_id = ? AND picnic_id = ?", adminID, picnicID)
+		if err == nil {
+			_, err = tx.Exec("INSERT INTO users_picnics (user_id, picnic_id, status, role) VALUES (?, ?, ?, ?)", adminID, picnicID, MembershipAttending, RoleOwner)
+		}
+	case err == sql.ErrNoRows:
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// SharePicnic tells whether both users attend some picnic together
+func SharePicnic(userID int, otherID int) (bool, error) {
+
+	var count int
+	err := DB.QueryRow("SELECT COUNT(*) FROM users_picnics a INNER JOIN users_picnics b ON a.picnic_id = b.picnic_id WHERE a.user_id = ? AND b.user_id = ? AND a.status = ? AND b.status = ?",
+		userID, otherID, MembershipAttending, MembershipAttending).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SetRole only moves people between co-host and attendee, ownership changes
+// go through TransferOwnership.
+func SetRole(userID int, picnicID int, role string) (bool, error) {
+
+	if role != RoleCoHost && role != RoleAttendee {
+		return false, i18n.Errorf("role must be %s or %s", RoleCoHost, RoleAttendee)
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE users_picnics SET role = ? WHERE user_id = ? AND picnic_id = ? AND role != ? AND status != ?", role, userID, picnicID, RoleOwner, MembershipDeclined)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("user %d can't be made %s of picnic %d", userID, role, picnicID)
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
+
+// TransferOwnership hands the picnic to newOwnerID, who must already be
+// attending. The previous owner stays on as co-host.
+func TransferOwnership(picnicID int, newOwnerID int) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM users_picnics WHERE user_id = ? AND picnic_id = ?", newOwnerID, picnicID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, i18n.Errorf("user %d is not part of picnic %d", newOwnerID, picnicID)
+		}
+		return false, err
+	}
+	if status != MembershipAttending {
+		return false, i18n.Errorf("user %d is %s, only attending users can own picnic %d", newOwnerID, status, picnicID)
+	}
+
+	_, err = tx.Exec("UPDATE users_picnics SET role = ? WHERE picnic_id = ? AND role = ?", RoleCoHost, picnicID, RoleOwner)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec("UPDATE users_picnics SET role = ? WHERE picnic_id = ? AND user_id = ?", RoleOwner, picnicID, newOwnerID)
+	if err != nil {
+		return false, err
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+)
+
+const CREATE_SECRETS_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS app_secrets (
+  name        VARCHAR PRIMARY KEY NOT NULL,
+  value       VARCHAR NOT NULL,
+  created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+`
+
+// GetSecret returns the signing key called name, creating a random one the
+// first time. Keeping it in the database means signed links survive restarts.
+func GetSecret(name string) ([]byte, error) {
+
+	var value string
+	err := DB.QueryRow("SELECT value FROM app_secrets WHERE name = ?", name).Scan(&value)
+	if err == nil {
+		return hex.DecodeString(value)
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+
+	// si otro proceso la creo primero nos quedamos con la suya
+	_, err = DB.Exec("INSERT OR IGNORE INTO app_secrets (name, value) VALUES (?, ?)", name, hex.EncodeToString(b))
+	if err != nil {
+		return nil, err
+	}
+	return GetSecret(name)
+}
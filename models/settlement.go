@@ -0,0 +1,398 @@
+package models
+
+import (
+	"database/sql"
+	"math/bits"
+	"server/i18n"
+	"sort"
+	"strings"
+)
+
+const CREATE_SETTLEMENTS_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS settlements (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  picnic_id     INTEGER,
+  from_user_id  INTEGER NOT NULL,
+  to_user_id    INTEGER NOT NULL,
+  amount        INTEGER NOT NULL,
+  created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (picnic_id) REFERENCES picnics(id) ON DELETE CASCADE,
+  FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_settlements_on_picnic_id ON settlements (picnic_id);
+
+`
+
+// Un pago de FromUserID a ToUserID, en centavos. PicnicID es 0 cuando el pago
+// no es de un picnic en particular.
+type Settlement struct {
+	ID         int    `json:"id"`
+	PicnicID   int    `json:"picnic_id"`
+	FromUserID int    `json:"from_user_id"`
+	ToUserID   int    `json:"to_user_id"`
+	Amount     int    `json:"amount"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// Balance is positive when the user is owed money and negative when they owe.
+type Balance struct {
+	UserID  int `json:"user_id"`
+	Paid    int `json:"paid"`
+	Share   int `json:"share"`
+	Balance int `json:"balance"`
+}
+
+type Transfer struct {
+	FromUserID int `json:"from_user_id"`
+	ToUserID   int `json:"to_user_id"`
+	Amount     int `json:"amount"`
+}
+
+type SettleUp struct {
+	PicnicIDs []int      `json:"picnic_ids"`
+	Balances  []Balance  `json:"balances"`
+	Transfers []Transfer `json:"transfers"`
+}
+
+func payerOf(contribution Contribution) int {
+	if contribution.PaidBy != 0 {
+		return contribution.PaidBy
+	}
+	return contribution.UserID
+}
+
+func CreateSettlement(newSettlement Settlement) (bool, error) {
+
+	if newSettlement.Amount <= 0 {
+		return false, i18n.Errorf("amount must be positive")
+	}
+	if newSettlement.FromUserID == newSettlement.ToUserID {
+		return false, i18n.Errorf("a user can't settle with themselves")
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	stmt, err := tx.Prepare("INSERT INTO settlements (picnic_id, from_user_id, to_user_id, amount) VALUES (?, ?, ?, ?)")
+
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	var picnicID sql.NullInt64
+	if newSettlement.PicnicID != 0 {
+		picnicID = sql.NullInt64{Int64: int64(newSettlement.PicnicID), Valid: true}
+	}
+
+	_, err = stmt.Exec(picnicID, newSettlement.FromUserID, newSettlement.ToUserID, newSettlement.Amount)
+
+	if err != nil {
+		return false, err
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
+
+// GetPicnicSettleUp computes balances and transfers for a single picnic. The
+// total paid is split evenly between the picnic's attendees.
+func GetPicnicSettleUp(picnicId int) (SettleUp, error) {
+	return computeSettleUp([]int{picnicId})
+}
+
+// GetSharedSettleUp does the same across every picnic all of userIds attended.
+// Settlements that aren't tied to a picnic are included when both sides are
+// in userIds.
+func GetSharedSettleUp(userIds []int) (SettleUp, error) {
+
+	if len(userIds) == 0 {
+		return SettleUp{}, i18n.Errorf("no users given")
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(userIds)), ",")
+	ids := make([]interface{}, 0, len(userIds))
+	for _, id := range userIds {
+		ids = append(ids, id)
+	}
+
+	rows, err := DB.Query("SELECT picnic_id FROM users_picnics WHERE status = ? AND user_id IN ("+placeholders+") GROUP BY picnic_id HAVING COUNT(DISTINCT user_id) = ?", append(append([]interface{}{MembershipAttending}, ids...), len(userIds))...)
+	if err != nil {
+		return SettleUp{}, err
+	}
+
+	picnicIds := make([]int, 0)
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return SettleUp{}, err
+		}
+		picnicIds = append(picnicIds, id)
+	}
+	rows.Close()
+
+	settleUp, err := computeSettleUp(picnicIds)
+	if err != nil {
+		return SettleUp{}, err
+	}
+
+	// pagos sin picnic entre los usuarios del grupo
+	rows, err = DB.Query("SELECT from_user_id, to_user_id, amount FROM settlements WHERE picnic_id IS NULL AND from_user_id IN ("+placeholders+") AND to_user_id IN ("+placeholders+")", append(ids, ids...)...)
+	if err != nil {
+		return SettleUp{}, err
+	}
+	defer rows.Close()
+
+	balances := balancesByUser(settleUp.Balances)
+	for rows.Next() {
+		var from, to, amount int
+		err = rows.Scan(&from, &to, &amount)
+		if err != nil {
+			return SettleUp{}, err
+		}
+		balanceOf(balances, from).Balance += amount
+		balanceOf(balances, to).Balance -= amount
+	}
+	err = rows.Err()
+	if err != nil {
+		return SettleUp{}, err
+	}
+
+	settleUp.Balances = sortedBalances(balances)
+	settleUp.Transfers = simplifyDebts(settleUp.Balances)
+	return settleUp, nil
+}
+
+func computeSettleUp(picnicIds []int) (SettleUp, error) {
+
+	balances := make(map[int]*Balance)
+
+	for _, picnicId := range picnicIds {
+		users, err := GetUsersByPicnic(picnicId)
+		if err != nil {
+			return SettleUp{}, err
+		}
+
+		paid := make(map[int]int)
+		total := 0
+		rows, err := DB.Query("SELECT COALESCE(paid_by, user_id), amount_paid FROM contributions WHERE picnic_id = ? AND amount_paid > 0", picnicId)
+		if err != nil {
+			return SettleUp{}, err
+		}
+		for rows.Next() {
+			var payer, amount int
+			err = rows.Scan(&payer, &amount)
+			if err != nil {
+				rows.Close()
+				return SettleUp{}, err
+			}
+			paid[payer] += amount
+			total += amount
+		}
+		rows.Close()
+
+		// el resto de la division se reparte de a un centavo
+		if len(users) > 0 {
+			share := total / len(users)
+			remainder := total % len(users)
+			for i, user := range users {
+				userShare := share
+				if i < remainder {
+					userShare++
+				}
+				b := balanceOf(balances, user.ID)
+				b.Share += userShare
+				b.Balance -= userShare
+			}
+		}
+		for payer, amount := range paid {
+			b := balanceOf(balances, payer)
+			b.Paid += amount
+			b.Balance += amount
+		}
+
+		rows, err = DB.Query("SELECT from_user_id, to_user_id, amount FROM settlements WHERE picnic_id = ?", picnicId)
+		if err != nil {
+			return SettleUp{}, err
+		}
+		for rows.Next() {
+			var from, to, amount int
+			err = rows.Scan(&from, &to, &amount)
+			if err != nil {
+				rows.Close()
+				return SettleUp{}, err
+			}
+			balanceOf(balances, from).Balance += amount
+			balanceOf(balances, to).Balance -= amount
+		}
+		rows.Close()
+	}
+
+	sorted := sortedBalances(balances)
+
+	return SettleUp{
+		PicnicIDs: picnicIds,
+		Balances:  sorted,
+		Transfers: simplifyDebts(sorted),
+	}, nil
+}
+
+func balanceOf(balances map[int]*Balance, userId int) *Balance {
+	b, ok := balances[userId]
+	if !ok {
+		b = &Balance{UserID: userId}
+		balances[userId] = b
+	}
+	return b
+}
+
+func balancesByUser(list []Balance) map[int]*Balance {
+	balances := make(map[int]*Balance, len(list))
+	for i := range list {
+		b := list[i]
+		balances[b.UserID] = &b
+	}
+	return balances
+}
+
+func sortedBalances(balances map[int]*Balance) []Balance {
+	list := make([]Balance, 0, len(balances))
+	for _, b := range balances {
+		list = append(list, *b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].UserID < list[j].UserID })
+	return list
+}
+
+// maxExactParties is how many people with a non-zero balance simplifyDebts
+// settles exactly, the search is over every subset of them
+const maxExactParties = 16
+
+// simplifyDebts returns the fewest transfers that settle balances. With n
+// people that aren't even, the minimum is n minus the most groups they can
+// be split into where each group sums to zero: every group of k people is
+// settled with k-1 transfers and no group can do with fewer.
+func simplifyDebts(balances []Balance) []Transfer {
+
+	parties := make([]party, 0)
+	for _, b := range balances {
+		if b.Balance != 0 {
+			parties = append(parties, party{b.UserID, b.Balance})
+		}
+	}
+
+	// con mucha gente se usa el greedy, n-1 transferencias como mucho
+	if len(parties) > maxExactParties {
+		return settleGroup(parties)
+	}
+
+	transfers := make([]Transfer, 0)
+	for _, group := range zeroSumGroups(parties) {
+		transfers = append(transfers, settleGroup(group)...)
+	}
+	return transfers
+}
+
+type party struct {
+	userID int
+	amount int
+}
+
+// zeroSumGroups splits parties into as many zero-sum groups as possible.
+// groups[mask] is the most zero-sum groups the parties in mask can be
+// split into, counting a leftover that doesn't sum to zero as none.
+func zeroSumGroups(parties []party) [][]party {
+
+	n := len(parties)
+	full := 1<<n - 1
+	sums := make([]int, full+1)
+	groups := make([]int, full+1)
+
+	for mask := 1; mask <= full; mask++ {
+		lowest := mask & -mask
+		i := bits.TrailingZeros(uint(lowest))
+		sums[mask] = sums[mask^lowest] + parties[i].amount
+
+		best := 0
+		for j := 0; j < n; j++ {
+			if mask&(1<<j) != 0 && groups[mask^(1<<j)] > best {
+				best = groups[mask^(1<<j)]
+			}
+		}
+		if sums[mask] == 0 {
+			best++
+		}
+		groups[mask] = best
+	}
+
+	// se sacan de a uno siguiendo el optimo, cada vez que lo que queda suma
+	// cero se cierra un grupo
+	result := make([][]party, 0)
+	current := make([]party, 0)
+	for mask := full; mask != 0; {
+		closes := 0
+		if sums[mask] == 0 {
+			closes = 1
+		}
+		for j := 0; j < n; j++ {
+			if mask&(1<<j) != 0 && groups[mask^(1<<j)]+closes == groups[mask] {
+				current = append(current, parties[j])
+				mask ^= 1 << j
+				break
+			}
+		}
+		if sums[mask] == 0 {
+			result = append(result, current)
+			current = make([]party, 0)
+		}
+	}
+	return result
+}
+
+// settleGroup repeatedly matches the largest debtor with the largest
+// creditor. Every transfer evens out at least one of them, so a group of k
+// people that sums to zero needs at most k-1.
+func settleGroup(parties []party) []Transfer {
+
+	debtors := make([]party, 0)
+	creditors := make([]party, 0)
+	for _, p := range parties {
+		if p.amount < 0 {
+			debtors = append(debtors, party{p.userID, -p.amount})
+		} else if p.amount > 0 {
+			creditors = append(creditors, p)
+		}
+	}
+
+	transfers := make([]Transfer, 0)
+	for len(debtors) > 0 && len(creditors) > 0 {
+		sort.SliceStable(debtors, func(i, j int) bool { return debtors[i].amount > debtors[j].amount })
+		sort.SliceStable(creditors, func(i, j int) bool { return creditors[i].amount > creditors[j].amount })
+
+		amount := debtors[0].amount
+		if creditors[0].amount < amount {
+			amount = creditors[0].amount
+		}
+
+		transfers = append(transfers, Transfer{FromUserID: debtors[0].userID, ToUserID: creditors[0].userID, Amount: amount})
+
+		debtors[0].amount -= amount
+		creditors[0].amount -= amount
+		if debtors[0].amount == 0 {
+			debtors = debtors[1:]
+		}
+		if creditors[0].amount == 0 {
+			creditors = creditors[1:]
+		}
+	}
+
+	return transfers
+}
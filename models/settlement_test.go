@@ -0,0 +1,52 @@
+package models
+
+import "testing"
+
+func TestSimplifyDebtsIsMinimal(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		balances []int
+		want     int
+	}{
+		{"even", []int{0, 0}, 0},
+		{"one debt", []int{-5, 5}, 1},
+		{"two creditors", []int{-10, 4, 6}, 2},
+		// el greedy empareja 6 con 5 y termina con 4
+		{"two zero-sum groups", []int{-3, 5, -3, -5, 6}, 3},
+		{"pairs", []int{-1, 1, -2, 2, -3, 3, -4, 4}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			balances := make([]Balance, len(tt.balances))
+			for i, amount := range tt.balances {
+				balances[i] = Balance{UserID: i + 1, Balance: amount}
+			}
+
+			transfers := simplifyDebts(balances)
+			if len(transfers) != tt.want {
+				t.Fatalf("got %d transfers %v, want %d", len(transfers), transfers, tt.want)
+			}
+
+			// las transferencias tienen que dejar a todos en cero
+			left := make(map[int]int)
+			for _, b := range balances {
+				left[b.UserID] = b.Balance
+			}
+			for _, transfer := range transfers {
+				if transfer.Amount <= 0 {
+					t.Errorf("transfer %v is not positive", transfer)
+				}
+				left[transfer.FromUserID] += transfer.Amount
+				left[transfer.ToUserID] -= transfer.Amount
+			}
+			for userID, amount := range left {
+				if amount != 0 {
+					t.Errorf("user %d is left with %d", userID, amount)
+				}
+			}
+		})
+	}
+}
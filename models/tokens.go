@@ -0,0 +1,220 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"server/i18n"
+	"strings"
+	"time"
+)
+
+const CREATE_API_TOKENS_TABLE_SQL = `
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  user_id       INTEGER NOT NULL,
+  name          VARCHAR NOT NULL,
+  token_hash    VARCHAR UNIQUE NOT NULL,
+  scopes        VARCHAR NOT NULL,
+  expires_at    DATETIME,
+  last_used_at  DATETIME,
+  created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revoked_at    DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_api_tokens_on_user_id ON api_tokens (user_id);
+
+`
+
+// admin incluye a todos los demas
+const (
+	ScopePicnicsRead        = "picnics:read"
+	ScopeContributionsWrite = "contributions:write"
+	ScopeAdmin              = "admin"
+)
+
+var Scopes = []string{ScopePicnicsRead, ScopeContributionsWrite, ScopeAdmin}
+
+// prefijo para reconocer los tokens si se filtran en logs o repos
+const apiTokenPrefix = "pat_"
+
+type ApiToken struct {
+	ID         int      `json:"id"`
+	UserID     int      `json:"user_id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+func (t ApiToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateApiToken returns the raw token, it can't be recovered afterwards.
+// A zero expiresIn means the token never expires.
+func CreateApiToken(userID int, name string, scopes []string, expiresIn time.Duration) (string, error) {
+
+	if len(scopes) == 0 {
+		return "", i18n.Errorf("a token needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return "", i18n.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	raw, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	token := apiTokenPrefix + raw
+
+	var expiresAt sql.NullString
+	if expiresIn > 0 {
+		expiresAt = sql.NullString{String: time.Now().UTC().Add(expiresIn).Format(sqliteTimeFormat), Valid: true}
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at) VALUES (?, ?, ?, ?, ?)")
+
+	if err != nil {
+		return "", err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID, name, hashToken(token), strings.Join(scopes, ","), expiresAt)
+
+	if err != nil {
+		return "", err
+	}
+
+	tx.Commit()
+
+	return token, nil
+}
+
+// tokenUseResolution is how stale last_used_at may get
+const tokenUseResolution = time.Minute
+
+// GetApiTokenByToken returns an empty ApiToken for unknown, expired or
+// revoked tokens, and records the use otherwise.
+func GetApiTokenByToken(token string) (ApiToken, error) {
+
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return ApiToken{}, nil
+	}
+
+	apiToken, err := scanApiToken(DB.QueryRow("SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > datetime('now'))", hashToken(token)))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ApiToken{}, nil
+		}
+		return ApiToken{}, err
+	}
+
+	// el uso se anota de a un minuto, no en cada request. Si la base esta
+	// ocupada se pierde ese dato y el request sigue.
+	if lastUsed, err := time.Parse(time.RFC3339, apiToken.LastUsedAt); err == nil && time.Since(lastUsed) < tokenUseResolution {
+		return apiToken, nil
+	}
+	_, err = DB.Exec("UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?", apiToken.ID)
+	if err != nil {
+		log.Printf("tokens: recording use of token %d: %v", apiToken.ID, err)
+	}
+	return apiToken, nil
+}
+
+func GetApiTokensByUser(userID int) ([]ApiToken, error) {
+
+	rows, err := DB.Query("SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY id", userID)
+	tokens := make([]ApiToken, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		token, err := scanApiToken(rows)
+		if err != nil {
+			return make([]ApiToken, 0), err
+		}
+		tokens = append(tokens, token)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]ApiToken, 0), err
+	}
+
+	return tokens, err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanApiToken(row scanner) (ApiToken, error) {
+
+	token := ApiToken{}
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullString
+
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &scopes, &expiresAt, &lastUsedAt, &token.CreatedAt)
+	if err != nil {
+		return ApiToken{}, err
+	}
+
+	token.Scopes = strings.Split(scopes, ",")
+	token.ExpiresAt = expiresAt.String
+	token.LastUsedAt = lastUsedAt.String
+	return token, nil
+}
+
+func RevokeApiToken(tokenID int, userID int) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("token %d not found", tokenID)
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
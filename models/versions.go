@@ -0,0 +1,69 @@
+package models
+
+import (
+	"server/i18n"
+)
+
+// ErrVersionConflict means somebody else saved the row first
+var ErrVersionConflict = i18n.Errorf("contribution was changed by someone else")
+
+// UpdateContributionAtVersion only writes when the stored row is still at
+// version. On a conflict it returns the current row with ErrVersionConflict so
+// the client can rebase its edit.
+func UpdateContributionAtVersion(updatedContribution Contribution, idToUpdate int, version int) (Contribution, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Contribution{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE contributions SET user_id = ?, picnic_id = ?, food_item_id = ?, quantity = ?, paid_by = ?, amount_paid = ?, version = version + 1 WHERE id = ? AND version = ?",
+		updatedContribution.UserID, updatedContribution.PicnicID, updatedContribution.FoodItemID, updatedContribution.Quantity, payerOf(updatedContribution), updatedContribution.AmountPaid, idToUpdate, version)
+	if err != nil {
+		return Contribution{}, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return Contribution{}, err
+	}
+
+	tx.Commit()
+
+	current, err := GetContributionById(idToUpdate)
+	if err != nil {
+		return Contribution{}, err
+	}
+	if affected == 0 {
+		return current, ErrVersionConflict
+	}
+	return current, nil
+}
+
+func GetContributionsByPicnic(picnicID int) ([]Contribution, error) {
+
+	rows, err := DB.Query("SELECT id, user_id, picnic_id, food_item_id, quantity, COALESCE(paid_by, 0), amount_paid, version FROM contributions WHERE picnic_id = ? ORDER BY id", picnicID)
+	contributions := make([]Contribution, 0)
+	if err != nil {
+		return contributions, err
+	}
+
+	for rows.Next() {
+		contribution := Contribution{}
+		err = rows.Scan(&contribution.ID, &contribution.UserID, &contribution.PicnicID, &contribution.FoodItemID, &contribution.Quantity, &contribution.PaidBy, &contribution.AmountPaid, &contribution.Version)
+
+		if err != nil {
+			return make([]Contribution, 0), err
+		}
+
+		contributions = append(contributions, contribution)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Contribution, 0), err
+	}
+	return contributions, err
+}
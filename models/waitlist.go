@@ -0,0 +1,151 @@
+package models
+
+import (
+	"database/sql"
+	"server/i18n"
+)
+
+// Estados de users_picnics
+const (
+	MembershipAttending  = "attending"
+	MembershipWaitlisted = "waitlisted"
+	MembershipDeclined   = "declined"
+)
+
+// promoteWaitlisted moves the oldest waitlisted users to attending until the
+// picnic is full again. It runs inside the caller's transaction.
+func promoteWaitlisted(tx *sql.Tx, picnicID int) error {
+
+	var capacity int
+	err := tx.QueryRow("SELECT capacity FROM picnics WHERE id = ?", picnicID).Scan(&capacity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	var attending int
+	err = tx.QueryRow("SELECT COUNT(*) FROM users_picnics WHERE picnic_id = ? AND status = ?", picnicID, MembershipAttending).Scan(&attending)
+	if err != nil {
+		return err
+	}
+
+	// capacity 0 es sin limite, entran todos
+	openSpots := -1
+	if capacity > 0 {
+		openSpots = capacity - attending
+		if openSpots <= 0 {
+			return nil
+		}
+	}
+
+	_, err = tx.Exec("UPDATE users_picnics SET status = ? WHERE id IN (SELECT id FROM users_picnics WHERE picnic_id = ? AND status = ? ORDER BY id LIMIT ?)", MembershipAttending, picnicID, MembershipWaitlisted, openSpots)
+	return err
+}
+
+func GetMembership(userID int, picnicID int) (UserPicnic, error) {
+
+	stmt, err := DB.Prepare("SELECT id, user_id, picnic_id, status, role FROM users_picnics WHERE user_id = ? AND picnic_id = ?")
+
+	if err != nil {
+		return UserPicnic{}, err
+	}
+
+	defer stmt.Close()
+
+	membership := UserPicnic{}
+
+	sqlErr := stmt.QueryRow(userID, picnicID).Scan(&membership.ID, &membership.UserID, &membership.PicnicID, &membership.Status, &membership.Role)
+
+	if sqlErr != nil {
+		if sqlErr == sql.ErrNoRows {
+			return UserPicnic{}, nil
+		}
+		return UserPicnic{}, sqlErr
+	}
+
+	if membership.Status == MembershipWaitlisted {
+		err = DB.QueryRow("SELECT COUNT(*) FROM users_picnics WHERE picnic_id = ? AND status = ? AND id <= ?", picnicID, MembershipWaitlisted, membership.ID).Scan(&membership.WaitlistPosition)
+		if err != nil {
+			return UserPicnic{}, err
+		}
+	}
+	return membership, nil
+}
+
+func GetWaitlistByPicnic(picnicID int) ([]UserPicnic, error) {
+
+	rows, err := DB.Query("SELECT id, user_id, picnic_id, status, role FROM users_picnics WHERE picnic_id = ? AND status = ? ORDER BY id", picnicID, MembershipWaitlisted)
+	waitlist := make([]UserPicnic, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		membership := UserPicnic{}
+		err := rows.Scan(&membership.ID, &membership.UserID, &membership.PicnicID, &membership.Status, &membership.Role)
+		if err != nil {
+			return make([]UserPicnic, 0), err
+		}
+		membership.WaitlistPosition = len(waitlist) + 1
+		waitlist = append(waitlist, membership)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]UserPicnic, 0), err
+	}
+
+	return waitlist, err
+}
+
+// DeclinePicnic keeps the row so the organizer can see who said no.
+func DeclinePicnic(userID int, picnicID int) (bool, error) {
+	return leavePicnic(userID, picnicID, "UPDATE users_picnics SET status = '"+MembershipDeclined+"' WHERE user_id = ? AND picnic_id = ?")
+}
+
+func RemoveUserFromPicnic(userID int, picnicID int) (bool, error) {
+	return leavePicnic(userID, picnicID, "DELETE FROM users_picnics WHERE user_id = ? AND picnic_id = ?")
+}
+
+func leavePicnic(userID int, picnicID int, query string) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// el picnic no se puede quedar sin dueño
+	var role string
+	err = tx.QueryRow("SELECT role FROM users_picnics WHERE user_id = ? AND picnic_id = ?", userID, picnicID).Scan(&role)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if role == RoleOwner {
+		return false, i18n.Errorf("the owner of picnic %d must transfer ownership before leaving", picnicID)
+	}
+
+	result, err := tx.Exec(query, userID, picnicID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, i18n.Errorf("user %d is not part of picnic %d", userID, picnicID)
+	}
+
+	err = promoteWaitlisted(tx, picnicID)
+	if err != nil {
+		return false, err
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
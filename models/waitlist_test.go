@@ -0,0 +1,47 @@
+package models
+
+import "testing"
+
+func TestLeavingPromotesTheWaitlist(t *testing.T) {
+
+	leaves := map[string]func(userID int, picnicID int) (bool, error){
+		"removed":  RemoveUserFromPicnic,
+		"declined": DeclinePicnic,
+	}
+	for name, leave := range leaves {
+		t.Run(name, func(t *testing.T) {
+
+			first, second := newTestUser(t, "first"), newTestUser(t, "second")
+			// sin dueño, que no puede irse sin pasarle el picnic a otro
+			picnicID, err := CreatePicnic(Picnic{Name: "Small", Date: "2026-11-21 13:00", Capacity: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, user := range []User{first, second} {
+				if _, err := AddUserToPicnic(user.ID, picnicID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if status := membershipStatus(t, second.ID, picnicID); status != MembershipWaitlisted {
+				t.Fatalf("second user is %q before anyone leaves", status)
+			}
+
+			if _, err := leave(first.ID, picnicID); err != nil {
+				t.Fatal(err)
+			}
+			if status := membershipStatus(t, second.ID, picnicID); status != MembershipAttending {
+				t.Errorf("second user is %q after the first %s", status, name)
+			}
+		})
+	}
+}
+
+func membershipStatus(t *testing.T, userID int, picnicID int) string {
+	t.Helper()
+
+	membership, err := GetMembership(userID, picnicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return membership.Status
+}
@@ -0,0 +1,450 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/url"
+	"os"
+	"server/i18n"
+	"strings"
+	"time"
+)
+
+const CREATE_WEBHOOKS_TABLES_SQL = `
+
+CREATE TABLE IF NOT EXISTS webhooks (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  user_id     INTEGER NOT NULL,
+  url         VARCHAR NOT NULL,
+  events      VARCHAR NOT NULL,
+  secret      VARCHAR NOT NULL,
+  active      BOOLEAN NOT NULL DEFAULT 1,
+  created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id               INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  webhook_id       INTEGER NOT NULL,
+  event            VARCHAR NOT NULL,
+  payload          TEXT NOT NULL,
+  status           VARCHAR NOT NULL DEFAULT 'pending',
+  attempts         INTEGER NOT NULL DEFAULT 0,
+  response_status  INTEGER NOT NULL DEFAULT 0,
+  last_error       VARCHAR NOT NULL DEFAULT '',
+  next_attempt_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  delivered_at     DATETIME,
+  created_at       DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_deliveries_on_status ON webhook_deliveries (status, next_attempt_at);
+
+`
+
+// Eventos que se pueden suscribir, "*" recibe todos
+const (
+	EventPicnicCreated       = "picnic.created"
+	EventPicnicDeleted       = "picnic.deleted"
+	EventMembershipAdded     = "membership.added"
+	EventMembershipRemoved   = "membership.removed"
+	EventMembershipDeclined  = "membership.declined"
+	EventContributionCreated = "contribution.created"
+	EventContributionUpdated = "contribution.updated"
+	EventContributionDeleted = "contribution.deleted"
+)
+
+var WebhookEvents = []string{EventPicnicCreated, EventPicnicDeleted, EventMembershipAdded, EventMembershipRemoved, EventMembershipDeclined, EventContributionCreated, EventContributionUpdated, EventContributionDeleted}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Despues de esto la entrega queda en failed, se puede reintentar con replay
+const MaxWebhookAttempts = 10
+
+// Secret is only returned when the webhook is created
+type Webhook struct {
+	ID        int      `json:"id"`
+	UserID    int      `json:"user_id"`
+	URL       string   `json:"url" binding:"required"`
+	Events    []string `json:"events" binding:"required"`
+	Secret    string   `json:"secret,omitempty"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int    `json:"id"`
+	WebhookID      int    `json:"webhook_id"`
+	Event          string `json:"event"`
+	Payload        string `json:"payload"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"response_status"`
+	LastError      string `json:"last_error"`
+	NextAttemptAt  string `json:"next_attempt_at"`
+	DeliveredAt    string `json:"delivered_at,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+func (w Webhook) Wants(event string) bool {
+	for _, e := range w.Events {
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowPrivateWebhooks lets webhooks reach loopback and private addresses,
+// for receivers running next to the server in development.
+// WEBHOOKS_ALLOW_PRIVATE=1 turns it on.
+var AllowPrivateWebhooks = os.Getenv("WEBHOOKS_ALLOW_PRIVATE") == "1"
+
+// rangos que IsPrivate y compania no cubren
+var nonPublicNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// PublicIP is false for the addresses a webhook must not reach: loopback,
+// private, link-local, multicast and the other special ranges.
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNets {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookHost resolves the host of rawURL, every address it has must be
+// public. The dispatcher checks again when it connects, the DNS can change.
+func checkWebhookHost(rawURL string) error {
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return i18n.Errorf("url must be http or https")
+	}
+	if AllowPrivateWebhooks {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return i18n.Errorf("can't resolve %s", parsed.Hostname())
+	}
+	for _, addr := range addrs {
+		if !PublicIP(addr.IP) {
+			return i18n.Errorf("%s is not a public address", parsed.Hostname())
+		}
+	}
+	return nil
+}
+
+func validateWebhook(webhook Webhook) error {
+	if !strings.HasPrefix(webhook.URL, "http://") && !strings.HasPrefix(webhook.URL, "https://") {
+		return i18n.Errorf("url must be http or https")
+	}
+	if len(webhook.Events) == 0 {
+		return i18n.Errorf("a webhook needs at least one event")
+	}
+	for _, event := range webhook.Events {
+		known := event == "*"
+		for _, e := range WebhookEvents {
+			known = known || e == event
+		}
+		if !known {
+			return i18n.Errorf("unknown event %q", event)
+		}
+	}
+	return checkWebhookHost(webhook.URL)
+}
+
+// CreateWebhook generates the signing secret and returns it with the webhook.
+func CreateWebhook(newWebhook Webhook) (Webhook, error) {
+
+	if err := validateWebhook(newWebhook); err != nil {
+		return Webhook{}, err
+	}
+
+	secret, err := newToken()
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Webhook{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO webhooks (user_id, url, events, secret) VALUES (?, ?, ?, ?)", newWebhook.UserID, newWebhook.URL, strings.Join(newWebhook.Events, ","), secret)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	tx.Commit()
+
+	webhook, err := GetWebhookById(int(id))
+	if err != nil {
+		return Webhook{}, err
+	}
+	webhook.Secret = secret
+	return webhook, nil
+}
+
+const webhookColumns = "id, user_id, url, events, active, created_at"
+
+func scanWebhook(row scanner) (Webhook, error) {
+	webhook := Webhook{}
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &events, &webhook.Active, &webhook.CreatedAt)
+	webhook.Events = strings.Split(events, ",")
+	return webhook, err
+}
+
+func GetWebhookById(id int) (Webhook, error) {
+
+	webhook, err := scanWebhook(DB.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Webhook{}, nil
+		}
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func GetWebhookSecret(id int) (string, error) {
+	var secret string
+	err := DB.QueryRow("SELECT secret FROM webhooks WHERE id = ?", id).Scan(&secret)
+	return secret, err
+}
+
+func queryWebhooks(query string, args ...interface{}) ([]Webhook, error) {
+
+	rows, err := DB.Query(query, args...)
+	webhooks := make([]Webhook, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return make([]Webhook, 0), err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]Webhook, 0), err
+	}
+
+	return webhooks, err
+}
+
+func GetWebhooksByUser(userID int) ([]Webhook, error) {
+	return queryWebhooks("SELECT "+webhookColumns+" FROM webhooks WHERE user_id = ? ORDER BY id", userID)
+}
+
+func UpdateWebhook(updatedWebhook Webhook, idToUpdate int) (bool, error) {
+
+	if err := validateWebhook(updatedWebhook); err != nil {
+		return false, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	stmt, err := tx.Prepare("UPDATE webhooks SET url = ?, events = ?, active = ? WHERE id = ?")
+
+	if err != nil {
+		return false, err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.Exec(updatedWebhook.URL, strings.Join(updatedWebhook.Events, ","), updatedWebhook.Active, idToUpdate)
+
+	if err != nil {
+		return false, err
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
+
+func DeleteWebhook(webhookID int) (bool, error) {
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// sin foreign_keys activadas el cascade no corre, se borran a mano
+	_, err = tx.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", webhookID)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec("DELETE FROM webhooks WHERE id = ?", webhookID)
+	if err != nil {
+		return false, err
+	}
+
+	tx.Commit()
+
+	return true, nil
+}
+
+// GetWebhooksOfPicnic returns the active webhooks of the people attending
+// picnicID, nobody hears about picnics they are not in
+func GetWebhooksOfPicnic(picnicID int) ([]Webhook, error) {
+	return queryWebhooks("SELECT webhooks.id, webhooks.user_id, webhooks.url, webhooks.events, webhooks.active, webhooks.created_at FROM webhooks INNER JOIN users_picnics ON users_picnics.user_id = webhooks.user_id WHERE webhooks.active = 1 AND users_picnics.picnic_id = ? AND users_picnics.status = ?", picnicID, MembershipAttending)
+}
+
+// QueueWebhookDeliveries creates a pending delivery for every webhook of
+// GetWebhooksOfPicnic subscribed to event. It returns how many were queued.
+func QueueWebhookDeliveries(picnicID int, event string, payload string) (int, error) {
+
+	webhooks, err := GetWebhooksOfPicnic(picnicID)
+	if err != nil {
+		return 0, err
+	}
+	return QueueDeliveriesTo(webhooks, event, payload)
+}
+
+// QueueDeliveriesTo is QueueWebhookDeliveries for webhooks looked up
+// earlier, when the picnic may be gone by now
+func QueueDeliveriesTo(webhooks []Webhook, event string, payload string) (int, error) {
+
+	var err error
+	queued := 0
+	for _, webhook := range webhooks {
+		if !webhook.Wants(event) {
+			continue
+		}
+		_, err = DB.Exec("INSERT INTO webhook_deliveries (webhook_id, event, payload) VALUES (?, ?, ?)", webhook.ID, event, payload)
+		if err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+const deliveryColumns = "id, webhook_id, event, payload, status, attempts, response_status, last_error, next_attempt_at, delivered_at, created_at"
+
+func scanDelivery(row scanner) (WebhookDelivery, error) {
+	delivery := WebhookDelivery{}
+	var deliveredAt sql.NullString
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError, &delivery.NextAttemptAt, &deliveredAt, &delivery.CreatedAt)
+	delivery.DeliveredAt = deliveredAt.String
+	return delivery, err
+}
+
+func queryDeliveries(query string, args ...interface{}) ([]WebhookDelivery, error) {
+
+	rows, err := DB.Query(query, args...)
+	deliveries := make([]WebhookDelivery, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return make([]WebhookDelivery, 0), err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	err = rows.Err()
+
+	if err != nil {
+		return make([]WebhookDelivery, 0), err
+	}
+
+	return deliveries, err
+}
+
+func GetDueWebhookDeliveries(limit int) ([]WebhookDelivery, error) {
+	return queryDeliveries("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= datetime('now') ORDER BY id LIMIT ?", DeliveryPending, limit)
+}
+
+func GetDeliveriesByWebhook(webhookID int, limit int) ([]WebhookDelivery, error) {
+	return queryDeliveries("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?", webhookID, limit)
+}
+
+func GetWebhookDeliveryById(id int) (WebhookDelivery, error) {
+
+	delivery, err := scanDelivery(DB.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ?", id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return WebhookDelivery{}, nil
+		}
+		return WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func MarkDeliverySucceeded(id int, responseStatus int) error {
+	_, err := DB.Exec("UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, response_status = ?, last_error = '', delivered_at = CURRENT_TIMESTAMP WHERE id = ?", DeliverySucceeded, responseStatus, id)
+	return err
+}
+
+// MarkDeliveryFailed schedules a retry after backoff, or gives up once the
+// delivery used all its attempts.
+func MarkDeliveryFailed(delivery WebhookDelivery, responseStatus int, deliveryErr error, backoff time.Duration) error {
+	status := DeliveryPending
+	if delivery.Attempts+1 >= MaxWebhookAttempts {
+		status = DeliveryFailed
+	}
+	next := time.Now().UTC().Add(backoff).Format(sqliteTimeFormat)
+	_, err := DB.Exec("UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, response_status = ?, last_error = ?, next_attempt_at = ? WHERE id = ?", status, responseStatus, deliveryErr.Error(), next, delivery.ID)
+	return err
+}
+
+// ReplayWebhookDelivery queues a copy of an old delivery, the original stays
+// in the log untouched.
+func ReplayWebhookDelivery(delivery WebhookDelivery) (int, error) {
+	result, err := DB.Exec("INSERT INTO webhook_deliveries (webhook_id, event, payload) VALUES (?, ?, ?)", delivery.WebhookID, delivery.Event, delivery.Payload)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
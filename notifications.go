@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"server/i18n"
+	"server/mailer"
+	"server/models"
+	"server/reminders"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mail is replaced by mailer.FromEnv in main
+var mail mailer.Mailer = &mailer.Capture{}
+
+func baseURL() string {
+	if url := os.Getenv("BASE_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:8080"
+}
+
+// queueEmail renders templates/emails/<name>.html and .txt in the language
+// of the user and stores the result in the outbox. The subject is an English
+// format that gets translated too. Users without an email are skipped.
+// Errors are also logged, notifications never fail the request that caused
+// them.
+func queueEmail(to models.User, name string, data gin.H, subject string, args ...interface{}) error {
+
+	if to.Email == "" {
+		return nil
+	}
+	data["User"] = to
+
+	lang := userLang(to)
+	err := enqueueRendered(to, lang, i18n.T(lang, subject, args...), name, data)
+	if err != nil {
+		log.Printf("email %s: %v", name, err)
+	}
+	return err
+}
+
+func enqueueRendered(to models.User, lang string, subject string, name string, data gin.H) error {
+
+	htmlTemplates, textTemplates := siteTemplates.get(lang)
+
+	var html, text bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&html, name, data); err != nil {
+		return err
+	}
+	if err := textTemplates.ExecuteTemplate(&text, name, data); err != nil {
+		return err
+	}
+
+	_, err := models.EnqueueEmail(models.OutboxMessage{
+		To:       to.Email,
+		Subject:  subject,
+		TextBody: text.String(),
+		HTMLBody: html.String(),
+	})
+	return err
+}
+
+// notifyInvitation mails the invited user if the invitation names one. Like
+// in queueEmail, errors are only logged, a mail must never take down the
+// request that sent it.
+func notifyInvitation(invitation models.Invitation, token string) {
+
+	if invitation.UserID == 0 {
+		return
+	}
+
+	user, err := models.GetUserById(invitation.UserID)
+	if err != nil {
+		log.Printf("email email_invitation: %v", err)
+		return
+	}
+	inviter, err := models.GetUserById(invitation.CreatedBy)
+	if err != nil {
+		log.Printf("email email_invitation: %v", err)
+		return
+	}
+	picnic, err := models.GetPicnicById(invitation.PicnicID)
+	if err != nil {
+		log.Printf("email email_invitation: %v", err)
+		return
+	}
+
+	queueEmail(user, "email_invitation", gin.H{
+		"Inviter": inviter,
+		"Picnic":  picnic,
+		"URL":     baseURL() + "/invitations/" + token,
+	}, "You're invited to %s", picnic.Name)
+}
+
+// notifyPicnicChanged only mails when the date or the location moved, a new
+// name or capacity isn't worth an email.
+func notifyPicnicChanged(before models.Picnic, after models.Picnic, editorID int) {
+
+	if sameDate(before.Date, after.Date) && before.Location == after.Location {
+		return
+	}
+
+	editor, err := models.GetUserById(editorID)
+	if err != nil {
+		log.Printf("email email_picnic_changed: %v", err)
+		return
+	}
+
+	users, err := models.GetUsersByPicnic(after.ID)
+	if err != nil {
+		log.Printf("email email_picnic_changed: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if user.ID == editorID {
+			continue
+		}
+		queueEmail(user, "email_picnic_changed", gin.H{
+			"Editor": editor,
+			"Before": before,
+			"Picnic": after,
+		}, "%s has new plans", after.Name)
+	}
+}
+
+// sameDate compares the dates as times, the database hands back
+// "2026-11-21T13:00:00Z" for a picnic saved as "2026-11-21 13:00"
+func sameDate(a string, b string) bool {
+	at, errA := models.ParseDate(a)
+	bt, errB := models.ParseDate(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return at.Equal(bt)
+}
+
+// notifyContributionChanged tells the assigned user when someone else edits
+// their contribution.
+func notifyContributionChanged(before models.Contribution, after models.Contribution, editorID int) {
+
+	if after.UserID == editorID {
+		return
+	}
+
+	user, err := models.GetUserById(after.UserID)
+	if err != nil {
+		log.Printf("email email_contribution_changed: %v", err)
+		return
+	}
+	editor, err := models.GetUserById(editorID)
+	if err != nil {
+		log.Printf("email email_contribution_changed: %v", err)
+		return
+	}
+	picnic, err := models.GetPicnicById(after.PicnicID)
+	if err != nil {
+		log.Printf("email email_contribution_changed: %v", err)
+		return
+	}
+	beforeItem, err := models.GetFoodItemById(before.FoodItemID)
+	if err != nil {
+		log.Printf("email email_contribution_changed: %v", err)
+		return
+	}
+	foodItem, err := models.GetFoodItemById(after.FoodItemID)
+	if err != nil {
+		log.Printf("email email_contribution_changed: %v", err)
+		return
+	}
+
+	queueEmail(user, "email_contribution_changed", gin.H{
+		"Editor":       editor,
+		"Picnic":       picnic,
+		"Before":       before,
+		"BeforeItem":   beforeItem,
+		"Contribution": after,
+		"FoodItem":     foodItem,
+	}, "Your contribution to %s changed", picnic.Name)
+}
+
+// flushOutbox sends every due message once. Failures stay in the outbox with
+// a later next_attempt_at.
+func flushOutbox(m mailer.Mailer) error {
+
+	messages, err := models.GetDueEmails(50)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		sendErr := m.Send(mailer.Message{
+			To:      message.To,
+			Subject: message.Subject,
+			Text:    message.TextBody,
+			HTML:    message.HTMLBody,
+		})
+
+		if sendErr != nil {
+			log.Printf("outbox %d: %v", message.ID, sendErr)
+			err = models.MarkEmailFailed(message, sendErr)
+		} else {
+			err = models.MarkEmailSent(message.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runOutbox(m mailer.Mailer, interval time.Duration) {
+	for {
+		if err := flushOutbox(m); err != nil {
+			log.Printf("outbox: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// reminderEmail is the email channel of the reminder engine, it goes through
+// the outbox like every other mail.
+type reminderEmail struct{}
+
+func (reminderEmail) Name() string { return "email" }
+
+func (reminderEmail) Send(reminder reminders.Reminder) error {
+	return queueEmail(reminder.User, "email_reminder", gin.H{
+		"Picnic": reminder.Picnic,
+		"Items":  reminder.Items,
+		"When":   humanDuration(userLang(reminder.User), reminder.StartsIn),
+	}, "%s is coming up", reminder.Picnic.Name)
+}
+
+func humanDuration(lang string, d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return i18n.T(lang, "%d days", int(d.Hours()/24))
+	case d >= 2*time.Hour:
+		return i18n.T(lang, "%d hours", int(d.Hours()))
+	case d >= time.Hour:
+		return i18n.T(lang, "1 hour")
+	default:
+		return i18n.T(lang, "%d minutes", int(d.Minutes()))
+	}
+}
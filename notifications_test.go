@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"server/mailer"
+	"server/models"
+	"strings"
+	"testing"
+)
+
+// sentMail flushes the outbox into a Capture and returns what reached to
+func sentMail(t *testing.T, to testUser) []mailer.Message {
+	t.Helper()
+
+	capture := &mailer.Capture{}
+	if err := flushOutbox(capture); err != nil {
+		t.Fatal(err)
+	}
+	return capture.SentTo(to.Email)
+}
+
+func TestInvitationMail(t *testing.T) {
+
+	p := newRolePicnic(t)
+	guest := newTestUser(t, "guest")
+
+	w := p.users["owner"].do(t, "POST", fmt.Sprintf("/api/v1/picnics/%d/invitations", p.picnicID), map[string]int{"user_id": guest.ID})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+
+	sent := sentMail(t, guest)
+	if len(sent) != 1 {
+		t.Fatalf("got %d mails, want 1", len(sent))
+	}
+	if sent[0].Subject != "You're invited to Roles" {
+		t.Errorf("subject is %q", sent[0].Subject)
+	}
+	if !strings.Contains(sent[0].Text, "/invitations/") || !strings.Contains(sent[0].HTML, "/invitations/") {
+		t.Errorf("the mail has no invitation link:\n%s", sent[0].Text)
+	}
+}
+
+func TestPicnicChangedMail(t *testing.T) {
+
+	p := newRolePicnic(t)
+	attendee := p.users["attendee"]
+	if _, err := models.UpdateUser(models.User{Name: attendee.Name, Email: attendee.Email, Locale: "es"}, attendee.ID); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/v1/picnics/%d", p.picnicID)
+
+	// un nombre nuevo no amerita un mail
+	w := p.users["owner"].do(t, "PUT", path, models.Picnic{Name: "Roles", Date: "2026-11-21 13:00", Capacity: 5})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	if sent := sentMail(t, attendee); len(sent) != 0 {
+		t.Fatalf("got %d mails for a capacity change: %q", len(sent), sent[0].Subject)
+	}
+
+	w = p.users["owner"].do(t, "PUT", path, models.Picnic{Name: "Roles", Date: "2026-11-28 13:00", Capacity: 5})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+
+	sent := sentMail(t, attendee)
+	if len(sent) != 1 {
+		t.Fatalf("got %d mails, want 1", len(sent))
+	}
+	if sent[0].Subject != "Roles tiene planes nuevos" {
+		t.Errorf("subject is %q", sent[0].Subject)
+	}
+	if others := sentMail(t, p.users["owner"]); len(others) != 0 {
+		t.Errorf("the editor got %d mails", len(others))
+	}
+	if others := sentMail(t, p.users["waitlisted"]); len(others) != 0 {
+		t.Errorf("a waitlisted user got %d mails", len(others))
+	}
+}
+
+func TestContributionChangedMail(t *testing.T) {
+
+	p := newRolePicnic(t)
+	path := fmt.Sprintf("/api/v1/contributions/%d", p.contribution.ID)
+
+	// el que trae la comida no se avisa a si mismo
+	edited := p.contribution
+	edited.Quantity = 2
+	if w := p.bringer.do(t, "PUT", path, edited); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	if sent := sentMail(t, p.bringer); len(sent) != 0 {
+		t.Fatalf("got %d mails for an own edit", len(sent))
+	}
+
+	edited.Quantity = 5
+	if w := p.users["co-host"].do(t, "PUT", path, edited); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+
+	sent := sentMail(t, p.bringer)
+	if len(sent) != 1 {
+		t.Fatalf("got %d mails, want 1", len(sent))
+	}
+	if sent[0].Subject != "Your contribution to Roles changed" {
+		t.Errorf("subject is %q", sent[0].Subject)
+	}
+	if !strings.Contains(sent[0].Text, p.users["co-host"].Name) {
+		t.Errorf("the mail doesn't name the editor:\n%s", sent[0].Text)
+	}
+}
+
+func TestEmailIsOnlyShownToItsUser(t *testing.T) {
+
+	p := newRolePicnic(t)
+	owner := p.users["owner"]
+
+	for _, path := range []string{
+		"/api/v1/users/",
+		fmt.Sprintf("/api/v1/users/%d", owner.ID),
+		fmt.Sprintf("/api/v1/picnics/%d/users", p.picnicID),
+	} {
+		for _, caller := range []testUser{{}, p.users["attendee"], owner} {
+			w := caller.do(t, "GET", path, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: got %d", path, w.Code)
+			}
+			if strings.Contains(w.Body.String(), "@example.com") {
+				t.Errorf("%s shows emails: %s", path, w.Body)
+			}
+		}
+	}
+
+	w := owner.do(t, "GET", "/api/v1/auth/me", nil)
+	if !strings.Contains(w.Body.String(), owner.Email) {
+		t.Errorf("/auth/me has no email: %s", w.Body)
+	}
+
+	query := map[string]string{"query": fmt.Sprintf("{ users { email } picnic(id: %d) { attendees { email } } }", p.picnicID)}
+	w = p.users["attendee"].do(t, "POST", "/api/v1/graphql", query)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), owner.Email) {
+		t.Errorf("graphql shows emails (%d): %s", w.Code, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), p.users["attendee"].Email) {
+		t.Errorf("graphql hides the email of the viewer: %s", w.Body)
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"server/models"
+	"server/openapi"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiPrefix = "/api/v1"
+
+// apiOperation documents one /api/v1 route. Body and Data are zero values
+// of the request type and of what the handler puts in "data", the schemas
+// come from their json tags.
+type apiOperation struct {
+	Summary     string
+	Description string
+	// Scope is the scope a token needs, cookie sessions aren't scoped
+	Scope string
+	// Anonymous routes work without a session or token
+	Anonymous bool
+	Query     []openapi.Parameter
+	Body      interface{}
+	// Upload is the multipart field of an image upload
+	Upload string
+	// Data nil means the response is just {"message": "Success"}
+	Data interface{}
+	// Response replaces the whole {"message", "data"} body
+	Response interface{}
+	// ContentType of responses that aren't JSON
+	ContentType string
+}
+
+func queryParam(name string, kind string, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: kind}}
+}
+
+var userIDsParam = openapi.Parameter{Name: "user_ids", In: "query", Required: true, Description: "Comma separated user ids, 1,2,3", Schema: &openapi.Schema{Type: "string"}}
+
+// apiOperations has an entry for every route of the v1 group, keyed by
+// method and path without the /api/v1 prefix. A route missing here fails
+// TestOpenAPICoversEveryRoute, see buildOpenAPI.
+var apiOperations = map[string]apiOperation{
+
+	// docs
+	"GET /openapi.json": {Summary: "This OpenAPI document", Anonymous: true, ContentType: "application/json"},
+	"GET /docs":         {Summary: "Interactive API docs", Anonymous: true, ContentType: "text/html"},
+
+	// auth
+	"POST /auth/register": {Summary: "Register and log in", Anonymous: true, Body: credentials{}, Data: models.Account{},
+		Description: "Sets the session cookie. Without locale the language of the request is saved."},
+	"POST /auth/login":                  {Summary: "Log in", Anonymous: true, Body: credentials{}, Data: models.Account{}, Description: "Sets the session cookie."},
+	"POST /auth/logout":                 {Summary: "Log out", Scope: models.ScopeAdmin},
+	"GET /auth/me":                      {Summary: "Current user", Scope: models.ScopePicnicsRead, Data: models.Account{}},
+	"GET /auth/sessions":                {Summary: "Open sessions of the current user", Scope: models.ScopeAdmin, Data: []models.Session{}},
+	"DELETE /auth/sessions/:session_id": {Summary: "Revoke a session", Scope: models.ScopeAdmin},
+
+	// tokens
+	"POST /tokens/": {Summary: "Create an API token", Scope: models.ScopeAdmin,
+		Body: struct {
+			Name          string   `json:"name" binding:"required"`
+			Scopes        []string `json:"scopes" binding:"required"`
+			ExpiresInDays int      `json:"expires_in_days"`
+		}{},
+		Data: struct {
+			Token string `json:"token"`
+		}{},
+		Description: "The token is only shown in this response."},
+	"GET /tokens/":             {Summary: "API tokens of the current user", Scope: models.ScopeAdmin, Data: []models.ApiToken{}},
+	"DELETE /tokens/:token_id": {Summary: "Revoke an API token", Scope: models.ScopeAdmin},
+
+	// webhooks
+	"POST /webhooks/":              {Summary: "Create a webhook", Scope: models.ScopeAdmin, Body: models.Webhook{}, Data: models.Webhook{}, Description: "The secret is only shown in this response. The url must resolve to a public address. A webhook only receives events of picnics its owner attends."},
+	"GET /webhooks/":               {Summary: "Webhooks of the current user", Scope: models.ScopeAdmin, Data: []models.Webhook{}},
+	"GET /webhooks/:webhook_id":    {Summary: "Get a webhook", Scope: models.ScopeAdmin, Data: models.Webhook{}},
+	"PUT /webhooks/:webhook_id":    {Summary: "Update a webhook", Scope: models.ScopeAdmin, Body: models.Webhook{}},
+	"DELETE /webhooks/:webhook_id": {Summary: "Delete a webhook", Scope: models.ScopeAdmin},
+	"GET /webhooks/:webhook_id/deliveries": {Summary: "Latest deliveries of a webhook", Scope: models.ScopeAdmin, Data: []models.WebhookDelivery{},
+		Query: []openapi.Parameter{queryParam("limit", "integer", "50 by default")}},
+	"POST /webhooks/:webhook_id/deliveries/:delivery_id/replay": {Summary: "Send a delivery again", Scope: models.ScopeAdmin,
+		Data: struct {
+			DeliveryID int `json:"delivery_id"`
+		}{}},
+
+	// picnics
+	"POST /picnics/":             {Summary: "Create a picnic", Scope: models.ScopeAdmin, Body: models.Picnic{}, Data: models.Picnic{}, Description: "The creator becomes the owner."},
+	"GET /picnics/:picnic_id":    {Summary: "Get a picnic", Scope: models.ScopePicnicsRead, Anonymous: true, Data: models.Picnic{}},
+	"GET /picnics/":              {Summary: "List picnics", Scope: models.ScopePicnicsRead, Anonymous: true, Data: []models.Picnic{}},
+	"PUT /picnics/:picnic_id":    {Summary: "Update a picnic", Scope: models.ScopeAdmin, Body: models.Picnic{}, Description: "Owners and co-hosts only."},
+	"DELETE /picnics/:picnic_id": {Summary: "Delete a picnic", Scope: models.ScopeAdmin, Description: "Owner only."},
+	"POST /picnics/:picnic_id/owner": {Summary: "Transfer ownership", Scope: models.ScopeAdmin, Description: "Owner only, the new owner has to be attending.",
+		Body: struct {
+			UserID int `json:"user_id" binding:"required"`
+		}{}},
+
+	// users
+	"POST /users/":        {Summary: "Create a user without password", Scope: models.ScopeAdmin, Description: "For a logged-in user, to add friends who don't have an account yet.", Body: models.Account{}},
+	"GET /users/:user_id": {Summary: "Get a user", Scope: models.ScopePicnicsRead, Anonymous: true, Data: models.User{}},
+	"GET /users/":         {Summary: "List users", Scope: models.ScopePicnicsRead, Anonymous: true, Data: []models.User{}},
+	"PUT /users/:user_id": {Summary: "Update your own user", Scope: models.ScopeAdmin, Body: models.Account{}},
+
+	// memberships
+	"POST /picnics/:picnic_id/users/:user_id": {Summary: "Add a user to a picnic", Scope: models.ScopeAdmin, Data: models.UserPicnic{},
+		Description: "Owners and co-hosts only. A full picnic puts the user on the waitlist."},
+	"GET /picnics/:picnic_id/users":                   {Summary: "Users attending a picnic", Scope: models.ScopePicnicsRead, Anonymous: true, Data: []models.User{}},
+	"GET /users/:user_id/picnics":                     {Summary: "Picnics of a user", Scope: models.ScopePicnicsRead, Anonymous: true, Data: []models.Picnic{}},
+	"GET /users/:user_id/settlements":                 {Summary: "Settlements of a user, only your own", Scope: models.ScopePicnicsRead, Data: []models.Settlement{}},
+	"DELETE /picnics/:picnic_id/users/:user_id":       {Summary: "Remove a user from a picnic", Scope: models.ScopeAdmin, Description: "The user themselves, owners and co-hosts."},
+	"POST /picnics/:picnic_id/users/:user_id/decline": {Summary: "Decline a picnic", Scope: models.ScopeAdmin, Description: "Frees the spot for the waitlist."},
+	"PUT /picnics/:picnic_id/users/:user_id/role": {Summary: "Change the role of a member", Scope: models.ScopeAdmin, Description: "Owner only.",
+		Body: struct {
+			Role string `json:"role" binding:"required"`
+		}{}},
+	"GET /picnics/:picnic_id/waitlist":       {Summary: "Waitlist of a picnic", Scope: models.ScopePicnicsRead, Description: "Only for people attending the picnic.", Data: []models.UserPicnic{}},
+	"GET /users/:user_id/picnics/:picnic_id": {Summary: "Membership of a user in a picnic", Scope: models.ScopePicnicsRead, Description: "Your own, or anyone's for owners and co-hosts.", Data: models.UserPicnic{}},
+
+	// live
+	"GET /picnics/:picnic_id/session": {Summary: "Collaborative planning session", Scope: models.ScopeContributionsWrite, ContentType: "application/json",
+		Description: "Upgrades to a WebSocket. Members only."},
+	"GET /picnics/:picnic_id/events": {Summary: "Event stream of a picnic", Scope: models.ScopePicnicsRead, ContentType: "text/event-stream",
+		Description: "Server-Sent Events, resumes from the Last-Event-ID header. Without it only new events are sent. Members only.",
+		Query:       []openapi.Parameter{queryParam("last_event_id", "integer", "Resume after this event when the header can't be sent")}},
+
+	// comments
+	"GET /picnics/:picnic_id/comments": {Summary: "Comments of a picnic", Scope: models.ScopePicnicsRead, Description: "Members only, oldest first.",
+		Query: []openapi.Parameter{
+			queryParam("contribution_id", "integer", "Only comments on this contribution"),
+			queryParam("after", "integer", "Comments after this id, next_after of the previous page"),
+			queryParam("limit", "integer", "1 to 200, 50 by default"),
+		},
+		Response: struct {
+			Data      []models.Comment `json:"data"`
+			NextAfter int              `json:"next_after,omitempty"`
+		}{}},
+	"POST /picnics/:picnic_id/comments":               {Summary: "Comment on a picnic", Scope: models.ScopeAdmin, Body: models.Comment{}, Data: models.Comment{}},
+	"PUT /picnics/:picnic_id/comments/:comment_id":    {Summary: "Edit your comment", Scope: models.ScopeAdmin, Body: models.Comment{}, Data: models.Comment{}},
+	"DELETE /picnics/:picnic_id/comments/:comment_id": {Summary: "Delete a comment", Scope: models.ScopeAdmin, Description: "The author, owners and co-hosts."},
+
+	// polls
+	"POST /picnics/:picnic_id/polls": {Summary: "Create a poll", Scope: models.ScopeAdmin, Body: models.Poll{}, Data: models.Poll{}, Description: "Owners and co-hosts only."},
+	"GET /picnics/:picnic_id/polls":  {Summary: "Polls of a picnic", Scope: models.ScopePicnicsRead, Data: []models.Poll{}},
+	"GET /picnics/:picnic_id/polls/:poll_id": {Summary: "Get a poll with your votes", Scope: models.ScopePicnicsRead,
+		Response: struct {
+			Data    models.Poll       `json:"data"`
+			MyVotes map[string]string `json:"my_votes"`
+		}{}},
+	"PUT /picnics/:picnic_id/polls/:poll_id/votes": {Summary: "Vote", Scope: models.ScopeAdmin, Data: models.Poll{},
+		Body: struct {
+			Votes []models.PollVote `json:"votes" binding:"required"`
+		}{}},
+	"POST /picnics/:picnic_id/polls/:poll_id/close": {Summary: "Close a poll", Scope: models.ScopeAdmin, Description: "The winning option is copied to the picnic.",
+		Data: struct {
+			Poll   models.Poll   `json:"poll"`
+			Picnic models.Picnic `json:"picnic"`
+		}{}},
+
+	// images
+	"POST /picnics/:picnic_id/images":             {Summary: "Upload a picnic photo", Scope: models.ScopeAdmin, Upload: "image", Data: models.Image{}},
+	"GET /picnics/:picnic_id/images":              {Summary: "Photos of a picnic", Scope: models.ScopePicnicsRead, Data: []models.Image{}},
+	"DELETE /picnics/:picnic_id/images/:image_id": {Summary: "Delete a photo", Scope: models.ScopeAdmin, Description: "The uploader, owners and co-hosts."},
+	"POST /food-items/:item_id/image":             {Summary: "Upload the image of a food item", Scope: models.ScopeAdmin, Upload: "image", Data: models.Image{}},
+
+	// invitations
+	"POST /picnics/:picnic_id/invitations": {Summary: "Invite someone", Scope: models.ScopeAdmin, Description: "Without user_id anyone with the link can accept it.",
+		Body: struct {
+			UserID         int `json:"user_id"`
+			ExpiresInHours int `json:"expires_in_hours"`
+		}{},
+		Data: struct {
+			Invitation models.Invitation `json:"invitation"`
+			Token      string            `json:"token"`
+			URL        string            `json:"url"`
+		}{}},
+	"GET /picnics/:picnic_id/invitations":                   {Summary: "Invitations of a picnic", Scope: models.ScopePicnicsRead, Data: []models.Invitation{}},
+	"DELETE /picnics/:picnic_id/invitations/:invitation_id": {Summary: "Revoke an invitation", Scope: models.ScopeAdmin},
+	"GET /invitations/:token": {Summary: "Look up an invitation", Scope: models.ScopePicnicsRead, Anonymous: true,
+		Data: struct {
+			Invitation models.Invitation `json:"invitation"`
+			Picnic     models.Picnic     `json:"picnic"`
+		}{}},
+	"POST /invitations/:token/accept":  {Summary: "Accept an invitation", Scope: models.ScopeAdmin, Data: invitationAnswer{}},
+	"POST /invitations/:token/decline": {Summary: "Decline an invitation", Scope: models.ScopeAdmin, Data: invitationAnswer{}},
+
+	// food items
+	"POST /food-items/":        {Summary: "Create a food item", Scope: models.ScopeAdmin, Anonymous: true, Body: models.FoodItem{}},
+	"GET /food-items/:item_id": {Summary: "Get a food item", Scope: models.ScopePicnicsRead, Anonymous: true, Data: models.FoodItem{}},
+	"GET /food-items/":         {Summary: "List food items", Scope: models.ScopePicnicsRead, Anonymous: true, Data: []models.FoodItem{}},
+	"PUT /food-items/:item_id": {Summary: "Update a food item", Scope: models.ScopeAdmin, Anonymous: true, Body: models.FoodItem{}},
+
+	// gear
+	"POST /gear/":                                    {Summary: "Create a gear item", Scope: models.ScopeAdmin, Anonymous: true, Body: models.GearItem{}, Data: models.GearItem{}},
+	"GET /gear/:gear_id":                             {Summary: "Get a gear item", Scope: models.ScopePicnicsRead, Anonymous: true, Data: models.GearItem{}},
+	"GET /gear/":                                     {Summary: "List gear items", Scope: models.ScopePicnicsRead, Anonymous: true, Data: []models.GearItem{}},
+	"PUT /gear/:gear_id":                             {Summary: "Update a gear item", Scope: models.ScopeAdmin, Anonymous: true, Body: models.GearItem{}},
+	"GET /picnics/:picnic_id/gear":                   {Summary: "Gear of a picnic", Scope: models.ScopePicnicsRead, Data: []models.GearAssignment{}},
+	"POST /picnics/:picnic_id/gear":                  {Summary: "Bring gear to a picnic", Scope: models.ScopeContributionsWrite, Body: models.GearAssignment{}, Data: models.GearAssignment{}},
+	"PUT /picnics/:picnic_id/gear/:assignment_id":    {Summary: "Update gear you bring", Scope: models.ScopeContributionsWrite, Body: models.GearAssignment{}, Data: models.GearAssignment{}},
+	"DELETE /picnics/:picnic_id/gear/:assignment_id": {Summary: "Stop bringing gear", Scope: models.ScopeContributionsWrite},
+	"GET /picnics/:picnic_id/list":                   {Summary: "Packing list of a picnic", Scope: models.ScopePicnicsRead, Data: []models.ListItem{}, Description: "Food and gear together."},
+
+	// inventory
+	"POST /inventory/": {Summary: "Add shared equipment", Scope: models.ScopeAdmin, Body: models.InventoryItem{}, Data: models.InventoryItem{}},
+	"GET /inventory/":  {Summary: "List shared equipment", Scope: models.ScopePicnicsRead, Anonymous: true, Data: []models.InventoryItem{}},
+	"GET /inventory/conflicts": {Summary: "Double booked equipment", Scope: models.ScopePicnicsRead, Data: [][2]models.Reservation{},
+		Description: "Pairs of reservations of the same item on overlapping dates."},
+	"GET /inventory/:item_id":            {Summary: "Get shared equipment", Scope: models.ScopePicnicsRead, Anonymous: true, Data: models.InventoryItem{}},
+	"PUT /inventory/:item_id":            {Summary: "Update shared equipment", Scope: models.ScopeAdmin, Body: models.InventoryItem{}},
+	"GET /inventory/:item_id/history":    {Summary: "Reservation history of an item", Scope: models.ScopePicnicsRead, Data: []models.Reservation{}},
+	"GET /picnics/:picnic_id/inventory":  {Summary: "Equipment reserved for a picnic", Scope: models.ScopePicnicsRead, Data: []models.Reservation{}},
+	"POST /picnics/:picnic_id/inventory": {Summary: "Reserve equipment", Scope: models.ScopeContributionsWrite, Body: models.Reservation{}, Data: models.Reservation{}},
+	"POST /picnics/:picnic_id/inventory/:reservation_id/checkout": {Summary: "Check out reserved equipment", Scope: models.ScopeContributionsWrite, Data: models.Reservation{},
+		Description: "Organizers can hand it to someone else with user_id.",
+		Body: struct {
+			UserID int `json:"user_id"`
+		}{}},
+	"POST /picnics/:picnic_id/inventory/:reservation_id/return": {Summary: "Return equipment", Scope: models.ScopeContributionsWrite, Data: models.Reservation{}},
+	"DELETE /picnics/:picnic_id/inventory/:reservation_id":      {Summary: "Cancel a reservation", Scope: models.ScopeContributionsWrite},
+
+	// contributions
+	"POST /contributions/":                   {Summary: "Add a contribution", Scope: models.ScopeContributionsWrite, Body: models.Contribution{}, Data: models.Contribution{}},
+	"GET /contributions/:contribution_id":    {Summary: "Get a contribution", Scope: models.ScopePicnicsRead, Anonymous: true, Data: models.Contribution{}},
+	"GET /contributions/":                    {Summary: "List contributions", Scope: models.ScopePicnicsRead, Anonymous: true, Data: []models.Contribution{}},
+	"PUT /contributions/:contribution_id":    {Summary: "Update a contribution", Scope: models.ScopeContributionsWrite, Body: models.Contribution{}, Description: "Send the version you read, a stale one gets 409."},
+	"DELETE /contributions/:contribution_id": {Summary: "Delete a contribution", Scope: models.ScopeContributionsWrite},
+
+	// graphql
+	"POST /graphql": {Summary: "Run a GraphQL query or mutation", Scope: models.ScopePicnicsRead, Anonymous: true, Body: graphqlBody{}, Response: graphqlResponse{},
+		Description: "Picnics, users, food items and contributions with their relationships in one request. Mutations need the scope of the matching REST route. Queries deeper than 8 levels or too complex get 400."},
+
+	// settlements
+	"POST /settlements/":               {Summary: "Record a payment you made to another user", Scope: models.ScopeAdmin, Body: models.Settlement{}, Description: "from_user_id must be you, and both users must be in the picnic, or in some picnic together when picnic_id is 0."},
+	"GET /picnics/:picnic_id/balances": {Summary: "Who owes whom in a picnic", Scope: models.ScopePicnicsRead, Description: "Only for people attending the picnic.", Data: models.SettleUp{}},
+	"GET /balances": {Summary: "Who owes whom across the picnics of some users", Scope: models.ScopePicnicsRead, Data: models.SettleUp{},
+		Description: "Every user in user_ids must attend a picnic with the caller.",
+		Query:       []openapi.Parameter{userIDsParam}},
+
+	// availability
+	"POST /users/:user_id/availability":                    {Summary: "Add an availability window", Scope: models.ScopeAdmin, Body: models.Availability{}, Data: models.Availability{}},
+	"GET /users/:user_id/availability":                     {Summary: "Availability of your user", Scope: models.ScopePicnicsRead, Data: []models.Availability{}},
+	"DELETE /users/:user_id/availability/:availability_id": {Summary: "Delete an availability window", Scope: models.ScopeAdmin},
+	"GET /schedule/suggestions": {Summary: "Times when everybody is free", Scope: models.ScopePicnicsRead, Data: []models.Slot{},
+		Description: "Every user in user_ids must attend a picnic with the caller.",
+		Query: []openapi.Parameter{
+			userIDsParam,
+			queryParam("from", "string", "Start of the range, 2006-01-02 or 2006-01-02 15:04"),
+			queryParam("to", "string", "End of the range, a date only includes that day"),
+			queryParam("duration", "string", "Length of the picnic, 3h by default"),
+			queryParam("limit", "integer", "Number of suggestions, 5 by default"),
+		}},
+}
+
+type invitationAnswer struct {
+	Invitation models.Invitation `json:"invitation"`
+	Membership models.UserPicnic `json:"membership"`
+}
+
+type apiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+var apiSpec []byte
+
+// buildOpenAPI documents the v1 routes of r. Every route needs an entry in
+// apiOperations and every entry a route. The error lists the ones that
+// don't match, the document still has every route that does.
+func buildOpenAPI(routes gin.RoutesInfo) (*openapi.Document, error) {
+
+	doc := openapi.New(openapi.Info{
+		Title:       "Picnic API",
+		Version:     "1",
+		Description: "Errors come back as {\"error\": \"...\"} in the language of the Accept-Language header.",
+	})
+	doc.Servers = []openapi.Server{{URL: apiPrefix}}
+	for _, tag := range apiTags {
+		doc.Tags = append(doc.Tags, openapi.Tag{Name: tag})
+	}
+	doc.Components.SecuritySchemes["session"] = openapi.SecurityScheme{Type: "apiKey", In: "cookie", Name: sessionCookie,
+		Description: "Set by /auth/login and /auth/register. Writes with it must be JSON or send X-Requested-With."}
+	doc.Components.SecuritySchemes["token"] = openapi.SecurityScheme{Type: "http", Scheme: "bearer",
+		Description: "API token from /tokens/, limited to its scopes"}
+	errorSchema := doc.SchemaOf(apiErrorResponse{})
+
+	registered := make([]openapi.Route, 0)
+	missing := make([]string, 0)
+
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, apiPrefix+"/") {
+			continue
+		}
+		path := strings.TrimPrefix(route.Path, apiPrefix)
+		registered = append(registered, openapi.Route{Method: route.Method, Path: path})
+
+		operation, ok := apiOperations[route.Method+" "+path]
+		if !ok {
+			missing = append(missing, route.Method+" "+route.Path)
+			continue
+		}
+		doc.Add(route.Method, path, apiOperationSpec(doc, route.Method, path, operation, errorSchema))
+	}
+
+	stale := make([]string, 0)
+	for key := range apiOperations {
+		method, path, _ := strings.Cut(key, " ")
+		if !doc.Has(method, path) {
+			stale = append(stale, key)
+		}
+	}
+
+	// el doc sale igual, sin las rutas que faltan
+	if len(missing) > 0 || len(stale) > 0 {
+		sort.Strings(missing)
+		sort.Strings(stale)
+		return doc, fmt.Errorf("openapi: routes without docs %v, docs without a route %v", missing, stale)
+	}
+	return doc, nil
+}
+
+func apiOperationSpec(doc *openapi.Document, method string, path string, operation apiOperation, errorSchema *openapi.Schema) *openapi.Operation {
+
+	spec := &openapi.Operation{
+		Tags:        []string{operationTag(path)},
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		OperationID: operationID(method, path),
+		Parameters:  operation.Query,
+		Responses:   make(map[string]openapi.Response),
+	}
+
+	if operation.Scope != "" {
+		spec.Description = strings.TrimSpace(spec.Description + " Token scope: " + operation.Scope + ".")
+	}
+	spec.Security = []openapi.Requirement{{"session": {}}, {"token": {}}}
+	if operation.Anonymous {
+		spec.Security = append(spec.Security, openapi.Requirement{})
+	}
+
+	switch {
+	case operation.Upload != "":
+		spec.RequestBody = &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"multipart/form-data": {Schema: &openapi.Schema{
+				Type:       "object",
+				Required:   []string{operation.Upload},
+				Properties: map[string]*openapi.Schema{operation.Upload: {Type: "string", Format: "binary"}},
+			}},
+		}}
+	case operation.Body != nil:
+		spec.RequestBody = &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/json": {Schema: doc.SchemaOf(operation.Body)},
+		}}
+	}
+
+	success := openapi.Response{Description: "OK"}
+	switch {
+	case operation.ContentType != "":
+		success.Content = map[string]openapi.MediaType{operation.ContentType: {Schema: &openapi.Schema{}}}
+	case operation.Response != nil:
+		success.Content = map[string]openapi.MediaType{"application/json": {Schema: doc.SchemaOf(operation.Response)}}
+	default:
+		envelope := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+			"message": {Type: "string"},
+		}}
+		if operation.Data != nil {
+			envelope.Properties["data"] = doc.SchemaOf(operation.Data)
+		}
+		success.Content = map[string]openapi.MediaType{"application/json": {Schema: envelope}}
+	}
+	spec.Responses["200"] = success
+	spec.Responses["default"] = openapi.Response{
+		Description: "Error",
+		Content:     map[string]openapi.MediaType{"application/json": {Schema: errorSchema}},
+	}
+	return spec
+}
+
+// apiTags are the groups of the docs, in the order they're shown
+var apiTags = []string{"docs", "auth", "tokens", "users", "picnics", "invitations", "waitlist", "food-items", "contributions",
+	"graphql", "comments", "polls", "images", "gear", "inventory", "settlements", "availability", "live", "webhooks"}
+
+// tagAliases are path segments that belong to a group with another name
+var tagAliases = map[string]string{
+	"openapi.json": "docs",
+	"image":        "images",
+	"balances":     "settlements",
+	"schedule":     "availability",
+	"session":      "live",
+	"events":       "live",
+}
+
+// operationTag groups a route by the last segment of its path that names a
+// group, /picnics/:picnic_id/comments/:comment_id is "comments" and
+// /picnics/:picnic_id/owner "picnics".
+func operationTag(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		part := parts[i]
+		if alias, ok := tagAliases[part]; ok {
+			part = alias
+		}
+		for _, tag := range apiTags {
+			if part == tag {
+				return tag
+			}
+		}
+	}
+	return parts[0]
+}
+
+// operationID is the method and the literal parts of the path,
+// GET /picnics/:picnic_id/users -> getPicnicsUsers
+func operationID(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	if strings.HasSuffix(path, "_id") || strings.HasSuffix(path, ":token") {
+		id += "ById"
+	}
+	return id
+}
+
+// setupOpenAPI runs after every route is registered. A route without docs
+// is only logged and left out, TestOpenAPICoversEveryRoute is what fails.
+func setupOpenAPI(r *gin.Engine) error {
+
+	doc, err := buildOpenAPI(r.Routes())
+	if err != nil {
+		log.Print(err)
+	}
+	apiSpec, err = json.Marshal(doc)
+	return err
+}
+
+func readOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", apiSpec)
+}
+
+func apiDocsPage(c *gin.Context) {
+	renderPage(c, http.StatusOK, "api_docs", gin.H{"Title": tr(c, "API docs")})
+}
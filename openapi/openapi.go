@@ -0,0 +1,213 @@
+// Package openapi arma el documento OpenAPI 3 de la API. Los schemas salen
+// por reflection de los structs de models (los tags json), asi no hay que
+// escribirlos a mano y no se desincronizan.
+package openapi
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	Security   []Requirement       `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem maps a lowercase method to its operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	OperationID string              `json:"operationId,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	Security    []Requirement       `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Requirement is a security requirement, {} means anonymous is fine too
+type Requirement map[string][]string
+
+// Route is a registered route, Path in gin syntax (/picnics/:picnic_id)
+type Route struct {
+	Method string
+	Path   string
+}
+
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]SecurityScheme),
+		},
+	}
+}
+
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z_]+)`)
+
+// PathOf turns a gin path into an OpenAPI one, /picnics/:picnic_id ->
+// /picnics/{picnic_id}
+func PathOf(ginPath string) string {
+	return ginParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+// PathParams are the names of the params of a gin path in order
+func PathParams(ginPath string) []string {
+	names := make([]string, 0)
+	for _, match := range ginParam.FindAllStringSubmatch(ginPath, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// Add registers op under a gin path. The path params are added to the
+// operation when it doesn't declare them itself.
+func (d *Document) Add(method string, ginPath string, op *Operation) {
+
+	declared := make(map[string]bool)
+	for _, param := range op.Parameters {
+		if param.In == "path" {
+			declared[param.Name] = true
+		}
+	}
+
+	params := make([]Parameter, 0)
+	for _, name := range PathParams(ginPath) {
+		if declared[name] {
+			continue
+		}
+		schema := &Schema{Type: "string"}
+		if strings.HasSuffix(name, "_id") {
+			schema = &Schema{Type: "integer"}
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	op.Parameters = append(params, op.Parameters...)
+
+	path := PathOf(ginPath)
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(PathItem)
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+
+	for _, tag := range op.Tags {
+		d.addTag(tag)
+	}
+}
+
+func (d *Document) addTag(name string) {
+	for _, tag := range d.Tags {
+		if tag.Name == name {
+			return
+		}
+	}
+	d.Tags = append(d.Tags, Tag{Name: name})
+}
+
+// Has reports if method and gin path are documented
+func (d *Document) Has(method string, ginPath string) bool {
+	_, ok := d.Paths[PathOf(ginPath)][strings.ToLower(method)]
+	return ok
+}
+
+// Missing returns the routes that have no operation in the document
+func (d *Document) Missing(routes []Route) []Route {
+	missing := make([]Route, 0)
+	for _, route := range routes {
+		if !d.Has(route.Method, route.Path) {
+			missing = append(missing, route)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Path != missing[j].Path {
+			return missing[i].Path < missing[j].Path
+		}
+		return missing[i].Method < missing[j].Method
+	})
+	return missing
+}
+
+// Undocumented is the opposite of Missing, operations in the document that
+// no route serves anymore.
+func (d *Document) Undocumented(routes []Route) []Route {
+
+	served := make(map[string]bool)
+	for _, route := range routes {
+		served[strings.ToLower(route.Method)+" "+PathOf(route.Path)] = true
+	}
+
+	stale := make([]Route, 0)
+	for path, item := range d.Paths {
+		for method := range item {
+			if !served[method+" "+path] {
+				stale = append(stale, Route{Method: strings.ToUpper(method), Path: path})
+			}
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Path+stale[i].Method < stale[j].Path+stale[j].Method
+	})
+	return stale
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// toda ruta de /api/v1 tiene docs y todo doc tiene ruta
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+
+	doc, err := buildOpenAPI(newRouter().Routes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Has("POST", "/picnics/") {
+		t.Error("POST /picnics/ is not in the spec")
+	}
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"server/i18n"
+	"server/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Paginas HTML. Usan los mismos models que la API en /api/v1 y la sesion por
+// cookie, los formularios hacen POST y despues redirigen (post/redirect/get).
+
+// renderPage adds the logged in user for the navbar, the csrf token and the
+// language the templates are rendered in
+func renderPage(c *gin.Context, status int, name string, data gin.H) {
+	if data == nil {
+		data = gin.H{}
+	}
+	if user, ok := currentUser(c); ok {
+		data["CurrentUser"] = user
+	}
+	data["CSRFToken"] = c.GetString(csrfTokenKey)
+	data["Lang"] = langOf(c)
+	c.HTML(status, name, data)
+}
+
+// renderErrorPage translates message, pass it in English
+func renderErrorPage(c *gin.Context, status int, message string) {
+	c.Abort()
+	renderPage(c, status, "error_page", gin.H{
+		"Title":   errorTitle(c, status),
+		"Message": tr(c, message),
+	})
+}
+
+func errorTitle(c *gin.Context, status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return tr(c, "Something is wrong with that")
+	case http.StatusForbidden:
+		return tr(c, "Not allowed")
+	case http.StatusNotFound:
+		return tr(c, "Page not found")
+	case http.StatusConflict:
+		return tr(c, "Conflict")
+	default:
+		return tr(c, "Something went wrong")
+	}
+}
+
+func redirectTo(c *gin.Context, path string) {
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+// requirePageUser sends anonymous visitors to the login page and back
+func requirePageUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := currentUserID(c); !ok {
+			next := c.Request.URL.Path
+			if c.Request.Method != http.MethodGet {
+				next = c.GetHeader("Referer")
+			}
+			c.Abort()
+			redirectTo(c, "/login?next="+url.QueryEscape(next))
+			return
+		}
+		c.Next()
+	}
+}
+
+// pageID parses an id route param, a bad one is just a missing page
+func pageID(c *gin.Context, param string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil {
+		renderErrorPage(c, http.StatusNotFound, "There's nothing here.")
+		return 0, false
+	}
+	return id, true
+}
+
+// safeNext only follows local paths so the login form can't be used to
+// redirect somewhere else.
+func safeNext(next string) string {
+	if u, err := url.Parse(next); err == nil && u.Host == "" && u.Scheme == "" &&
+		strings.HasPrefix(u.Path, "/") && !strings.HasPrefix(next, "//") && !strings.HasPrefix(next, "/\\") {
+		return next
+	}
+	return "/picnics"
+}
+
+// parseAmount reads money typed in a form, "12.5" -> 1250 cents. The
+// separators are the ones of the page language.
+func parseAmount(c *gin.Context, value string) (int, error) {
+	return i18n.ParseMoney(langOf(c), value)
+}
+
+func homePage(c *gin.Context) {
+	redirectTo(c, "/picnics")
+}
+
+func loginPage(c *gin.Context) {
+	renderPage(c, http.StatusOK, "login", gin.H{"Title": tr(c, "Log in"), "Next": c.Query("next")})
+}
+
+func loginPageSubmit(c *gin.Context) {
+
+	name := c.PostForm("name")
+	next := c.PostForm("next")
+
+	user, err := models.CheckPassword(name, c.PostForm("password"))
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		renderPage(c, http.StatusUnauthorized, "login", gin.H{"Title": tr(c, "Log in"), "Next": next, "Name": name, "Error": trErr(c, err)})
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if err := openSession(c, user); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "We couldn't log you in, try again.")
+		return
+	}
+	redirectTo(c, safeNext(next))
+}
+
+func logoutPage(c *gin.Context) {
+
+	if err := closeSession(c); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "We couldn't log you out, try again.")
+		return
+	}
+	redirectTo(c, "/picnics")
+}
+
+func usersIndexPage(c *gin.Context) {
+
+	users, err := models.GetUsers()
+
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	renderPage(c, http.StatusOK, "users_index", gin.H{"Title": tr(c, "People"), "Users": users})
+}
+
+func showUserPage(c *gin.Context) {
+
+	id, ok := pageID(c, "user_id")
+	if !ok {
+		return
+	}
+
+	user, err := models.GetUserById(id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if user.Name == "" {
+		renderErrorPage(c, http.StatusNotFound, "That person doesn't exist.")
+		return
+	}
+
+	picnics, err := models.GetPicnicsByUser(id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	renderPage(c, http.StatusOK, "user_show", gin.H{
+		"Title":   user.Name,
+		"User":    user,
+		"Picnics": picnics,
+		"IsSelf":  userID == id,
+	})
+}
+
+func newUserPage(c *gin.Context) {
+	renderPage(c, http.StatusOK, "user_new", gin.H{"Title": tr(c, "Sign up"), "User": models.User{}})
+}
+
+// createUserPage registers the user and logs them in
+func createUserPage(c *gin.Context) {
+
+	// se queda con el idioma en el que vio el formulario
+	form := models.User{
+		Name:   strings.TrimSpace(c.PostForm("name")),
+		Email:  strings.TrimSpace(c.PostForm("email")),
+		Locale: langOf(c),
+	}
+
+	if form.Name == "" {
+		renderPage(c, http.StatusBadRequest, "user_new", gin.H{"Title": tr(c, "Sign up"), "User": form, "Error": tr(c, "Name can't be empty")})
+		return
+	}
+
+	user, err := models.RegisterUser(form, c.PostForm("password"))
+	if err != nil {
+		renderPage(c, http.StatusBadRequest, "user_new", gin.H{"Title": tr(c, "Sign up"), "User": form, "Error": trErr(c, err)})
+		return
+	}
+
+	if err := openSession(c, user); err != nil {
+		redirectTo(c, "/login")
+		return
+	}
+	redirectTo(c, fmt.Sprintf("/users/%d", user.ID))
+}
+
+// requirePageSelf is requireSelf for pages
+func requirePageSelf() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := currentUserID(c)
+		if c.Param("user_id") != strconv.Itoa(userID) {
+			renderErrorPage(c, http.StatusForbidden, "You can only change your own profile.")
+			return
+		}
+		c.Next()
+	}
+}
+
+func editUserPage(c *gin.Context) {
+	user, _ := currentUser(c)
+	renderPage(c, http.StatusOK, "user_edit", gin.H{"Title": tr(c, "Edit profile"), "User": user})
+}
+
+func updateUserPage(c *gin.Context) {
+
+	userID, _ := currentUserID(c)
+	form := models.User{
+		ID:     userID,
+		Name:   strings.TrimSpace(c.PostForm("name")),
+		Email:  strings.TrimSpace(c.PostForm("email")),
+		Locale: c.PostForm("locale"),
+	}
+
+	if form.Name == "" {
+		renderPage(c, http.StatusBadRequest, "user_edit", gin.H{"Title": tr(c, "Edit profile"), "User": form, "Error": tr(c, "Name can't be empty")})
+		return
+	}
+
+	_, err := models.UpdateUser(form, userID)
+	if err != nil {
+		renderPage(c, http.StatusBadRequest, "user_edit", gin.H{"Title": tr(c, "Edit profile"), "User": form, "Error": trErr(c, err)})
+		return
+	}
+	redirectTo(c, fmt.Sprintf("/users/%d", userID))
+}
+
+func foodItemsIndexPage(c *gin.Context) {
+
+	foodItems, err := models.GetFoodItems()
+
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	renderPage(c, http.StatusOK, "items_index", gin.H{"Title": tr(c, "Food"), "Items": foodItems})
+}
+
+func showFoodItemPage(c *gin.Context) {
+
+	id, ok := pageID(c, "item_id")
+	if !ok {
+		return
+	}
+
+	foodItem, err := models.GetFoodItemById(id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if foodItem.Name == "" {
+		renderErrorPage(c, http.StatusNotFound, "That food item doesn't exist.")
+		return
+	}
+	renderPage(c, http.StatusOK, "item_show", gin.H{"Title": foodItem.Name, "Item": foodItem})
+}
+
+func newFoodItemPage(c *gin.Context) {
+	renderPage(c, http.StatusOK, "item_new", gin.H{"Title": tr(c, "New item"), "Item": models.FoodItem{}})
+}
+
+func foodItemForm(c *gin.Context) models.FoodItem {
+	return models.FoodItem{
+		Name:    strings.TrimSpace(c.PostForm("name")),
+		Measure: strings.TrimSpace(c.PostForm("measure")),
+	}
+}
+
+func createFoodItemPage(c *gin.Context) {
+
+	form := foodItemForm(c)
+
+	if form.Name == "" {
+		renderPage(c, http.StatusBadRequest, "item_new", gin.H{"Title": tr(c, "New item"), "Item": form, "Error": tr(c, "Name can't be empty")})
+		return
+	}
+
+	_, err := models.CreateFoodItem(form)
+	if err != nil {
+		renderPage(c, http.StatusBadRequest, "item_new", gin.H{"Title": tr(c, "New item"), "Item": form, "Error": trErr(c, err)})
+		return
+	}
+	redirectTo(c, "/food-items")
+}
+
+func editFoodItemPage(c *gin.Context) {
+
+	id, ok := pageID(c, "item_id")
+	if !ok {
+		return
+	}
+
+	foodItem, err := models.GetFoodItemById(id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if foodItem.Name == "" {
+		renderErrorPage(c, http.StatusNotFound, "That food item doesn't exist.")
+		return
+	}
+	renderPage(c, http.StatusOK, "item_edit", gin.H{"Title": tr(c, "Edit %s", foodItem.Name), "Item": foodItem})
+}
+
+// updateFoodItemPage keeps the image, it is changed through the upload endpoint
+func updateFoodItemPage(c *gin.Context) {
+
+	id, ok := pageID(c, "item_id")
+	if !ok {
+		return
+	}
+
+	foodItem, err := models.GetFoodItemById(id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if foodItem.Name == "" {
+		renderErrorPage(c, http.StatusNotFound, "That food item doesn't exist.")
+		return
+	}
+
+	form := foodItemForm(c)
+	form.ID = id
+	form.Url = foodItem.Url
+
+	if form.Name == "" {
+		renderPage(c, http.StatusBadRequest, "item_edit", gin.H{"Title": tr(c, "Edit %s", foodItem.Name), "Item": form, "Error": tr(c, "Name can't be empty")})
+		return
+	}
+
+	_, err = models.UpdateFoodItem(form, id)
+	if err != nil {
+		renderPage(c, http.StatusBadRequest, "item_edit", gin.H{"Title": tr(c, "Edit %s", foodItem.Name), "Item": form, "Error": trErr(c, err)})
+		return
+	}
+	redirectTo(c, fmt.Sprintf("/food-items/%d", id))
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"net/http"
+	"server/live"
+	"server/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func currentUserID(c *gin.Context) (int, bool) {
+	userID, ok := c.Get(currentUserKey)
+	if !ok {
+		return 0, false
+	}
+	return userID.(int), true
+}
+
+func requireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := currentUserID(c); !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Authentication required")})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireSelf is for /users/:user_id routes that only the user may call
+func requireSelf() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Authentication required")})
+			return
+		}
+		if c.Param("user_id") != strconv.Itoa(userID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only change your own account")})
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasRole(role string, roles []string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// requirePicnicRole lets the request through only when the current user has
+// one of roles in the picnic named by the :picnic_id param.
+func requirePicnicRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authorizePicnic(c, roles, false) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireSelfOrPicnicRole is for routes with a :user_id param, the user can
+// always act on their own membership.
+func requireSelfOrPicnicRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authorizePicnic(c, roles, true) {
+			return
+		}
+		c.Next()
+	}
+}
+
+func authorizePicnic(c *gin.Context, roles []string, allowSelf bool) bool {
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Authentication required")})
+		return false
+	}
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return false
+	}
+
+	if allowSelf && c.Param("user_id") == strconv.Itoa(userID) {
+		return true
+	}
+
+	role, err := models.GetRole(userID, picnicID)
+	if err != nil {
+		serverError(c, err)
+		return false
+	}
+
+	if !hasRole(role, roles) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "You don't have permission to do that in this picnic")})
+		return false
+	}
+	return true
+}
+
+// canManageContribution writes the error response itself when it returns
+// false. Owners and co-hosts manage every contribution of their picnic,
+// attendees only their own.
+func canManageContribution(c *gin.Context, contribution models.Contribution) bool {
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Authentication required")})
+		return false
+	}
+
+	allowed, err := mayManageContribution(userID, contribution)
+	if err != nil {
+		serverError(c, err)
+		return false
+	}
+
+	if allowed {
+		return true
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only manage your own contributions")})
+	return false
+}
+
+func mayManageContribution(userID int, contribution models.Contribution) (bool, error) {
+	return mayManageItem(userID, contribution.PicnicID, contribution.UserID)
+}
+
+// mayManageItem is the rule for anything someone brings to a picnic:
+// owners and co-hosts manage all of it, attendees only what is theirs.
+func mayManageItem(userID int, picnicID int, bringerID int) (bool, error) {
+
+	role, err := models.GetRole(userID, picnicID)
+	if err != nil {
+		return false, err
+	}
+
+	if role == models.RoleOwner || role == models.RoleCoHost {
+		return true, nil
+	}
+	return role == models.RoleAttendee && bringerID == userID, nil
+}
+
+// requireContributionAccess checks the stored contribution behind :contribution_id
+func requireContributionAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		id, err := strconv.Atoi(c.Param("contribution_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid ID")})
+			return
+		}
+
+		contribution, err := models.GetContributionById(id)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if contribution.ID == 0 {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": tr(c, "Contribution of that id not found")})
+			return
+		}
+
+		if !canManageContribution(c, contribution) {
+			return
+		}
+		c.Next()
+	}
+}
+
+func transferOwnership(c *gin.Context) {
+
+	var json struct {
+		UserID int `json:"user_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	success, err := models.TransferOwnership(picnicID, json.UserID)
+
+	if success {
+		live.Publish(picnicID, models.EventMembershipUpdated, gin.H{"user_id": json.UserID, "picnic_id": picnicID, "role": models.RoleOwner})
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+func updateRole(c *gin.Context) {
+
+	var json struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid user ID")})
+		return
+	}
+
+	success, err := models.SetRole(userID, picnicID, json.Role)
+
+	if success {
+		live.Publish(picnicID, models.EventMembershipUpdated, gin.H{"user_id": userID, "picnic_id": picnicID, "role": json.Role})
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"server/models"
+	"testing"
+)
+
+// rolePicnic is a picnic with someone in every situation. bringer is an
+// attendee too, the contribution being edited is theirs.
+type rolePicnic struct {
+	picnicID     int
+	contribution models.Contribution
+	bringer      testUser
+	users        map[string]testUser
+}
+
+var roleNames = []string{"owner", "co-host", "attendee", "waitlisted", "declined", "outsider"}
+
+func newRolePicnic(t *testing.T) rolePicnic {
+	t.Helper()
+	must := mustOK(t)
+
+	users := make(map[string]testUser)
+	for _, name := range roleNames {
+		users[name] = newTestUser(t, name)
+	}
+	bringer := newTestUser(t, "bringer")
+
+	// owner, co-host, attendee y bringer llenan el picnic
+	picnicID, err := models.CreatePicnic(models.Picnic{Name: "Roles", Date: "2026-11-21 13:00", Capacity: 4, CreatedBy: users["owner"].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"co-host", "attendee", "waitlisted", "declined"} {
+		if name == "waitlisted" {
+			must(models.AddUserToPicnic(bringer.ID, picnicID))
+		}
+		must(models.AddUserToPicnic(users[name].ID, picnicID))
+	}
+	must(models.SetRole(users["co-host"].ID, picnicID, models.RoleCoHost))
+	must(models.DeclinePicnic(users["declined"].ID, picnicID))
+
+	membership, err := models.GetMembership(users["waitlisted"].ID, picnicID)
+	if err != nil || membership.Status != models.MembershipWaitlisted {
+		t.Fatalf("waitlisted user is %q, %v", membership.Status, err)
+	}
+
+	item := models.FoodItem{Name: fmt.Sprintf("Pan %d", picnicID), Measure: "loaves"}
+	must(models.CreateFoodItem(item))
+	items, err := models.GetFoodItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contribution := models.Contribution{UserID: bringer.ID, PicnicID: picnicID, FoodItemID: items[len(items)-1].ID, Quantity: 1}
+	contribution.ID, err = models.CreateContribution(contribution)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rolePicnic{picnicID, contribution, bringer, users}
+}
+
+func TestPicnicPermissions(t *testing.T) {
+
+	actions := []struct {
+		name    string
+		allowed []string
+		do      func(t *testing.T, p rolePicnic, u testUser) int
+	}{
+		{"update picnic", []string{"owner", "co-host"}, func(t *testing.T, p rolePicnic, u testUser) int {
+			return u.do(t, "PUT", fmt.Sprintf("/api/v1/picnics/%d", p.picnicID), models.Picnic{Name: "Renamed", Date: "2026-11-22 13:00", Capacity: 4}).Code
+		}},
+		{"delete picnic", []string{"owner"}, func(t *testing.T, p rolePicnic, u testUser) int {
+			return u.do(t, "DELETE", fmt.Sprintf("/api/v1/picnics/%d", p.picnicID), nil).Code
+		}},
+		{"transfer ownership", []string{"owner"}, func(t *testing.T, p rolePicnic, u testUser) int {
+			return u.do(t, "POST", fmt.Sprintf("/api/v1/picnics/%d/owner", p.picnicID), map[string]int{"user_id": p.bringer.ID}).Code
+		}},
+		{"edit contribution", []string{"owner", "co-host"}, func(t *testing.T, p rolePicnic, u testUser) int {
+			edited := p.contribution
+			edited.Quantity = 3
+			return u.do(t, "PUT", fmt.Sprintf("/api/v1/contributions/%d", p.contribution.ID), edited).Code
+		}},
+	}
+
+	for _, action := range actions {
+		for _, role := range roleNames {
+			action, role := action, role
+			t.Run(action.name+"/"+role, func(t *testing.T) {
+
+				p := newRolePicnic(t)
+				want := http.StatusForbidden
+				if hasRole(role, action.allowed) {
+					want = http.StatusOK
+				}
+
+				if got := action.do(t, p, p.users[role]); got != want {
+					t.Errorf("%s as %s: got %d, want %d", action.name, role, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestAttendeeEditsOwnContribution(t *testing.T) {
+
+	p := newRolePicnic(t)
+	edited := p.contribution
+	edited.Quantity = 2
+
+	if w := p.bringer.do(t, "PUT", fmt.Sprintf("/api/v1/contributions/%d", p.contribution.ID), edited); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestAnonymousCannotUpdatePicnic(t *testing.T) {
+
+	p := newRolePicnic(t)
+	w := testUser{}.do(t, "PUT", fmt.Sprintf("/api/v1/picnics/%d", p.picnicID), models.Picnic{Name: "Renamed"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("got %d, want 401", w.Code)
+	}
+}
+
+func TestAnonymousCannotCreateUsers(t *testing.T) {
+
+	u := newTestUser(t, "inviter")
+	name := fmt.Sprintf("friend-of-%d", u.ID)
+
+	if w := (testUser{}).do(t, "POST", "/api/v1/users/", models.Account{User: models.User{Name: name}}); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: got %d, want 401", w.Code)
+	}
+	if w := u.do(t, "POST", "/api/v1/users/", models.Account{User: models.User{Name: name}}); w.Code != http.StatusOK {
+		t.Errorf("logged in: got %d: %s", w.Code, w.Body)
+	}
+}
+
+// los que esperan lugar no participan hasta que entran
+func TestWaitlistedIsNotMember(t *testing.T) {
+
+	p := newRolePicnic(t)
+	for _, role := range []string{"waitlisted", "declined", "outsider"} {
+		w := p.users[role].do(t, "GET", fmt.Sprintf("/api/v1/picnics/%d/comments", p.picnicID), nil)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s reading comments: got %d, want 403", role, w.Code)
+		}
+	}
+	if w := p.users["attendee"].do(t, "GET", fmt.Sprintf("/api/v1/picnics/%d/comments", p.picnicID), nil); w.Code != http.StatusOK {
+		t.Errorf("attendee reading comments: got %d", w.Code)
+	}
+}
+
+// la lista de espera es de los que van, la membresia de cada uno es suya y
+// de quienes organizan
+func TestWaitlistAndMembershipAccess(t *testing.T) {
+
+	p := newRolePicnic(t)
+	waitlist := fmt.Sprintf("/api/v1/picnics/%d/waitlist", p.picnicID)
+	membershipOf := func(u testUser) string {
+		return fmt.Sprintf("/api/v1/users/%d/picnics/%d", u.ID, p.picnicID)
+	}
+
+	checks := []struct {
+		name string
+		user testUser
+		path string
+		want int
+	}{
+		{"attendee, waitlist", p.users["attendee"], waitlist, http.StatusOK},
+		{"waitlisted, waitlist", p.users["waitlisted"], waitlist, http.StatusForbidden},
+		{"outsider, waitlist", p.users["outsider"], waitlist, http.StatusForbidden},
+		{"anonymous, waitlist", testUser{}, waitlist, http.StatusUnauthorized},
+
+		{"own membership", p.users["waitlisted"], membershipOf(p.users["waitlisted"]), http.StatusOK},
+		{"co-host, membership of another", p.users["co-host"], membershipOf(p.users["waitlisted"]), http.StatusOK},
+		{"attendee, membership of another", p.users["attendee"], membershipOf(p.users["waitlisted"]), http.StatusForbidden},
+		{"outsider, membership of another", p.users["outsider"], membershipOf(p.users["attendee"]), http.StatusForbidden},
+	}
+	for _, check := range checks {
+		if w := check.user.do(t, "GET", check.path, nil); w.Code != check.want {
+			t.Errorf("%s: got %d, want %d: %s", check.name, w.Code, check.want, w.Body)
+		}
+	}
+}
+
+func TestBackfillOwners(t *testing.T) {
+
+	must := mustOK(t)
+	early, late := newTestUser(t, "early"), newTestUser(t, "late")
+
+	// un picnic de antes de los roles: sin created_by y todos attendee
+	picnicID, err := models.CreatePicnic(models.Picnic{Name: "Old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	must(models.AddUserToPicnic(early.ID, picnicID))
+	must(models.AddUserToPicnic(late.ID, picnicID))
+
+	// las migraciones corren al abrir la base
+	checkErr(models.OpenDatabase(testDB))
+
+	for user, want := range map[testUser]string{early: models.RoleOwner, late: models.RoleAttendee} {
+		role, err := models.GetRole(user.ID, picnicID)
+		if err != nil || role != want {
+			t.Errorf("%s is %q (%v), want %q", user.Name, role, err, want)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"server/i18n"
+	"server/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contributionRow is a contribution as the picnic page shows it
+type contributionRow struct {
+	Contribution models.Contribution
+	FoodItem     models.FoodItem
+	User         models.User
+	CanManage    bool
+}
+
+func picnicsIndexPage(c *gin.Context) {
+
+	picnics, err := models.GetPicnics()
+
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	renderPage(c, http.StatusOK, "picnics_index", gin.H{"Title": tr(c, "Picnics"), "Picnics": picnics})
+}
+
+// loadPagePicnic renders the not found page itself when it returns false
+func loadPagePicnic(c *gin.Context) (models.Picnic, bool) {
+
+	id, ok := pageID(c, "picnic_id")
+	if !ok {
+		return models.Picnic{}, false
+	}
+
+	picnic, err := models.GetPicnicById(id)
+	if err != nil {
+		serverError(c, err)
+		return models.Picnic{}, false
+	}
+
+	if picnic.Name == "" {
+		renderErrorPage(c, http.StatusNotFound, "That picnic doesn't exist.")
+		return models.Picnic{}, false
+	}
+	return picnic, true
+}
+
+// requirePagePicnicRole is requirePicnicRole with HTML errors
+func requirePagePicnicRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		picnicID, ok := pageID(c, "picnic_id")
+		if !ok {
+			return
+		}
+
+		userID, _ := currentUserID(c)
+		role, err := models.GetRole(userID, picnicID)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if !hasRole(role, roles) {
+			renderErrorPage(c, http.StatusForbidden, "You don't have permission to do that in this picnic.")
+			return
+		}
+		c.Next()
+	}
+}
+
+func showPicnicPage(c *gin.Context) {
+
+	picnic, ok := loadPagePicnic(c)
+	if !ok {
+		return
+	}
+	renderPicnicPage(c, http.StatusOK, picnic, models.Contribution{}, "")
+}
+
+// renderPicnicPage also shows the "bring something" form, with the values
+// and the error of a failed submit. Who's coming, what they bring and the
+// photos are only for members, everyone else sees when and where.
+func renderPicnicPage(c *gin.Context, status int, picnic models.Picnic, form models.Contribution, formError string) {
+
+	userID, _ := currentUserID(c)
+	role, err := models.GetRole(userID, picnic.ID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if !hasRole(role, []string{models.RoleOwner, models.RoleCoHost, models.RoleAttendee}) {
+		renderPage(c, status, "picnic_show", gin.H{"Title": picnic.Name, "Picnic": picnic})
+		return
+	}
+
+	users, err := models.GetUsersByPicnic(picnic.ID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	contributions, err := models.GetContributionsByPicnic(picnic.ID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	foodItems, err := models.GetFoodItems()
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	images, err := models.GetImagesByPicnic(picnic.ID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	foodItemsByID := make(map[int]models.FoodItem)
+	for _, foodItem := range foodItems {
+		foodItemsByID[foodItem.ID] = foodItem
+	}
+	usersByID := make(map[int]models.User)
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	rows := make([]contributionRow, 0, len(contributions))
+	for _, contribution := range contributions {
+		user, ok := usersByID[contribution.UserID]
+		if !ok {
+			// ya no es del picnic, pero lo que trajo sigue en la lista
+			user, err = models.GetUserById(contribution.UserID)
+			if err != nil {
+				serverError(c, err)
+				return
+			}
+		}
+		canManage, err := mayManageContribution(userID, contribution)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		rows = append(rows, contributionRow{
+			Contribution: contribution,
+			FoodItem:     foodItemsByID[contribution.FoodItemID],
+			User:         user,
+			CanManage:    canManage,
+		})
+	}
+
+	renderPage(c, status, "picnic_show", gin.H{
+		"Title":         picnic.Name,
+		"Picnic":        picnic,
+		"Users":         users,
+		"Contributions": rows,
+		"Images":        images,
+		"FoodItems":     foodItems,
+		"Contribution":  form,
+		"Error":         formError,
+		"CanEdit":       role == models.RoleOwner || role == models.RoleCoHost,
+		"CanDelete":     role == models.RoleOwner,
+		"IsMember":      true,
+	})
+}
+
+func picnicForm(c *gin.Context) (models.Picnic, error) {
+
+	picnic := models.Picnic{
+		Name:     strings.TrimSpace(c.PostForm("name")),
+		Location: strings.TrimSpace(c.PostForm("location")),
+		Date:     strings.TrimSpace(c.PostForm("date")),
+	}
+
+	if capacity := strings.TrimSpace(c.PostForm("capacity")); capacity != "" {
+		value, err := strconv.Atoi(capacity)
+		if err != nil || value < 0 {
+			return picnic, i18n.Errorf("capacity must be a number, 0 for no limit")
+		}
+		picnic.Capacity = value
+	}
+
+	if picnic.Name == "" {
+		return picnic, i18n.Errorf("name can't be empty")
+	}
+	return picnic, nil
+}
+
+func newPicnicPage(c *gin.Context) {
+	renderPage(c, http.StatusOK, "picnic_new", gin.H{"Title": tr(c, "New picnic"), "Picnic": models.Picnic{}})
+}
+
+func createPicnicPage(c *gin.Context) {
+
+	picnic, err := picnicForm(c)
+	if err != nil {
+		renderPage(c, http.StatusBadRequest, "picnic_new", gin.H{"Title": tr(c, "New picnic"), "Picnic": picnic, "Error": trErr(c, err)})
+		return
+	}
+
+	// quien lo crea queda como owner
+	picnic.CreatedBy, _ = currentUserID(c)
+
+	id, err := models.CreatePicnic(picnic)
+	if err != nil {
+		renderPage(c, http.StatusBadRequest, "picnic_new", gin.H{"Title": tr(c, "New picnic"), "Picnic": picnic, "Error": trErr(c, err)})
+		return
+	}
+
+	picnic.ID = id
+	picnicCreated(picnic)
+	redirectTo(c, fmt.Sprintf("/picnics/%d", id))
+}
+
+func editPicnicPage(c *gin.Context) {
+
+	picnic, ok := loadPagePicnic(c)
+	if !ok {
+		return
+	}
+	renderPage(c, http.StatusOK, "picnic_edit", gin.H{"Title": tr(c, "Edit %s", picnic.Name), "Picnic": picnic})
+}
+
+func updatePicnicPage(c *gin.Context) {
+
+	before, ok := loadPagePicnic(c)
+	if !ok {
+		return
+	}
+
+	picnic, err := picnicForm(c)
+	picnic.ID = before.ID
+	picnic.CreatedBy = before.CreatedBy
+	if err != nil {
+		renderPage(c, http.StatusBadRequest, "picnic_edit", gin.H{"Title": tr(c, "Edit %s", before.Name), "Picnic": picnic, "Error": trErr(c, err)})
+		return
+	}
+
+	_, err = models.UpdatePicnic(picnic, picnic.ID)
+	if err != nil {
+		renderPage(c, http.StatusBadRequest, "picnic_edit", gin.H{"Title": tr(c, "Edit %s", before.Name), "Picnic": picnic, "Error": trErr(c, err)})
+		return
+	}
+
+	editorID, _ := currentUserID(c)
+	picnicUpdated(before, picnic, editorID)
+	redirectTo(c, fmt.Sprintf("/picnics/%d", picnic.ID))
+}
+
+func deletePicnicPage(c *gin.Context) {
+
+	picnic, ok := loadPagePicnic(c)
+	if !ok {
+		return
+	}
+
+	_, err := removePicnic(picnic.ID)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, trErr(c, err))
+		return
+	}
+
+	redirectTo(c, "/picnics")
+}
+
+// contributionForm reads the fields a person can change, who brings it and
+// to which picnic comes from the route or the stored contribution.
+func contributionForm(c *gin.Context, contribution models.Contribution) (models.Contribution, error) {
+
+	foodItemID, err := strconv.Atoi(c.PostForm("food_item_id"))
+	if err != nil {
+		return contribution, i18n.Errorf("pick something to bring")
+	}
+	contribution.FoodItemID = foodItemID
+
+	quantity, err := strconv.Atoi(c.PostForm("quantity"))
+	if err != nil || quantity < 1 {
+		return contribution, i18n.Errorf("quantity must be at least 1")
+	}
+	contribution.Quantity = quantity
+
+	contribution.AmountPaid, err = parseAmount(c, c.PostForm("amount_paid"))
+	if err != nil {
+		return contribution, err
+	}
+
+	foodItem, err := models.GetFoodItemById(foodItemID)
+	if err != nil {
+		return contribution, err
+	}
+	if foodItem.Name == "" {
+		return contribution, i18n.Errorf("food item %d not found", foodItemID)
+	}
+	return contribution, nil
+}
+
+func createContributionPage(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.PostForm("picnic_id"))
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "The form didn't say which picnic this is for.")
+		return
+	}
+
+	picnic, err := models.GetPicnicById(picnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if picnic.Name == "" {
+		renderErrorPage(c, http.StatusNotFound, "That picnic doesn't exist.")
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	contribution, err := contributionForm(c, models.Contribution{UserID: userID, PicnicID: picnicID})
+	if err != nil {
+		renderPicnicPage(c, http.StatusBadRequest, picnic, contribution, trErr(c, err))
+		return
+	}
+
+	allowed, err := mayManageContribution(userID, contribution)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if !allowed {
+		renderErrorPage(c, http.StatusForbidden, "Only people coming to the picnic can bring something.")
+		return
+	}
+
+	id, err := models.CreateContribution(contribution)
+	if err != nil {
+		renderPicnicPage(c, http.StatusBadRequest, picnic, contribution, trErr(c, err))
+		return
+	}
+
+	contribution.ID = id
+	contribution.Version = 1
+	contributionCreated(contribution)
+	redirectTo(c, fmt.Sprintf("/picnics/%d", picnicID))
+}
+
+// requirePageContribution loads :contribution_id into "contribution" when the
+// current user may manage it.
+func requirePageContribution() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		id, ok := pageID(c, "contribution_id")
+		if !ok {
+			return
+		}
+
+		contribution, err := models.GetContributionById(id)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if contribution.ID == 0 {
+			renderErrorPage(c, http.StatusNotFound, "That contribution doesn't exist.")
+			return
+		}
+
+		userID, _ := currentUserID(c)
+		allowed, err := mayManageContribution(userID, contribution)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if !allowed {
+			renderErrorPage(c, http.StatusForbidden, "You can only change what you bring.")
+			return
+		}
+
+		c.Set("contribution", contribution)
+		c.Next()
+	}
+}
+
+func renderContributionEditPage(c *gin.Context, status int, contribution models.Contribution, formError string) {
+
+	picnic, err := models.GetPicnicById(contribution.PicnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	foodItems, err := models.GetFoodItems()
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	renderPage(c, status, "contribution_edit", gin.H{
+		"Title":        tr(c, "Edit contribution"),
+		"Picnic":       picnic,
+		"Contribution": contribution,
+		"FoodItems":    foodItems,
+		"Error":        formError,
+	})
+}
+
+func editContributionPage(c *gin.Context) {
+	renderContributionEditPage(c, http.StatusOK, c.MustGet("contribution").(models.Contribution), "")
+}
+
+// updateContributionPage sends the version the form was loaded with, so an
+// edit made meanwhile by someone else isn't silently overwritten.
+func updateContributionPage(c *gin.Context) {
+
+	before := c.MustGet("contribution").(models.Contribution)
+
+	contribution, err := contributionForm(c, before)
+	if err != nil {
+		renderContributionEditPage(c, http.StatusBadRequest, contribution, trErr(c, err))
+		return
+	}
+
+	version, err := strconv.Atoi(c.PostForm("version"))
+	if err != nil {
+		version = before.Version
+	}
+
+	saved, err := models.UpdateContributionAtVersion(contribution, before.ID, version)
+	if err == models.ErrVersionConflict {
+		renderContributionEditPage(c, http.StatusConflict, saved, tr(c, "Someone changed this while you were editing, these are the current values."))
+		return
+	}
+	if err != nil {
+		renderContributionEditPage(c, http.StatusBadRequest, contribution, trErr(c, err))
+		return
+	}
+
+	editorID, _ := currentUserID(c)
+	contributionUpdated(before, saved, editorID)
+	redirectTo(c, fmt.Sprintf("/picnics/%d", saved.PicnicID))
+}
+
+func deleteContributionPage(c *gin.Context) {
+
+	contribution := c.MustGet("contribution").(models.Contribution)
+
+	_, err := models.DeleteContribution(contribution.ID)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, trErr(c, err))
+		return
+	}
+
+	contributionDeleted(contribution)
+	redirectTo(c, fmt.Sprintf("/picnics/%d", contribution.PicnicID))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// getPage renders path as u would see it in the browser
+func getPage(t *testing.T, u testUser, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", path, nil)
+	if u.session != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: u.session})
+	}
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestPicnicPageShowsPeopleOnlyToMembers(t *testing.T) {
+
+	p := newRolePicnic(t)
+	path := fmt.Sprintf("/picnics/%d", p.picnicID)
+	food := fmt.Sprintf("Pan %d", p.picnicID)
+
+	w := getPage(t, p.users["attendee"], path)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), p.bringer.Name) || !strings.Contains(w.Body.String(), food) {
+		t.Errorf("attendee: got %d without the bringer or the food:\n%s", w.Code, w.Body)
+	}
+
+	for _, u := range []testUser{p.users["outsider"], p.users["waitlisted"], {}} {
+		w := getPage(t, u, path)
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, "Roles") {
+			t.Errorf("%q: got %d without the picnic", u.Name, w.Code)
+		}
+		if strings.Contains(body, p.bringer.Name) || strings.Contains(body, food) {
+			t.Errorf("%q sees who comes and what they bring:\n%s", u.Name, body)
+		}
+	}
+}
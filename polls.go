@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http"
+	"server/live"
+	"server/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requirePoll loads :poll_id, it has to belong to :picnic_id
+func requirePoll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+			return
+		}
+
+		id, err := strconv.Atoi(c.Param("poll_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid poll ID")})
+			return
+		}
+
+		poll, err := models.GetPollById(id)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		if poll.ID == 0 || poll.PicnicID != picnicID {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": tr(c, "Poll of that id not found")})
+			return
+		}
+
+		c.Set("poll", poll)
+		c.Next()
+	}
+}
+
+func addPoll(c *gin.Context) {
+
+	var json models.Poll
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	json.PicnicID = picnicID
+	json.CreatedBy, _ = currentUserID(c)
+
+	poll, err := models.CreatePoll(json)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	live.Publish(picnicID, models.EventPollCreated, poll)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": poll})
+}
+
+func readAllPollsOfPicnic(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	polls, err := models.GetPollsByPicnic(picnicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve polls")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": polls})
+}
+
+// readPoll also returns what the current user voted, as option id -> vote
+func readPoll(c *gin.Context) {
+
+	poll := c.MustGet("poll").(models.Poll)
+	userID, _ := currentUserID(c)
+
+	votes, err := models.GetPollVotesOfUser(poll.ID, userID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": poll, "my_votes": votes})
+}
+
+// PUT /picnics/:picnic_id/polls/:poll_id/votes
+// {"votes": [{"option_id": 1, "vote": "yes"}, {"option_id": 2, "vote": "maybe"}]}
+func votePoll(c *gin.Context) {
+
+	var json struct {
+		Votes []models.PollVote `json:"votes" binding:"required,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	poll := c.MustGet("poll").(models.Poll)
+	userID, _ := currentUserID(c)
+
+	success, err := models.Vote(poll.ID, userID, json.Votes)
+	if !success {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	poll, err = models.GetPollById(poll.ID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	live.Publish(poll.PicnicID, models.EventPollVoted, poll)
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": poll})
+}
+
+// closePoll writes the winners into the picnic, so everyone gets the same
+// notifications as with a manual updatePicnic.
+func closePoll(c *gin.Context) {
+
+	poll := c.MustGet("poll").(models.Poll)
+
+	closed, before, err := models.ClosePoll(poll.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	picnic, err := models.GetPicnicById(closed.PicnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	editorID, _ := currentUserID(c)
+	live.Publish(closed.PicnicID, models.EventPollClosed, closed)
+	picnicUpdated(before, picnic, editorID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": gin.H{"poll": closed, "picnic": picnic}})
+}
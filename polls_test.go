@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"server/models"
+	"testing"
+)
+
+func newDatePoll(t *testing.T, p rolePicnic) models.Poll {
+	t.Helper()
+
+	poll, err := models.CreatePoll(models.Poll{PicnicID: p.picnicID, Title: "When?", CreatedBy: p.users["owner"].ID, Options: []models.PollOption{
+		{Date: "2026-11-21 13:00", Location: "Park"},
+		{Date: "2026-12-05 13:00", Location: "Beach"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	must := mustOK(t)
+	must(models.Vote(poll.ID, p.users["attendee"].ID, []models.PollVote{{OptionID: poll.Options[1].ID, Vote: models.VoteYes}}))
+	return poll
+}
+
+func TestClosePollMovesPicnic(t *testing.T) {
+
+	p := newRolePicnic(t)
+	poll := newDatePoll(t, p)
+
+	w := p.users["owner"].do(t, "POST", fmt.Sprintf("/api/v1/picnics/%d/polls/%d/close", p.picnicID, poll.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+
+	picnic, err := models.GetPicnicById(p.picnicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameDate(picnic.Date, "2026-12-05 13:00") || picnic.Location != "Beach" {
+		t.Errorf("picnic is on %s at %q", picnic.Date, picnic.Location)
+	}
+}
+
+// si el picnic no se puede actualizar el poll sigue abierto
+func TestClosePollIsAtomic(t *testing.T) {
+
+	p := newRolePicnic(t)
+	poll := newDatePoll(t, p)
+
+	trigger := fmt.Sprintf("fail_picnic_%d", p.picnicID)
+	_, err := models.DB.Exec(fmt.Sprintf("CREATE TRIGGER %s BEFORE UPDATE ON picnics WHEN NEW.id = %d BEGIN SELECT RAISE(ABORT, 'picnic is locked'); END", trigger, p.picnicID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer models.DB.Exec("DROP TRIGGER " + trigger)
+
+	if _, _, err := models.ClosePoll(poll.ID); err == nil {
+		t.Fatal("ClosePoll worked with a locked picnic")
+	}
+
+	stored, err := models.GetPollById(poll.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.PollOpen {
+		t.Errorf("poll is %s after a failed close", stored.Status)
+	}
+}
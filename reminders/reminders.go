@@ -0,0 +1,210 @@
+// Package reminders sends attendees a heads-up before their picnics. The
+// Engine runs inside the server and delivers through pluggable Channels.
+package reminders
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"server/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultOffsets are used when REMINDER_OFFSETS is not set
+var DefaultOffsets = []time.Duration{72 * time.Hour, 2 * time.Hour}
+
+type Item struct {
+	Quantity int
+	Measure  string
+	Name     string
+}
+
+type Reminder struct {
+	User   models.User
+	Picnic models.Picnic
+	Offset time.Duration
+	// tiempo real hasta el picnic, puede ser menos que Offset
+	StartsIn time.Duration
+	// lo que lleva este usuario al picnic
+	Items []Item
+}
+
+// Channel delivers a reminder. Name is stored with every sent reminder, so a
+// renamed channel sends its reminders again.
+type Channel interface {
+	Name() string
+	Send(reminder Reminder) error
+}
+
+type LogChannel struct{}
+
+func (LogChannel) Name() string { return "log" }
+
+func (LogChannel) Send(reminder Reminder) error {
+	log.Printf("reminder for %s: %s starts in %s", reminder.User.Name, reminder.Picnic.Name, reminder.StartsIn.Round(time.Minute))
+	return nil
+}
+
+type Engine struct {
+	Offsets  []time.Duration
+	Channels []Channel
+	Interval time.Duration
+}
+
+// OffsetsFromEnv parses REMINDER_OFFSETS, e.g. "72h,2h".
+func OffsetsFromEnv() ([]time.Duration, error) {
+	raw := os.Getenv("REMINDER_OFFSETS")
+	if raw == "" {
+		return DefaultOffsets, nil
+	}
+
+	offsets := make([]time.Duration, 0)
+	for _, part := range strings.Split(raw, ",") {
+		offset, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("REMINDER_OFFSETS: %v", err)
+		}
+		if offset <= 0 {
+			return nil, fmt.Errorf("REMINDER_OFFSETS: %s must be positive", part)
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets, nil
+}
+
+// Run checks for due reminders every Interval until stop is closed.
+func (e *Engine) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.RunOnce(time.Now()); err != nil {
+			log.Printf("reminders: %v", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends every reminder due at now. When several offsets are due for
+// the same picnic (the server was down, or the picnic was created late) only
+// the closest one goes out.
+func (e *Engine) RunOnce(now time.Time) error {
+
+	if len(e.Offsets) == 0 {
+		return nil
+	}
+
+	offsets := append([]time.Duration(nil), e.Offsets...)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	picnics, err := models.GetUpcomingPicnics(offsets[len(offsets)-1])
+	if err != nil {
+		return err
+	}
+
+	for _, picnic := range picnics {
+		date, err := models.ParseDate(picnic.Date)
+		if err != nil {
+			log.Printf("reminders: picnic %d: %v", picnic.ID, err)
+			continue
+		}
+
+		startsIn := date.Sub(now)
+		offset, ok := dueOffset(offsets, startsIn)
+		if !ok {
+			continue
+		}
+
+		users, err := models.GetUsersByPicnic(picnic.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := e.remind(user, picnic, offset, startsIn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dueOffset picks the smallest offset that is already due
+func dueOffset(sorted []time.Duration, untilStart time.Duration) (time.Duration, bool) {
+	for _, offset := range sorted {
+		if untilStart <= offset {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+func (e *Engine) remind(user models.User, picnic models.Picnic, offset time.Duration, startsIn time.Duration) error {
+
+	var reminder *Reminder
+
+	for _, channel := range e.Channels {
+		claimed, err := models.ClaimReminder(picnic.ID, user.ID, offset, channel.Name())
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		if reminder == nil {
+			r, err := buildReminder(user, picnic, offset, startsIn)
+			if err != nil {
+				return err
+			}
+			reminder = &r
+		}
+
+		if err := channel.Send(*reminder); err != nil {
+			log.Printf("reminders: %s to user %d: %v", channel.Name(), user.ID, err)
+			if err := models.ReleaseReminder(picnic.ID, user.ID, offset, channel.Name()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func buildReminder(user models.User, picnic models.Picnic, offset time.Duration, startsIn time.Duration) (Reminder, error) {
+
+	contributions, err := models.GetContributionsByUserAndPicnic(user.ID, picnic.ID)
+	if err != nil {
+		return Reminder{}, err
+	}
+
+	items := make([]Item, 0, len(contributions))
+	for _, contribution := range contributions {
+		foodItem, err := models.GetFoodItemById(contribution.FoodItemID)
+		if err != nil {
+			return Reminder{}, err
+		}
+		items = append(items, Item{Quantity: contribution.Quantity, Measure: foodItem.Measure, Name: foodItem.Name})
+	}
+
+	gear, err := models.GetGearAssignmentsByUserAndPicnic(user.ID, picnic.ID)
+	if err != nil {
+		return Reminder{}, err
+	}
+
+	for _, assignment := range gear {
+		gearItem, err := models.GetGearItemById(assignment.GearItemID)
+		if err != nil {
+			return Reminder{}, err
+		}
+		items = append(items, Item{Quantity: assignment.Quantity, Name: gearItem.Name})
+	}
+
+	return Reminder{User: user, Picnic: picnic, Offset: offset, StartsIn: startsIn, Items: items}, nil
+}
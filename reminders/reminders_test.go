@@ -0,0 +1,78 @@
+package reminders
+
+import (
+	"os"
+	"path/filepath"
+	"server/models"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+
+	models.PasswordCost = bcrypt.MinCost
+
+	dir, err := os.MkdirTemp("", "picnic-reminders-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := models.OpenDatabase(filepath.Join(dir, "test.db")); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	models.DB.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// recordChannel keeps what it was asked to send
+type recordChannel struct {
+	sent []Reminder
+}
+
+func (*recordChannel) Name() string { return "record" }
+
+func (r *recordChannel) Send(reminder Reminder) error {
+	r.sent = append(r.sent, reminder)
+	return nil
+}
+
+func TestRunOnceSendsEachOffsetOnce(t *testing.T) {
+
+	user, err := models.RegisterUser(models.User{Name: "reminded", Email: "reminded@example.com"}, "secretpw1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// uno entra en la ventana de 2h y el otro en la de 72h
+	now := time.Now().UTC()
+	soon, later := now.Add(time.Hour), now.Add(48*time.Hour)
+	picnicOffsets := make(map[int]time.Duration)
+	for date, offset := range map[time.Time]time.Duration{soon: 2 * time.Hour, later: 72 * time.Hour} {
+		picnicID, err := models.CreatePicnic(models.Picnic{Name: "Reminded", Date: date.Format("2006-01-02 15:04"), CreatedBy: user.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		picnicOffsets[picnicID] = offset
+	}
+
+	channel := &recordChannel{}
+	engine := Engine{Offsets: []time.Duration{72 * time.Hour, 2 * time.Hour}, Channels: []Channel{channel}}
+	for i := 0; i < 2; i++ {
+		if err := engine.RunOnce(now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(channel.sent) != len(picnicOffsets) {
+		t.Fatalf("sent %d reminders, want %d", len(channel.sent), len(picnicOffsets))
+	}
+	for _, reminder := range channel.sent {
+		if want := picnicOffsets[reminder.Picnic.ID]; reminder.Offset != want || reminder.User.ID != user.ID {
+			t.Errorf("picnic %d: sent the %s reminder to user %d, want %s to %d", reminder.Picnic.ID, reminder.Offset, reminder.User.ID, want, user.ID)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"net/http"
+	"server/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func addSettlement(c *gin.Context) {
+
+	var json models.Settlement
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	// solo se anotan pagos propios, y con alguien del mismo picnic
+	userID, _ := currentUserID(c)
+	if json.FromUserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "You can only record payments you made")})
+		return
+	}
+
+	shared, err := settlementShared(json)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": trErr(c, err)})
+		return
+	}
+	if !shared {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Both users must be in the picnic")})
+		return
+	}
+
+	success, err := models.CreateSettlement(json)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+// settlementShared checks both users are in the picnic of the payment, or
+// in some picnic together when it isn't tied to one
+func settlementShared(settlement models.Settlement) (bool, error) {
+
+	if settlement.PicnicID == 0 {
+		return models.SharePicnic(settlement.FromUserID, settlement.ToUserID)
+	}
+
+	for _, userID := range []int{settlement.FromUserID, settlement.ToUserID} {
+		role, err := models.GetRole(userID, settlement.PicnicID)
+		if err != nil || role == "" {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func readPicnicBalances(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	picnic, err := models.GetPicnicById(picnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if picnic.Name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Picnic of that id not found")})
+		return
+	}
+
+	settleUp, err := models.GetPicnicSettleUp(picnicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to compute balances")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": settleUp})
+}
+
+// GET /balances?user_ids=1,2,3 settles everything the given users did
+// together, they must all share a picnic with the caller
+func readSharedBalances(c *gin.Context) {
+
+	userIDs, ok := parseUserIDs(c)
+	if !ok {
+		return
+	}
+	if !requirePicnicMates(c, userIDs, "You can only see the balances of people who share a picnic with you") {
+		return
+	}
+
+	settleUp, err := models.GetSharedSettleUp(userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to compute balances")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": settleUp})
+}
+
+// parseUserIDs reads ?user_ids=1,2,3 and writes the error response itself
+func parseUserIDs(c *gin.Context) ([]int, bool) {
+
+	userIDs := make([]int, 0)
+	for _, raw := range strings.Split(c.Query("user_ids"), ",") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid user ID")})
+			return nil, false
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	if len(userIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "user_ids is required")})
+		return nil, false
+	}
+	return userIDs, true
+}
+
+func readAllSettlementsOfUser(c *gin.Context) {
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid user ID")})
+		return
+	}
+
+	user, err := models.GetUserById(userID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if user.Name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User of that id not found")})
+		return
+	}
+
+	settlements, err := models.GetSettlementsByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve settlements")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": settlements})
+}
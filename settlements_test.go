@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBalancesOnlyForPicnicMates(t *testing.T) {
+
+	p := newRolePicnic(t)
+	attendee, outsider := p.users["attendee"], p.users["outsider"]
+
+	checks := []struct {
+		name string
+		user testUser
+		path string
+		want int
+	}{
+		{"attendee, picnic balances", attendee, fmt.Sprintf("/api/v1/picnics/%d/balances", p.picnicID), http.StatusOK},
+		{"outsider, picnic balances", outsider, fmt.Sprintf("/api/v1/picnics/%d/balances", p.picnicID), http.StatusForbidden},
+		{"anonymous, picnic balances", testUser{}, fmt.Sprintf("/api/v1/picnics/%d/balances", p.picnicID), http.StatusUnauthorized},
+
+		{"own settlements", attendee, fmt.Sprintf("/api/v1/users/%d/settlements", attendee.ID), http.StatusOK},
+		{"settlements of someone else", outsider, fmt.Sprintf("/api/v1/users/%d/settlements", attendee.ID), http.StatusForbidden},
+
+		{"shared balances with picnic mates", attendee, "/api/v1/balances?user_ids=" + joinIDs([]int{attendee.ID, p.users["owner"].ID}), http.StatusOK},
+		{"shared balances with an outsider", attendee, "/api/v1/balances?user_ids=" + joinIDs([]int{attendee.ID, outsider.ID}), http.StatusForbidden},
+		{"shared balances of others", outsider, "/api/v1/balances?user_ids=" + joinIDs([]int{attendee.ID, p.users["owner"].ID}), http.StatusForbidden},
+		{"anonymous, shared balances", testUser{}, "/api/v1/balances?user_ids=" + joinIDs([]int{attendee.ID}), http.StatusUnauthorized},
+	}
+	for _, check := range checks {
+		if w := check.user.do(t, "GET", check.path, nil); w.Code != check.want {
+			t.Errorf("%s: got %d, want %d: %s", check.name, w.Code, check.want, w.Body)
+		}
+	}
+}
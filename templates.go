@@ -1,35 +1,208 @@
 package main
 
 import (
+	"embed"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
+	"server/i18n"
+	"server/models"
 	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
 )
 
-var templates *template.Template
+// Los templates y assets van dentro del binario. Con DEV_MODE=1 se leen de
+// disco (desde la raiz del repo) y los templates se vuelven a parsear cuando
+// cambia algun archivo.
+var devMode = os.Getenv("DEV_MODE") != ""
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// templateFuncs are bound to one language, every language gets its own
+// parsed copy of the templates.
+func templateFuncs(lang string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			return i18n.T(lang, key, args...)
+		},
+		"money": func(cents int) string {
+			return i18n.Money(lang, cents)
+		},
+		"number": func(n int) string {
+			return i18n.Number(lang, n)
+		},
+		"date": func(value string) string {
+			date, err := models.ParseDate(value)
+			if err != nil {
+				return value
+			}
+			return i18n.Date(lang, date)
+		},
+		"unit": func(measure string) string {
+			return i18n.Unit(lang, measure)
+		},
+		"lang": func() string {
+			return lang
+		},
+		"languages": languageOptions,
+		"asset":     assetURL,
+	}
+}
+
+type languageOption struct {
+	Code string
+	Name string
+}
+
+func languageOptions() []languageOption {
+	options := make([]languageOption, 0, len(i18n.Supported))
+	for _, code := range i18n.Supported {
+		options = append(options, languageOption{Code: code, Name: i18n.Names[code]})
+	}
+	return options
+}
+
+var siteTemplates = newTemplateSet()
+
+func newTemplateSet() *templateSet {
+	if devMode {
+		return &templateSet{fsys: os.DirFS("templates"), reload: true}
+	}
+
+	fsys, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		log.Fatal(err)
+	}
+	set := &templateSet{fsys: fsys}
+	if err := set.parse(); err != nil {
+		log.Fatal(err)
+	}
+	return set
+}
+
+// templateSet holds the html pages and emails and the plain text versions
+// of the emails, parsed once per language.
+type templateSet struct {
+	fsys   fs.FS
+	reload bool
+
+	mu       sync.Mutex
+	html     map[string]*template.Template
+	text     map[string]*texttemplate.Template
+	modified time.Time
+}
+
+// get returns the parsed templates, in dev mode it first parses them again
+// if a file changed. A template that doesn't parse keeps the last good ones
+// and is logged.
+func (t *templateSet) get(lang string) (*template.Template, *texttemplate.Template) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.reload {
+		modified, err := latestModTime(t.fsys)
+		if err != nil {
+			log.Printf("templates: %v", err)
+		} else if t.html == nil || modified.After(t.modified) {
+			if err := t.parse(); err != nil {
+				log.Printf("templates: %v", err)
+			}
+			t.modified = modified
+		}
+	}
+	if !i18n.IsSupported(lang) {
+		lang = i18n.Default
+	}
+	return t.html[lang], t.text[lang]
+}
+
+func (t *templateSet) parse() error {
+
+	htmlFiles, err := templateFiles(t.fsys, ".html")
+	if err != nil {
+		return err
+	}
+	// versiones en texto plano de los mails, las html van en html
+	textFiles, err := templateFiles(t.fsys, ".txt")
+	if err != nil {
+		return err
+	}
+
+	htmlSets := make(map[string]*template.Template)
+	textSets := make(map[string]*texttemplate.Template)
+	for _, lang := range i18n.Supported {
+		funcs := templateFuncs(lang)
+
+		htmlSets[lang], err = template.New("").Funcs(funcs).ParseFS(t.fsys, htmlFiles...)
+		if err != nil {
+			return err
+		}
+		textSets[lang], err = texttemplate.New("").Funcs(texttemplate.FuncMap(funcs)).ParseFS(t.fsys, textFiles...)
+		if err != nil {
+			return err
+		}
+	}
 
-func init() {
-	files := loadTemplates("templates")
-	templates = template.Must(template.ParseFiles(files...))
+	t.html, t.text = htmlSets, textSets
+	return nil
 }
 
-func loadTemplates(templateDir string) []string {
+func templateFiles(fsys fs.FS, suffix string) ([]string, error) {
 	result := make([]string, 0)
-	fileSystem := os.DirFS(templateDir)
-	fs.WalkDir(fileSystem, ".", func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		if !d.IsDir() && strings.HasSuffix(d.Name(), ".html") {
-			result = append(result, filepath.Join(templateDir, path))
+		if !d.IsDir() && strings.HasSuffix(d.Name(), suffix) {
+			result = append(result, path)
 		}
 
 		return nil
 	})
+	if err == nil && len(result) == 0 {
+		err = fmt.Errorf("no %s templates found", suffix)
+	}
+	return result, err
+}
+
+func latestModTime(fsys fs.FS) (time.Time, error) {
+	var latest time.Time
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
 
-	return result
+// templateRender is gin's HTMLRender on top of siteTemplates, renderPage
+// puts the language in data["Lang"].
+type templateRender struct{}
+
+func (templateRender) Instance(name string, data interface{}) render.Render {
+	lang := i18n.Default
+	if h, ok := data.(gin.H); ok {
+		if value, ok := h["Lang"].(string); ok {
+			lang = value
+		}
+	}
+	html, _ := siteTemplates.get(lang)
+	return render.HTML{Template: html, Name: name, Data: data}
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"server/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const currentTokenKey = "current_api_token"
+
+// authenticateToken accepts "Authorization: Bearer <token>". A bad token is
+// rejected right away instead of silently falling back to anonymous.
+func authenticateToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Authorization header must be a Bearer token")})
+			return
+		}
+
+		apiToken, err := models.GetApiTokenByToken(token)
+		if err != nil {
+			authError(c, err)
+			return
+		}
+
+		if apiToken.ID == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid or expired token")})
+			return
+		}
+
+		user, err := models.GetUserById(apiToken.UserID)
+		if err != nil {
+			authError(c, err)
+			return
+		}
+
+		if user.Name == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid or expired token")})
+			return
+		}
+
+		// el token reemplaza a cualquier sesion que venga en la cookie
+		delete(c.Keys, currentSessionKey)
+		setCurrentUser(c, user)
+		setLang(c)
+		c.Set(currentTokenKey, apiToken)
+		c.Next()
+	}
+}
+
+// requireScope only applies to token requests, cookie sessions are not scoped.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiToken, ok := c.Get(currentTokenKey); ok && !apiToken.(models.ApiToken).HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "Token is missing the %s scope", scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+func addToken(c *gin.Context) {
+
+	var json struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	userID, _ := currentUserID(c)
+
+	token, err := models.CreateApiToken(userID, json.Name, json.Scopes, time.Duration(json.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	// solo se muestra esta vez
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": gin.H{"token": token}})
+}
+
+func readAllTokens(c *gin.Context) {
+
+	userID, _ := currentUserID(c)
+
+	tokens, err := models.GetApiTokensByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve tokens")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+func revokeToken(c *gin.Context) {
+
+	tokenID, err := strconv.Atoi(c.Param("token_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid token ID")})
+		return
+	}
+
+	userID, _ := currentUserID(c)
+
+	success, err := models.RevokeApiToken(tokenID, userID)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusNotFound, gin.H{"error": trErr(c, err)})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"server/models"
+	"testing"
+	"time"
+)
+
+func TestTokenUseIsRecordedOncePerMinute(t *testing.T) {
+
+	user := newTestUser(t, "tokens")
+	token, err := models.CreateApiToken(user.ID, "test", []string{models.ScopePicnicsRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastUsed := func() string {
+		var at string
+		if err := models.DB.QueryRow("SELECT COALESCE(last_used_at, '') FROM api_tokens WHERE user_id = ?", user.ID).Scan(&at); err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+
+	if _, err := models.GetApiTokenByToken(token); err != nil {
+		t.Fatal(err)
+	}
+	first := lastUsed()
+	if first == "" {
+		t.Fatal("first use was not recorded")
+	}
+
+	// un uso reciente no se vuelve a escribir
+	if _, err := models.DB.Exec("UPDATE api_tokens SET last_used_at = datetime('now', '-30 seconds') WHERE user_id = ?", user.ID); err != nil {
+		t.Fatal(err)
+	}
+	recent := lastUsed()
+	apiToken, err := models.GetApiTokenByToken(token)
+	if err != nil || apiToken.ID == 0 {
+		t.Fatalf("token not found: %v", err)
+	}
+	if _, err := time.Parse(time.RFC3339, apiToken.LastUsedAt); err != nil {
+		t.Fatalf("last_used_at %q: %v", apiToken.LastUsedAt, err)
+	}
+	if got := lastUsed(); got != recent {
+		t.Errorf("last_used_at changed from %s to %s within a minute", recent, got)
+	}
+
+	if _, err := models.DB.Exec("UPDATE api_tokens SET last_used_at = datetime('now', '-2 minutes') WHERE user_id = ?", user.ID); err != nil {
+		t.Fatal(err)
+	}
+	stale := lastUsed()
+	if _, err := models.GetApiTokenByToken(token); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastUsed(); got == stale {
+		t.Errorf("last_used_at older than a minute was not updated")
+	}
+}
+
+func TestBearerTokenAuthenticates(t *testing.T) {
+
+	user := newTestUser(t, "bearer")
+	token, err := models.CreateApiToken(user.ID, "test", []string{models.ScopePicnicsRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := func(header string) int {
+		r := httptest.NewRequest("GET", "/api/v1/auth/me", nil)
+		r.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
+		testRouter.ServeHTTP(w, r)
+		return w.Code
+	}
+	if code := req("Bearer " + token); code != http.StatusOK {
+		t.Errorf("valid token: got %d", code)
+	}
+	if code := req("Bearer pat_nope"); code != http.StatusUnauthorized {
+		t.Errorf("unknown token: got %d", code)
+	}
+}
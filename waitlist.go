@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"server/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func deleteUserFromPicnic(c *gin.Context) {
+	leavePicnic(c, models.RemoveUserFromPicnic, models.EventMembershipRemoved)
+}
+
+func declinePicnic(c *gin.Context) {
+	leavePicnic(c, models.DeclinePicnic, models.EventMembershipDeclined)
+}
+
+func leavePicnic(c *gin.Context, leave func(userID int, picnicID int) (bool, error), event string) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid user ID")})
+		return
+	}
+
+	success, err := leave(userID, picnicID)
+
+	if success {
+		membershipLeft(picnicID, userID, event)
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+func readWaitlistOfPicnic(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	picnic, err := models.GetPicnicById(picnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if picnic.Name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Picnic of that id not found")})
+		return
+	}
+
+	waitlist, err := models.GetWaitlistByPicnic(picnicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve waitlist")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": waitlist})
+}
+
+// readMembership tells a user whether they are in, waitlisted (and where) or
+// declined. Owners and co-hosts can ask for anyone in their picnic.
+func readMembership(c *gin.Context) {
+
+	picnicID, err := strconv.Atoi(c.Param("picnic_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid picnic ID")})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid user ID")})
+		return
+	}
+
+	membership, err := models.GetMembership(userID, picnicID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if membership.ID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User is not part of that picnic")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": membership})
+}
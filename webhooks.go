@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"server/models"
+	"server/webhooks"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var webhookDispatcher = webhooks.NewDispatcher()
+
+func startWebhooks(ctx context.Context) {
+	go webhookDispatcher.Run(ctx)
+}
+
+// requireWebhookOwner loads :webhook_id, only its creator can see or change it
+func requireWebhookOwner() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		id, err := strconv.Atoi(c.Param("webhook_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid webhook ID")})
+			return
+		}
+
+		webhook, err := models.GetWebhookById(id)
+		if err != nil {
+			serverError(c, err)
+			return
+		}
+
+		userID, _ := currentUserID(c)
+		if webhook.ID == 0 || webhook.UserID != userID {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": tr(c, "Webhook of that id not found")})
+			return
+		}
+
+		c.Set("webhook", webhook)
+		c.Next()
+	}
+}
+
+func addWebhook(c *gin.Context) {
+
+	var json models.Webhook
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	json.UserID, _ = currentUserID(c)
+
+	webhook, err := models.CreateWebhook(json)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	// el secret solo se devuelve aca
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": webhook})
+}
+
+func readAllWebhooks(c *gin.Context) {
+
+	userID, _ := currentUserID(c)
+
+	webhooks, err := models.GetWebhooksByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve webhooks")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": webhooks})
+}
+
+func readWebhook(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": c.MustGet("webhook")})
+}
+
+func updateWebhook(c *gin.Context) {
+
+	var json models.Webhook
+
+	if err := c.ShouldBindJSON(&json); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+		return
+	}
+
+	webhook := c.MustGet("webhook").(models.Webhook)
+
+	success, err := models.UpdateWebhook(json, webhook.ID)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+func deleteWebhook(c *gin.Context) {
+
+	webhook := c.MustGet("webhook").(models.Webhook)
+
+	success, err := models.DeleteWebhook(webhook.ID)
+
+	if success {
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trErr(c, err)})
+	}
+}
+
+// GET /webhooks/:webhook_id/deliveries?limit=50, newest first
+func readAllDeliveriesOfWebhook(c *gin.Context) {
+
+	webhook := c.MustGet("webhook").(models.Webhook)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid limit")})
+		return
+	}
+
+	deliveries, err := models.GetDeliveriesByWebhook(webhook.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to retrieve deliveries")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
+
+func replayDelivery(c *gin.Context) {
+
+	webhook := c.MustGet("webhook").(models.Webhook)
+
+	deliveryID, err := strconv.Atoi(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid delivery ID")})
+		return
+	}
+
+	delivery, err := models.GetWebhookDeliveryById(deliveryID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+
+	if delivery.ID == 0 || delivery.WebhookID != webhook.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Delivery of that id not found")})
+		return
+	}
+
+	id, err := models.ReplayWebhookDelivery(delivery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": trErr(c, err)})
+		return
+	}
+	webhooks.Kick()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success", "data": gin.H{"delivery_id": id}})
+}
@@ -0,0 +1,215 @@
+// Package webhooks delivers the events queued in webhook_deliveries. Every
+// request is a JSON POST signed with the webhook secret:
+//
+//	X-Picnic-Signature: sha256=<hex HMAC-SHA256 of the body>
+//
+// Failed deliveries are retried with exponential backoff.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"server/models"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Envelope is the body every receiver gets
+type Envelope struct {
+	Event     string      `json:"event"`
+	CreatedAt string      `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Emit queues event of picnicID for the subscribed webhooks of its members
+// and wakes up the dispatcher. Errors are only logged, a webhook must never
+// break the request that fired it.
+func Emit(picnicID int, event string, data interface{}) {
+
+	recipients, err := models.GetWebhooksOfPicnic(picnicID)
+	if err != nil {
+		log.Printf("webhooks: %s: %v", event, err)
+		return
+	}
+	EmitTo(recipients, event, data)
+}
+
+// EmitTo is Emit for recipients looked up before the change, for events
+// after which the picnic has no members left to look up
+func EmitTo(recipients []models.Webhook, event string, data interface{}) {
+
+	body, err := json.Marshal(Envelope{
+		Event:     event,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("webhooks: %s: %v", event, err)
+		return
+	}
+
+	queued, err := models.QueueDeliveriesTo(recipients, event, string(body))
+	if err != nil {
+		log.Printf("webhooks: %s: %v", event, err)
+		return
+	}
+	if queued > 0 {
+		Kick()
+	}
+}
+
+var kick = make(chan struct{}, 1)
+
+// Kick makes a running Dispatcher deliver now instead of waiting for its tick
+func Kick() {
+	select {
+	case kick <- struct{}{}:
+	default:
+	}
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is 30s, 1m, 2m, 4m... capped at 6 hours
+func Backoff(attempts int) time.Duration {
+	backoff := 30 * time.Second
+	for i := 0; i < attempts && backoff < 6*time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > 6*time.Hour {
+		backoff = 6 * time.Hour
+	}
+	return backoff
+}
+
+type Dispatcher struct {
+	Client   *http.Client
+	Interval time.Duration
+}
+
+// NewDispatcher only connects to public addresses, a host that resolved to
+// a public one when the webhook was saved can point elsewhere later.
+func NewDispatcher() *Dispatcher {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: publicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+
+	return &Dispatcher{
+		Client:   &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		Interval: 15 * time.Second,
+	}
+}
+
+// publicOnly runs with the resolved address, right before connecting
+func publicOnly(network string, address string, _ syscall.RawConn) error {
+	if models.AllowPrivateWebhooks {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !models.PublicIP(ip) {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	return nil
+}
+
+// Run delivers due webhooks until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DeliverDue(ctx); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-kick:
+		}
+	}
+}
+
+// DeliverDue makes one attempt at every pending delivery that is due.
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+
+	deliveries, err := models.GetDueWebhookDeliveries(50)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		status, sendErr := d.send(ctx, delivery)
+		if sendErr != nil {
+			err = models.MarkDeliveryFailed(delivery, status, sendErr, Backoff(delivery.Attempts))
+		} else {
+			err = models.MarkDeliverySucceeded(delivery.ID, status)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+
+	webhook, err := models.GetWebhookById(delivery.WebhookID)
+	if err != nil {
+		return 0, err
+	}
+	if webhook.ID == 0 {
+		return 0, fmt.Errorf("webhook %d no longer exists", delivery.WebhookID)
+	}
+
+	secret, err := models.GetWebhookSecret(webhook.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "picnic-webhooks/1")
+	req.Header.Set("X-Picnic-Event", delivery.Event)
+	req.Header.Set("X-Picnic-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set("X-Picnic-Signature", Sign(secret, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,317 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"server/models"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+
+	models.PasswordCost = bcrypt.MinCost
+
+	dir, err := os.MkdirTemp("", "picnic-webhooks")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := models.OpenDatabase(filepath.Join(dir, "test.db")); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// receiver is an httptest.Server that answers the next statuses in order,
+// then 200
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []received
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, received{req.Header.Clone(), body})
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]received(nil), r.requests...)
+}
+
+var testUsers int
+
+// newHook registers a user with a picnic and a webhook pointing at url
+func newHook(t *testing.T, url string) (models.Webhook, int) {
+	t.Helper()
+
+	testUsers++
+	user, err := models.RegisterUser(models.User{Name: fmt.Sprintf("hook-%d", testUsers)}, "secretpw1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	picnicID, err := models.CreatePicnic(models.Picnic{Name: "Hooks", Date: "2026-11-21 13:00", CreatedBy: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models.AllowPrivateWebhooks = true
+	defer func() { models.AllowPrivateWebhooks = false }()
+
+	webhook, err := models.CreateWebhook(models.Webhook{UserID: user.ID, URL: url, Events: []string{"*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhook, picnicID
+}
+
+func deliver(t *testing.T, server *receiver) {
+	t.Helper()
+	d := &Dispatcher{Client: server.Client()}
+	if err := d.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lastDelivery(t *testing.T, webhook models.Webhook) models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := models.GetDeliveriesByWebhook(webhook.ID, 1)
+	if err != nil || len(deliveries) == 0 {
+		t.Fatalf("no deliveries: %v", err)
+	}
+	return deliveries[0]
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 de RFC 4231, caso 2
+	got := Sign("Jefe", []byte("what do ya want for nothing?"))
+	want := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestDeliveryIsSigned(t *testing.T) {
+
+	server := newReceiver(t)
+	webhook, picnicID := newHook(t, server.URL)
+
+	Emit(picnicID, models.EventPicnicCreated, models.Picnic{ID: picnicID, Name: "Hooks"})
+	deliver(t, server)
+
+	requests := server.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(req.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.header.Get("X-Picnic-Signature"); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("signature %s, want %s", got, want)
+	}
+	if got := req.header.Get("X-Picnic-Event"); got != models.EventPicnicCreated {
+		t.Errorf("event %q", got)
+	}
+	if delivery := lastDelivery(t, webhook); delivery.Status != models.DeliverySucceeded || delivery.ResponseStatus != http.StatusOK {
+		t.Errorf("delivery is %s (%d)", delivery.Status, delivery.ResponseStatus)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}
+	for attempts, backoff := range want {
+		if got := Backoff(attempts); got != backoff {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, backoff)
+		}
+	}
+	for _, attempts := range []int{10, 20, 100} {
+		if got := Backoff(attempts); got != 6*time.Hour {
+			t.Errorf("Backoff(%d) = %s, want 6h", attempts, got)
+		}
+	}
+}
+
+func TestFailedDeliveryIsRetried(t *testing.T) {
+
+	server := newReceiver(t, http.StatusInternalServerError)
+	webhook, picnicID := newHook(t, server.URL)
+
+	Emit(picnicID, models.EventPicnicCreated, models.Picnic{ID: picnicID})
+	deliver(t, server)
+
+	delivery := lastDelivery(t, webhook)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("after a 500 the delivery is %s, %d attempts, %d", delivery.Status, delivery.Attempts, delivery.ResponseStatus)
+	}
+	next, err := models.ParseDate(delivery.NextAttemptAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait := time.Until(next); wait < 25*time.Second || wait > 30*time.Second {
+		t.Errorf("next attempt in %s, want 30s", wait)
+	}
+
+	// no se reintenta antes de tiempo
+	deliver(t, server)
+	if n := len(server.received()); n != 1 {
+		t.Fatalf("retried before the backoff, %d requests", n)
+	}
+
+	if _, err := models.DB.Exec("UPDATE webhook_deliveries SET next_attempt_at = datetime('now', '-1 second') WHERE id = ?", delivery.ID); err != nil {
+		t.Fatal(err)
+	}
+	deliver(t, server)
+
+	delivery = lastDelivery(t, webhook)
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 2 {
+		t.Errorf("after the retry the delivery is %s, %d attempts", delivery.Status, delivery.Attempts)
+	}
+	if requests := server.received(); len(requests) != 2 || string(requests[0].body) != string(requests[1].body) {
+		t.Errorf("the retry sent a different body")
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+
+	server := newReceiver(t, http.StatusBadGateway)
+	webhook, picnicID := newHook(t, server.URL)
+
+	Emit(picnicID, models.EventPicnicCreated, models.Picnic{ID: picnicID})
+	if _, err := models.DB.Exec("UPDATE webhook_deliveries SET attempts = ? WHERE webhook_id = ?", models.MaxWebhookAttempts-1, webhook.ID); err != nil {
+		t.Fatal(err)
+	}
+	deliver(t, server)
+
+	if delivery := lastDelivery(t, webhook); delivery.Status != models.DeliveryFailed || delivery.Attempts != models.MaxWebhookAttempts {
+		t.Errorf("delivery is %s after %d attempts", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestReplay(t *testing.T) {
+
+	server := newReceiver(t)
+	webhook, picnicID := newHook(t, server.URL)
+
+	Emit(picnicID, models.EventPicnicCreated, models.Picnic{ID: picnicID})
+	deliver(t, server)
+	original := lastDelivery(t, webhook)
+
+	id, err := models.ReplayWebhookDelivery(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliver(t, server)
+
+	requests := server.received()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if string(requests[0].body) != string(requests[1].body) {
+		t.Errorf("the replay sent a different body")
+	}
+	if got := requests[1].header.Get("X-Picnic-Delivery"); got != strconv.Itoa(id) {
+		t.Errorf("replay delivery id %s, want %d", got, id)
+	}
+
+	// el original queda como estaba
+	if delivery, _ := models.GetWebhookDeliveryById(original.ID); delivery.Attempts != 1 || delivery.Status != models.DeliverySucceeded {
+		t.Errorf("the original changed: %s, %d attempts", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestEmitOnlyReachesMembers(t *testing.T) {
+
+	server := newReceiver(t)
+	_, picnicID := newHook(t, server.URL)
+	outsider := newReceiver(t)
+	outsiderHook, _ := newHook(t, outsider.URL)
+
+	Emit(picnicID, models.EventPicnicCreated, models.Picnic{ID: picnicID})
+	deliver(t, server)
+
+	if n := len(server.received()); n != 1 {
+		t.Errorf("the member got %d requests, want 1", n)
+	}
+	if deliveries, _ := models.GetDeliveriesByWebhook(outsiderHook.ID, 10); len(deliveries) != 0 {
+		t.Errorf("a picnic the owner is not in queued %d deliveries", len(deliveries))
+	}
+}
+
+func TestPrivateAddressesAreRejected(t *testing.T) {
+
+	user, err := models.RegisterUser(models.User{Name: "ssrf"}, "secretpw1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.7/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		if _, err := models.CreateWebhook(models.Webhook{UserID: user.ID, URL: url, Events: []string{"*"}}); err == nil {
+			t.Errorf("%s was accepted", url)
+		}
+	}
+
+	// y al conectar, por si el DNS cambio despues de guardarlo
+	server := newReceiver(t)
+	webhook, picnicID := newHook(t, server.URL)
+	Emit(picnicID, models.EventPicnicCreated, models.Picnic{ID: picnicID})
+
+	if err := NewDispatcher().DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.received()); n != 0 {
+		t.Errorf("the dispatcher reached a loopback address")
+	}
+	if delivery := lastDelivery(t, webhook); delivery.Status != models.DeliveryPending || delivery.Attempts != 1 {
+		t.Errorf("delivery is %s, %d attempts", delivery.Status, delivery.Attempts)
+	}
+}